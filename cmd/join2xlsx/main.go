@@ -0,0 +1,324 @@
+//go:generate goversioninfo
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/csv"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"gitee.com/nguaduot/split-xlsx-go/internal/xlsx"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+)
+
+const (
+	envPassword    = "XLSX_PASSWORD"     // 数据文件打开密码
+	envOutPassword = "XLSX_OUT_PASSWORD" // 导出文件加密密码
+)
+
+var (
+	reader      = bufio.NewReader(os.Stdin)
+	argPassword = flag.String("password", "", "加密数据文件的打开密码，也可通过环境变量 XLSX_PASSWORD 指定，均未指定则运行时输入")
+	argOutPass  = flag.String("out-password", "", "导出 xlsx 的加密密码，也可通过环境变量 XLSX_OUT_PASSWORD 指定，不填则不加密")
+	argOutRand  = flag.Bool("out-password-random", false, "导出 xlsx 的每个文件使用独立随机密码，并生成密码清单 -passwords.csv")
+	argDateFmt  = flag.String("date-format", "iso", "导出 CSV 的日期格式，如 yyyy/m/d，iso 即 yyyy-mm-dd")
+	argDTFmt    = flag.String("datetime-format", "iso", "导出 CSV 的日期时间格式，如 yyyy/m/d hh:mm，iso 即 yyyy-mm-dd hh:mm:ss")
+	argDelim    = flag.String("delimiter", ",", "导出 CSV 的分隔符，如 ,、;、|，tab 为制表符")
+	argQuoteAll = flag.Bool("quote-all", false, "导出 CSV 时所有字段均加引号")
+	argCRLF     = flag.Bool("crlf", false, "导出 CSV 时以 CRLF 换行，默认 LF")
+	argBOM      = flag.Bool("bom", true, "导出 CSV 时写 UTF-8 BOM（Windows Excel 需要），-bom=false 不写")
+	argNull     = flag.String("null", "", "导出 CSV 时空值的表示，如 NULL、\\N，默认留空")
+	argEncoding = flag.String("encoding", "utf-8", "导出 CSV 的编码：utf-8、gbk、gb18030（供只认本地编码的旧系统），无法编码的字符替换为 ? 并列出")
+	argKey      = flag.String("key", "", "键列，可填列名（A、B…）或行首文字")
+	argRightKey = flag.String("right-key", "", "右表键列，不填则与左表相同")
+	argJoin     = flag.String("join", "left", "拼接方式：left 保留左表全部行，inner 仅保留匹配行，full 保留左右两表全部行")
+	argSample   = flag.Int("sample-rows", 1000, "解析各列数据格式时采样的数据行数，按多数决定各列样式和类型")
+	argOverflow = flag.String("overflow", "sheet", "超出 Excel 最大行数时：sheet 续写至新表，file 续写至新文件，refuse 拒绝拼接")
+	argTextCols = flag.String("text-cols", "", "导出 xlsx 时按文本写入的列（身份证号、订单号等），逗号分隔，可填列名（A、B…）或行首文字；不填则仅超出 15 位有效数字的数值按文本写入")
+	argMerged   = flag.String("merged", "none", "合并单元格的处理方式：none 仅首格有值，fill 以首格的值填充区域内各单元格，keep 导出 xlsx 时保留左表的合并（区域须完整写入同一表）")
+)
+
+// getSrcPaths
+// 依次选择左表、右表
+func getSrcPaths() ([]string, error) {
+	files := []string{}
+	flag.Parse()
+	for _, arg := range flag.Args() { // 所有非 flag 参数
+		if len(files) == 2 {
+			break
+		}
+		if err := source.Check(arg); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		files = append(files, arg)
+		fmt.Printf("%s：%s\n", []string{"左表", "右表"}[len(files)-1], filepath.Base(arg))
+	}
+	for len(files) < 2 {
+		fmt.Printf("%s：", []string{"左表", "右表"}[len(files)])
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return []string{}, err
+		}
+		input = strings.Trim(strings.TrimSpace(input), "\"'")
+		if input == "" {
+			break
+		}
+		if err := source.Check(input); err != nil {
+			fmt.Println(err)
+			break
+		}
+		files = append(files, input)
+	}
+	return files, nil
+}
+
+// getTargetPath
+// 1. 输入含路径则按完整路径导出
+// 2. 输入不含路径则按源文件路径导出
+// 3. 不输入则按左表生成文件名 -join
+// 4. 输入含后缀则取为格式，否则使用 xlsx
+func getTargetPath(srcPaths []string) (string, error) {
+	fmt.Printf("导出文件名 %s：", color.HiBlackString("(直接回车自动生成)"))
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	name, ext := strings.Trim(strings.TrimSpace(input), "\"'"), ""
+	if name != "" { // 尝试从输入提取文件名和格式
+		if !filepath.IsAbs(input) {
+			name, err = util.RelativePath2Abs(name)
+			if err != nil {
+				return "", err
+			}
+		}
+		ext = filepath.Ext(name)
+		name = strings.TrimSuffix(name, ext)
+		ext = strings.ToLower(ext)
+		if ext != "" && ext != ".xlsx" && ext != ".csv" {
+			return "", fmt.Errorf("不支持拼接为该格式：%s", ext)
+		}
+	}
+	if ext == "" {
+		ext = ".xlsx"
+	}
+	if name == "" { // 未输入文件名，根据左表生成文件名
+		name = strings.TrimSuffix(srcPaths[0], filepath.Ext(srcPaths[0])) + "-join"
+	}
+	return name + ext, nil
+}
+
+// splitList
+// 拆分逗号分隔的参数值，忽略空项
+func splitList(s string) []string {
+	res := []string{}
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func getOptions() (option.Options, error) {
+	var opts option.Options
+	overflow, err := option.ParseOverflow(*argOverflow)
+	if err != nil {
+		return opts, err
+	}
+	opts.Overflow = overflow
+	joinType, err := option.ParseJoinType(*argJoin)
+	if err != nil {
+		return opts, err
+	}
+	key := strings.TrimSpace(*argKey)
+	if key == "" {
+		fmt.Printf("键列 %s：", color.HiBlackString("(列名 A、B… 或行首文字)"))
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return opts, err
+		}
+		key = strings.TrimSpace(input)
+	}
+	if key == "" {
+		return opts, errors.New("未指定键列，不进行拼接")
+	}
+	opts.Join = option.Join{
+		Key:      key,
+		RightKey: strings.TrimSpace(*argRightKey),
+		Type:     joinType,
+	}
+	opts.Protect = option.Protect{
+		Password: *argOutPass,
+		Random:   *argOutRand,
+	}
+	if opts.Protect.Password == "" {
+		opts.Protect.Password = os.Getenv(envOutPassword)
+	}
+	if opts.Date.Date, err = option.ParseDateLayout(*argDateFmt); err != nil {
+		return opts, err
+	}
+	if opts.Date.DateTime, err = option.ParseDateLayout(*argDTFmt); err != nil {
+		return opts, err
+	}
+	if opts.CSV, err = option.ParseCSVDialect(*argDelim, *argQuoteAll, *argCRLF, *argBOM, *argNull, *argEncoding); err != nil {
+		return opts, err
+	}
+	if *argSample < 1 {
+		return opts, fmt.Errorf("采样行数须大于0：%d", *argSample)
+	}
+	opts.Sample = *argSample
+	opts.TextCols = splitList(*argTextCols)
+	if opts.Merged, err = option.ParseMerged(*argMerged); err != nil {
+		return opts, err
+	}
+	opts.Source.FillMerged = opts.Merged == option.MergedFill
+	return opts, nil
+}
+
+func join(srcPaths []string, tarPath string, opts option.Options) error {
+	// 用于响应用户 Ctrl+C 打断
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		os.Interrupt,
+		syscall.SIGTERM,
+	)
+	defer stop()
+	if opts.Source.FillMerged {
+		for _, file := range srcPaths {
+			if source.IsXls(file) {
+				fmt.Printf("%s 为 xls 文件，不支持拆分填充合并单元格，已按 none 处理\n", filepath.Base(file))
+			}
+		}
+	}
+	if strings.ToLower(filepath.Ext(tarPath)) == ".csv" {
+		if opts.Protect.Enabled() {
+			fmt.Println("CSV 文件不支持加密，已忽略导出密码")
+		}
+		if opts.Merged == option.MergedKeep {
+			fmt.Println("CSV 文件不支持合并单元格，已按 none 处理")
+		}
+		return csv.JoinXlsx2csv(srcPaths[0], srcPaths[1], tarPath, opts, ctx)
+	}
+	return xlsx.JoinXlsx2xlsx(srcPaths[0], srcPaths[1], tarPath, opts, ctx)
+}
+
+// unlockSrcPaths
+// 加密的数据文件依次尝试 -password、环境变量 XLSX_PASSWORD 及此前输入过的密码，均不符则提示输入；
+// 返回各加密文件在内存中解密所得的工作簿
+func unlockSrcPaths(files []string) (map[string][]byte, error) {
+	unlocked := make(map[string][]byte)
+	tried := []string{*argPassword, os.Getenv(envPassword)}
+	for _, file := range files {
+		if !source.IsEncrypted(file) {
+			continue
+		}
+		for _, password := range tried {
+			if password == "" {
+				continue
+			}
+			if buf, err := source.Unlock(file, password); err == nil {
+				unlocked[file] = buf
+				break
+			}
+		}
+		for unlocked[file] == nil {
+			fmt.Printf("%s 已加密，请输入密码 %s：", filepath.Base(file), color.HiBlackString("(直接回车放弃)"))
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				return nil, err
+			}
+			input = strings.TrimRight(input, "\r\n")
+			if input == "" {
+				return nil, fmt.Errorf("未提供密码，无法打开：%s", filepath.Base(file))
+			}
+			buf, err := source.Unlock(file, input)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			tried = append(tried, input)
+			unlocked[file] = buf
+		}
+	}
+	return unlocked, nil
+}
+
+func welcome() {
+	fmt.Println("====", color.HiCyanString("Excel Join"), "======================================")
+	fmt.Println("Version :", color.HiGreenString("v1.2.260113"))
+	fmt.Println("Author  :", color.HiGreenString("nguaduot"))
+	fmt.Println("Repo    :", color.HiGreenString("https://github.com/nguaduot/xlsx-merge-split"))
+	fmt.Println("======================================================")
+
+	fmt.Printf("提示1：%s\n", color.HiRedString("按键列横向拼接左右两表（替代 VLOOKUP），请选择格式规整的纯数据 Excel 文件，公式按计算结果拼接。"))
+	fmt.Printf("提示2：%s\n", color.HiRedString("流式读写，右表过大时借助磁盘索引，内存占用稳定，支持超大数据文件。"))
+}
+
+func main() {
+	welcome()
+
+	srcPaths, err := getSrcPaths()
+	if err != nil {
+		fmt.Println(err)
+		util.WaitForExit()
+		return
+	}
+	if len(srcPaths) < 2 {
+		fmt.Println("未选择左右两个数据文件，不进行拼接")
+		util.WaitForExit()
+		return
+	}
+
+	unlocked, err := unlockSrcPaths(srcPaths)
+	if err != nil {
+		fmt.Println(err)
+		util.WaitForExit()
+		return
+	}
+	opts, err := getOptions()
+	if err != nil {
+		fmt.Println(err)
+		util.WaitForExit()
+		return
+	}
+	opts.Source.Unlocked = unlocked
+
+	tarPath, err := getTargetPath(srcPaths)
+	if err != nil {
+		fmt.Println(err)
+		util.WaitForExit()
+		return
+	}
+
+	cleanLog, err := util.InitLog(tarPath)
+	if err != nil {
+		fmt.Println(err)
+		util.WaitForExit()
+		return
+	}
+
+	err = join(srcPaths, tarPath, opts)
+	if err != nil {
+		fmt.Println(err)
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("注意：你已强行停止，拼接可能并未成功")
+		}
+		cleanLog()
+		util.WaitForExit()
+		return
+	}
+
+	cleanLog()
+	util.WaitForExit()
+}
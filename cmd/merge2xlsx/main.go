@@ -16,33 +16,136 @@ import (
 	"time"
 
 	"gitee.com/nguaduot/split-xlsx-go/internal/csv"
+	"gitee.com/nguaduot/split-xlsx-go/internal/jsonl"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/parquet"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
 	"gitee.com/nguaduot/split-xlsx-go/internal/xlsx"
 	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
 	"github.com/fatih/color"
 )
 
+const (
+	envPassword    = "XLSX_PASSWORD"     // 数据文件打开密码
+	envOutPassword = "XLSX_OUT_PASSWORD" // 导出文件加密密码
+)
+
 var (
 	reader      = bufio.NewReader(os.Stdin)
+	argPassword = flag.String("password", "", "加密数据文件的打开密码，也可通过环境变量 XLSX_PASSWORD 指定，均未指定则运行时输入")
+	argOutPass  = flag.String("out-password", "", "导出 xlsx 的加密密码，也可通过环境变量 XLSX_OUT_PASSWORD 指定，不填则不加密")
+	argOutRand  = flag.Bool("out-password-random", false, "导出 xlsx 的每个文件使用独立随机密码，并生成密码清单 -passwords.csv")
+	argDateFmt  = flag.String("date-format", "iso", "导出 CSV 的日期格式，如 yyyy/m/d，iso 即 yyyy-mm-dd")
+	argDTFmt    = flag.String("datetime-format", "iso", "导出 CSV 的日期时间格式，如 yyyy/m/d hh:mm，iso 即 yyyy-mm-dd hh:mm:ss")
+	argDelim    = flag.String("delimiter", ",", "导出 CSV 的分隔符，如 ,、;、|，tab 为制表符")
+	argQuoteAll = flag.Bool("quote-all", false, "导出 CSV 时所有字段均加引号")
+	argCRLF     = flag.Bool("crlf", false, "导出 CSV 时以 CRLF 换行，默认 LF")
+	argBOM      = flag.Bool("bom", true, "导出 CSV 时写 UTF-8 BOM（Windows Excel 需要），-bom=false 不写")
+	argNull     = flag.String("null", "", "导出 CSV 时空值的表示，如 NULL、\\N，默认留空")
+	argEncoding = flag.String("encoding", "utf-8", "导出 CSV 的编码：utf-8、gbk、gb18030（供只认本地编码的旧系统），无法编码的字符替换为 ? 并列出")
+	argCompress = flag.String("compression", "snappy", "导出 Parquet 的压缩算法：none、snappy、gzip、zstd")
+	argRowGroup = flag.Int("row-group-mb", 64, "导出 Parquet 的行组大小（MB，按未压缩数据估算），行组在内存中攒满后写出")
+	argFormula  = flag.String("formula", "value", "导出 xlsx 时公式的处理方式：value 按计算结果写入，keep 保留公式（引用按新行号改写）")
+	argSample   = flag.Int("sample-rows", 1000, "解析各列数据格式时采样的数据行数，按多数决定各列样式和类型")
+	argCellSty  = flag.Bool("cell-style", false, "导出 xlsx 时逐单元格保留样式（高亮行、红色负数、加粗合计等），较慢")
+	argTextCols = flag.String("text-cols", "", "导出 xlsx、Parquet 时按文本写入的列（身份证号、订单号等），逗号分隔，可填列名（A、B…）或行首文字；不填则仅超出 15 位有效数字的数值按文本写入")
+	argMerged   = flag.String("merged", "none", "合并单元格的处理方式：none 仅首格有值，fill 以首格的值填充区域内各单元格，keep 导出 xlsx 时保留合并（区域须完整写入同一表）")
+	argOverflow = flag.String("overflow", "sheet", "超出 Excel 最大行数时：sheet 续写至新表，file 续写至新文件，refuse 拒绝合并")
+	argDedup    = flag.Bool("dedup", false, "合并时去除重复行")
+	argDedupKey = flag.String("dedup-keys", "", "去重键列，逗号分隔，可填列名（A、B…）或行首文字，不填则按整行去重")
+	argDedupKep = flag.String("dedup-keep", "first", "重复行保留方式：first 保留首行，last 保留末行")
+	argSortKey  = flag.String("sort-key", "", "有序合并键列，可填列名（A、B…）或行首文字，各数据文件须已按该列排序")
+	argSortType = flag.String("sort-type", "auto", "有序合并键列类型：auto、number、text、date")
+	argSortDesc = flag.Bool("sort-desc", false, "有序合并按降序")
+	argSortStri = flag.Bool("sort-strict", false, "有序合并时数据文件未按键列有序则终止，否则仅记录并在结束时提示")
+	argRecurse  = flag.Bool("recursive", false, "数据文件为目录或通配符时递归子目录")
+	argOrder    = flag.String("order", "", "数据文件排序方式：name 按名称，mtime 按修改时间，natural 按名称自然排序，不填则保持输入顺序")
+	argInclude  = flag.String("include", "", "目录或通配符展开时仅保留匹配的文件名，逗号分隔，如 *2026*.xlsx")
+	argExclude  = flag.String("exclude", "", "目录或通配符展开时排除匹配的文件名，逗号分隔")
 	defMergeExt = ".xlsx"
 )
 
+// addSrcPath
+// 添加数据文件，目录或通配符则展开为其中的数据文件
+func addSrcPath(files []string, path string, verbose bool) ([]string, error) {
+	found := []string{path}
+	isDir, err := util.IsDir(path)
+	if err != nil {
+		return files, err
+	}
+	if isDir || util.HasGlobMeta(path) {
+		found, err = util.ExpandDataFiles(path, *argRecurse, splitList(*argInclude), splitList(*argExclude))
+		if err != nil {
+			return files, err
+		}
+		if len(found) == 0 {
+			fmt.Printf("未找到数据文件：%s\n", path)
+			return files, nil
+		}
+		verbose = true
+	} else if err := source.Check(path); err != nil {
+		fmt.Println(err)
+		return files, nil
+	}
+	for _, file := range found {
+		if verbose { // 展开所得文件仅按后缀筛选，再按内容核验
+			if err := source.Check(file); err != nil {
+				fmt.Printf("%s，已跳过\n", err)
+				continue
+			}
+		}
+		if !slices.Contains(files, file) {
+			files = append(files, file)
+			if verbose {
+				fmt.Printf("数据文件%d：%s\n", len(files), filepath.Base(file))
+			}
+		}
+	}
+	return files, nil
+}
+
+// sortSrcPaths
+// 按指定方式排序数据文件，默认保持输入顺序
+func sortSrcPaths(files []string, order option.Order) error {
+	switch order {
+	case option.OrderName:
+		slices.SortStableFunc(files, func(a, b string) int {
+			return strings.Compare(filepath.Base(a), filepath.Base(b))
+		})
+	case option.OrderMtime:
+		if err := util.SortFilesByMtime(files); err != nil {
+			return err
+		}
+	case option.OrderNatural:
+		slices.SortStableFunc(files, func(a, b string) int {
+			return util.NaturalCompare(filepath.Base(a), filepath.Base(b))
+		})
+	default:
+		return nil
+	}
+	fmt.Printf("已按%s排序：\n", color.HiYellowString(*argOrder))
+	for i, file := range files {
+		fmt.Printf("数据文件%d：%s\n", i+1, filepath.Base(file))
+	}
+	return nil
+}
+
 func getSrcPaths() ([]string, error) {
 	files := []string{}
 	flag.Parse()
+	order, err := option.ParseOrder(*argOrder)
+	if err != nil {
+		return files, err
+	}
 	args := flag.Args() // 所有非 flag 参数
 	for _, arg := range args {
-		if !util.IsExcelFile(arg) {
-			fmt.Printf("该文件不存在或非 Excel 文件：%s\n", arg)
-			continue
-		}
-		if !slices.Contains(files, arg) {
-			files = append(files, arg)
-			fmt.Printf("数据文件%d：%s\n", len(files), filepath.Base(arg))
+		if files, err = addSrcPath(files, arg, true); err != nil {
+			return files, err
 		}
 	}
 	if len(files) < 2 {
 		for {
-			fmt.Printf("数据文件%d %s：", len(files)+1, color.HiBlackString("(直接回车结束选择)"))
+			fmt.Printf("数据文件%d %s：", len(files)+1, color.HiBlackString("(可填目录或通配符，直接回车结束选择)"))
 			input, err := reader.ReadString('\n')
 			if err != nil {
 				return []string{}, err
@@ -51,15 +154,14 @@ func getSrcPaths() ([]string, error) {
 			if input == "" {
 				break
 			}
-			if !util.IsExcelFile(input) {
-				fmt.Printf("该文件不存在或非 Excel 文件：%s\n", input)
-				break
-			}
-			if !slices.Contains(files, input) {
-				files = append(files, input)
+			if files, err = addSrcPath(files, input, false); err != nil {
+				fmt.Println(err)
 			}
 		}
 	}
+	if err := sortSrcPaths(files, order); err != nil {
+		return files, err
+	}
 	return files, nil
 }
 
@@ -86,7 +188,7 @@ func getTargetPath(srcPaths []string) (string, error) {
 		ext = filepath.Ext(name)
 		name = strings.TrimSuffix(name, ext)
 		ext = strings.ToLower(ext)
-		if ext != "" && ext != ".xlsx" && ext != ".csv" {
+		if ext != "" && !slices.Contains([]string{".xlsx", ".csv", ".tsv", ".jsonl", ".parquet"}, ext) {
 			return "", fmt.Errorf("不支持合并为该格式：%s", ext)
 		}
 	}
@@ -141,7 +243,83 @@ func getTargetPath(srcPaths []string) (string, error) {
 	return name + ext, nil
 }
 
-func merge(srcPaths []string, tarPath string) error {
+// splitList
+// 拆分逗号分隔的参数值，忽略空项
+func splitList(s string) []string {
+	res := []string{}
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func getOptions() (option.Options, error) {
+	var opts option.Options
+	overflow, err := option.ParseOverflow(*argOverflow)
+	if err != nil {
+		return opts, err
+	}
+	opts.Overflow = overflow
+	if *argDedup || *argDedupKey != "" {
+		keep, err := option.ParseKeep(*argDedupKep)
+		if err != nil {
+			return opts, err
+		}
+		opts.Dedup.Enabled = true
+		opts.Dedup.Keep = keep
+		opts.Dedup.Keys = splitList(*argDedupKey)
+	}
+	if *argSortKey != "" {
+		keyType, err := option.ParseKeyType(*argSortType)
+		if err != nil {
+			return opts, err
+		}
+		opts.Sort = option.Sort{
+			Enabled: true,
+			Key:     strings.TrimSpace(*argSortKey),
+			Type:    keyType,
+			Desc:    *argSortDesc,
+			Strict:  *argSortStri,
+		}
+	}
+	opts.Protect = option.Protect{
+		Password: *argOutPass,
+		Random:   *argOutRand,
+	}
+	if opts.Protect.Password == "" {
+		opts.Protect.Password = os.Getenv(envOutPassword)
+	}
+	if opts.Date.Date, err = option.ParseDateLayout(*argDateFmt); err != nil {
+		return opts, err
+	}
+	if opts.Date.DateTime, err = option.ParseDateLayout(*argDTFmt); err != nil {
+		return opts, err
+	}
+	if opts.CSV, err = option.ParseCSVDialect(*argDelim, *argQuoteAll, *argCRLF, *argBOM, *argNull, *argEncoding); err != nil {
+		return opts, err
+	}
+	if opts.Parquet, err = option.ParseParquet(*argCompress, *argRowGroup); err != nil {
+		return opts, err
+	}
+	if opts.Formula, err = option.ParseFormula(*argFormula); err != nil {
+		return opts, err
+	}
+	if *argSample < 1 {
+		return opts, fmt.Errorf("采样行数须大于0：%d", *argSample)
+	}
+	opts.Sample = *argSample
+	opts.CellStyle = *argCellSty
+	opts.TextCols = splitList(*argTextCols)
+	if opts.Merged, err = option.ParseMerged(*argMerged); err != nil {
+		return opts, err
+	}
+	opts.Source.FillMerged = opts.Merged == option.MergedFill
+	return opts, nil
+}
+
+func merge(srcPaths []string, tarPath string, opts option.Options) error {
 	// 用于响应用户 Ctrl+C 打断
 	ctx, stop := signal.NotifyContext(
 		context.Background(),
@@ -149,11 +327,114 @@ func merge(srcPaths []string, tarPath string) error {
 		syscall.SIGTERM,
 	)
 	defer stop()
-	if strings.ToLower(filepath.Ext(tarPath)) == ".csv" {
-		return csv.MergeXlsx2csv(srcPaths, tarPath, ctx)
+	if opts.Source.FillMerged {
+		for _, file := range srcPaths {
+			if source.IsXls(file) {
+				fmt.Printf("%s 为 xls 文件，不支持拆分填充合并单元格，已按 none 处理\n", filepath.Base(file))
+			}
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(tarPath))
+	if ext == ".jsonl" {
+		if opts.Protect.Enabled() {
+			fmt.Println("JSONL 文件不支持加密，已忽略导出密码")
+		}
+		if opts.Formula == option.FormulaKeep {
+			fmt.Println("JSONL 文件不支持公式，已按计算结果写入")
+		}
+		if opts.CSV.Encoding != "" {
+			fmt.Println("JSONL 文件固定使用 UTF-8 编码，已忽略导出编码")
+		}
+		if opts.Sort.Enabled {
+			return errors.New("JSONL 文件暂不支持有序合并，请导出为 xlsx 或 CSV")
+		}
+		return jsonl.MergeXlsx2jsonl(srcPaths, tarPath, opts, ctx)
+	}
+	if ext == ".parquet" {
+		if opts.Protect.Enabled() {
+			fmt.Println("Parquet 文件不支持加密，已忽略导出密码")
+		}
+		if opts.Formula == option.FormulaKeep {
+			fmt.Println("Parquet 文件不支持公式，已按计算结果写入")
+		}
+		if opts.Sort.Enabled {
+			return errors.New("Parquet 文件暂不支持有序合并，请导出为 xlsx 或 CSV")
+		}
+		return parquet.MergeXlsx2parquet(srcPaths, tarPath, opts, ctx)
+	}
+	if ext == ".csv" || ext == ".tsv" {
+		if ext == ".tsv" { // 制表符分隔
+			opts.CSV.Comma = '\t'
+		}
+		if opts.Protect.Enabled() {
+			fmt.Println("CSV 文件不支持加密，已忽略导出密码")
+		}
+		if opts.Formula == option.FormulaKeep {
+			fmt.Println("CSV 文件不支持公式，已按计算结果写入")
+		}
+		if opts.CellStyle {
+			fmt.Println("CSV 文件不含样式，已忽略逐单元格保留样式")
+		}
+		if opts.Merged == option.MergedKeep {
+			fmt.Println("CSV 文件不支持合并单元格，已按 none 处理")
+		}
+		if opts.Sort.Enabled {
+			return csv.MergeXlsx2csvSorted(srcPaths, tarPath, opts, ctx)
+		}
+		return csv.MergeXlsx2csv(srcPaths, tarPath, opts, ctx)
+	}
+	if opts.Sort.Enabled {
+		if opts.Formula == option.FormulaKeep {
+			fmt.Println("有序合并交错写入各数据文件的行，不支持保留公式，已按计算结果写入")
+		}
+		if opts.CellStyle {
+			fmt.Println("有序合并不支持逐单元格保留样式，已按列样式写入")
+		}
+		return xlsx.MergeXlsx2xlsxSorted(srcPaths, tarPath, opts, ctx)
 	}
 	// return xlsx.MergeXlsx2xlsxV1(srcPaths, tarPath, ctx)
-	return xlsx.MergeXlsx2xlsxV2(srcPaths, tarPath, ctx)
+	return xlsx.MergeXlsx2xlsxV2(srcPaths, tarPath, opts, ctx)
+}
+
+// unlockSrcPaths
+// 加密的数据文件依次尝试 -password、环境变量 XLSX_PASSWORD 及此前输入过的密码，均不符则提示输入；
+// 返回各加密文件在内存中解密所得的工作簿
+func unlockSrcPaths(files []string) (map[string][]byte, error) {
+	unlocked := make(map[string][]byte)
+	tried := []string{*argPassword, os.Getenv(envPassword)}
+	for _, file := range files {
+		if !source.IsEncrypted(file) {
+			continue
+		}
+		for _, password := range tried {
+			if password == "" {
+				continue
+			}
+			if buf, err := source.Unlock(file, password); err == nil {
+				unlocked[file] = buf
+				break
+			}
+		}
+		for unlocked[file] == nil {
+			fmt.Printf("%s 已加密，请输入密码 %s：", filepath.Base(file), color.HiBlackString("(直接回车放弃)"))
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				return nil, err
+			}
+			input = strings.TrimRight(input, "\r\n")
+			if input == "" {
+				return nil, fmt.Errorf("未提供密码，无法打开：%s", filepath.Base(file))
+			}
+			buf, err := source.Unlock(file, input)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			tried = append(tried, input)
+			unlocked[file] = buf
+		}
+	}
+	return unlocked, nil
 }
 
 func welcome() {
@@ -163,8 +444,8 @@ func welcome() {
 	fmt.Println("Repo    :", color.HiGreenString("https://github.com/nguaduot/xlsx-merge-split"))
 	fmt.Println("======================================================")
 
-	fmt.Printf("提示1：%s\n", color.HiRedString("请选择格式规整、不含公式的纯数据 Excel 文件，多表首行保持一致，避免合并失败。"))
-	fmt.Printf("提示2：%s\n", color.HiRedString("流式读写，内存占用稳定，支持超大数据文件，但请注意 Excel 最大仅支持 1048576 行。"))
+	fmt.Printf("提示1：%s\n", color.HiRedString("请选择格式规整的纯数据 Excel 文件，多表首行保持一致，避免合并失败；公式按计算结果合并（-formula keep 保留公式）。"))
+	fmt.Printf("提示2：%s\n", color.HiRedString("流式读写，内存占用稳定，支持超大数据文件，超出 Excel 最大行数（1048576）时自动续写至新表。"))
 }
 
 func main() {
@@ -177,10 +458,24 @@ func main() {
 		return
 	}
 	if len(srcPaths) < 2 {
-		fmt.Println("未选择2个及以上 Excel 文件，不进行合并")
+		fmt.Println("未选择2个及以上数据文件，不进行合并")
+		util.WaitForExit()
+		return
+	}
+
+	unlocked, err := unlockSrcPaths(srcPaths)
+	if err != nil {
+		fmt.Println(err)
 		util.WaitForExit()
 		return
 	}
+	opts, err := getOptions()
+	if err != nil {
+		fmt.Println(err)
+		util.WaitForExit()
+		return
+	}
+	opts.Source.Unlocked = unlocked
 
 	tarPath, err := getTargetPath(srcPaths)
 	if err != nil {
@@ -188,6 +483,19 @@ func main() {
 		util.WaitForExit()
 		return
 	}
+	// 跳过本次导出将生成的文件（如上次合并留下的导出文件、续写文件、密码清单），避免边读边写
+	srcPaths = slices.DeleteFunc(srcPaths, func(file string) bool {
+		if util.IsOutputOf(file, tarPath) {
+			fmt.Printf("%s：为本次导出生成的文件，已跳过\n", file)
+			return true
+		}
+		return false
+	})
+	if len(srcPaths) < 2 {
+		fmt.Println("未选择2个及以上数据文件，不进行合并")
+		util.WaitForExit()
+		return
+	}
 
 	cleanLog, err := util.InitLog(tarPath)
 	if err != nil {
@@ -196,7 +504,7 @@ func main() {
 		return
 	}
 
-	err = merge(srcPaths, tarPath)
+	err = merge(srcPaths, tarPath, opts)
 	if err != nil {
 		fmt.Println(err)
 		if errors.Is(err, context.Canceled) {
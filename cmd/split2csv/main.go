@@ -16,13 +16,32 @@ import (
 	"syscall"
 
 	"gitee.com/nguaduot/split-xlsx-go/internal/csv"
+	"gitee.com/nguaduot/split-xlsx-go/internal/jsonl"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/parquet"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
 	"gitee.com/nguaduot/split-xlsx-go/internal/xlsx"
 	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
 	"github.com/fatih/color"
 )
 
+const envPassword = "XLSX_PASSWORD"
+
 var (
 	reader       = bufio.NewReader(os.Stdin)
+	argPassword  = flag.String("password", "", "加密数据文件的打开密码，也可通过环境变量 XLSX_PASSWORD 指定，均未指定则运行时输入")
+	argDateFmt   = flag.String("date-format", "iso", "日期格式，如 yyyy/m/d，iso 即 yyyy-mm-dd")
+	argDTFmt     = flag.String("datetime-format", "iso", "日期时间格式，如 yyyy/m/d hh:mm，iso 即 yyyy-mm-dd hh:mm:ss")
+	argDelim     = flag.String("delimiter", ",", "分隔符，如 ,、;、|，tab 为制表符")
+	argQuoteAll  = flag.Bool("quote-all", false, "所有字段均加引号")
+	argCRLF      = flag.Bool("crlf", false, "以 CRLF 换行，默认 LF")
+	argBOM       = flag.Bool("bom", true, "写 UTF-8 BOM（Windows Excel 需要），-bom=false 不写")
+	argNull      = flag.String("null", "", "空值的表示，如 NULL、\\N，默认留空")
+	argEncoding  = flag.String("encoding", "utf-8", "编码：utf-8、gbk、gb18030（供只认本地编码的旧系统），无法编码的字符替换为 ? 并列出")
+	argCompress  = flag.String("compression", "snappy", "拆分为 Parquet 的压缩算法：none、snappy、gzip、zstd")
+	argRowGroup  = flag.Int("row-group-mb", 64, "拆分为 Parquet 的行组大小（MB，按未压缩数据估算），行组在内存中攒满后写出")
+	argMerged    = flag.String("merged", "none", "合并单元格的处理方式：none 仅首格有值，fill 以首格的值填充区域内各单元格")
+	argFormat    = flag.String("format", "csv", "拆分格式：xlsx、csv、tsv（制表符分隔）、jsonl（每行一个 JSON 对象，数值、布尔列写为数字、布尔值）、parquet（列类型由采样确定，之后出现不符的取值则放宽）")
 	defSplitLine = 20000
 	defSplitFile = 2
 	defSplitExt  = ".csv"
@@ -33,8 +52,8 @@ func getSrcPath() (string, error) {
 	flag.Parse()
 	args := flag.Args() // 所有非 flag 参数
 	for _, arg := range args {
-		if !util.IsExcelFile(arg) {
-			fmt.Printf("该文件不存在或非 Excel 文件：%s\n", arg)
+		if err := source.Check(arg); err != nil {
+			fmt.Println(err)
 			continue
 		}
 		file = arg
@@ -48,8 +67,10 @@ func getSrcPath() (string, error) {
 			return "", err
 		}
 		file = strings.Trim(strings.TrimSpace(input), "\"'")
-		if file != "" && !util.IsExcelFile(file) {
-			return "", fmt.Errorf("该文件不存在或非 Excel 文件：%s\n", file)
+		if file != "" {
+			if err := source.Check(file); err != nil {
+				return "", err
+			}
 		}
 	}
 	return file, nil
@@ -181,10 +202,14 @@ func getTarget(srcPath string) (int, int, string, string, error) {
 	// } else {
 	// 	splitExt = ".xlsx"
 	// }
-	return splitLine, splitFile, dirTarget, defSplitExt, nil
+	splitExt, err := option.ParseFormat(*argFormat, defSplitExt)
+	if err != nil {
+		return 0, 0, "", "", err
+	}
+	return splitLine, splitFile, dirTarget, splitExt, nil
 }
 
-func split(srcPath string, splitLine int, splitFile int, splitDir string, splitExt string) error {
+func split(srcPath string, unlocked map[string][]byte, splitLine int, splitFile int, splitDir string, splitExt string) error {
 	// 用于响应用户 Ctrl+C 打断
 	ctx, stop := signal.NotifyContext(
 		context.Background(),
@@ -192,20 +217,104 @@ func split(srcPath string, splitLine int, splitFile int, splitDir string, splitE
 		syscall.SIGTERM,
 	)
 	defer stop()
-	if splitExt == ".csv" {
+	var (
+		opts = option.Options{Source: option.Source{Unlocked: unlocked}}
+		err  error
+	)
+	if opts.Date.Date, err = option.ParseDateLayout(*argDateFmt); err != nil {
+		return err
+	}
+	if opts.Date.DateTime, err = option.ParseDateLayout(*argDTFmt); err != nil {
+		return err
+	}
+	if opts.Merged, err = option.ParseMerged(*argMerged); err != nil {
+		return err
+	}
+	if opts.CSV, err = option.ParseCSVDialect(*argDelim, *argQuoteAll, *argCRLF, *argBOM, *argNull, *argEncoding); err != nil {
+		return err
+	}
+	if opts.Parquet, err = option.ParseParquet(*argCompress, *argRowGroup); err != nil {
+		return err
+	}
+	opts.Source.FillMerged = opts.Merged == option.MergedFill
+	if opts.Source.FillMerged && source.IsXls(srcPath) {
+		fmt.Println("xls 文件不支持拆分填充合并单元格，已按 none 处理")
+	}
+	if splitExt == ".parquet" {
+		if splitFile > 0 {
+			return parquet.SplitXlsx2parquetByFile(srcPath, splitDir, splitFile, opts, ctx)
+		} else {
+			return parquet.SplitXlsx2parquetByLine(srcPath, splitDir, splitLine, opts, ctx)
+		}
+	}
+	if splitExt == ".jsonl" {
+		if splitFile > 0 {
+			return jsonl.SplitXlsx2jsonlByFile(srcPath, splitDir, splitFile, opts, ctx)
+		} else {
+			return jsonl.SplitXlsx2jsonlByLine(srcPath, splitDir, splitLine, opts, ctx)
+		}
+	}
+	if splitExt == ".csv" || splitExt == ".tsv" {
+		if splitExt == ".tsv" { // 制表符分隔
+			opts.CSV.Comma = '\t'
+		}
+		if opts.Merged == option.MergedKeep {
+			fmt.Println("CSV 文件不支持合并单元格，已按 none 处理")
+		}
 		if splitFile > 0 {
-			return csv.SplitXlsx2csvByFile(srcPath, splitDir, splitFile, ctx)
+			return csv.SplitXlsx2csvByFile(srcPath, splitDir, splitFile, opts, ctx)
 		} else {
-			return csv.SplitXlsx2csvByLine(srcPath, splitDir, splitLine, ctx)
+			return csv.SplitXlsx2csvByLine(srcPath, splitDir, splitLine, opts, ctx)
 		}
 	}
 	if splitFile > 0 {
-		return xlsx.SplitXlsx2xlsxByFile(srcPath, splitDir, splitFile, ctx)
+		return xlsx.SplitXlsx2xlsxByFile(srcPath, splitDir, splitFile, opts, ctx)
 	} else {
-		return xlsx.SplitXlsx2xlsxByLine(srcPath, splitDir, splitLine, ctx)
+		return xlsx.SplitXlsx2xlsxByLine(srcPath, splitDir, splitLine, opts, ctx)
 	}
 }
 
+// unlockSrcPaths
+// 加密的数据文件依次尝试 -password、环境变量 XLSX_PASSWORD 及此前输入过的密码，均不符则提示输入；
+// 返回各加密文件在内存中解密所得的工作簿
+func unlockSrcPaths(files []string) (map[string][]byte, error) {
+	unlocked := make(map[string][]byte)
+	tried := []string{*argPassword, os.Getenv(envPassword)}
+	for _, file := range files {
+		if !source.IsEncrypted(file) {
+			continue
+		}
+		for _, password := range tried {
+			if password == "" {
+				continue
+			}
+			if buf, err := source.Unlock(file, password); err == nil {
+				unlocked[file] = buf
+				break
+			}
+		}
+		for unlocked[file] == nil {
+			fmt.Printf("%s 已加密，请输入密码 %s：", filepath.Base(file), color.HiBlackString("(直接回车放弃)"))
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				return nil, err
+			}
+			input = strings.TrimRight(input, "\r\n")
+			if input == "" {
+				return nil, fmt.Errorf("未提供密码，无法打开：%s", filepath.Base(file))
+			}
+			buf, err := source.Unlock(file, input)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			tried = append(tried, input)
+			unlocked[file] = buf
+		}
+	}
+	return unlocked, nil
+}
+
 func welcome() {
 	fmt.Println("====", color.HiCyanString("Excel Split"), "=====================================")
 	fmt.Println("Version :", color.HiGreenString("v1.2.260113"))
@@ -213,7 +322,7 @@ func welcome() {
 	fmt.Println("Repo    :", color.HiGreenString("https://github.com/nguaduot/xlsx-merge-split"))
 	fmt.Println("======================================================")
 
-	fmt.Printf("提示1：%s\n", color.HiRedString("请选择格式规整、不含公式的纯数据 Excel 文件，避免拆分失败。"))
+	fmt.Printf("提示1：%s\n", color.HiRedString("请选择格式规整的纯数据 Excel 文件，避免拆分失败；公式按计算结果拆分。"))
 	fmt.Printf("提示2：%s\n", color.HiRedString("流式读写，内存占用稳定，支持超大数据文件，但请注意 Excel 最大仅支持 1048576 行。"))
 }
 
@@ -227,11 +336,17 @@ func main() {
 		return
 	}
 	if srcPath == "" {
-		fmt.Println("未选择 Excel 文件，无可拆分")
+		fmt.Println("未选择数据文件，无可拆分")
 		util.WaitForExit()
 		return
 	}
 
+	unlocked, err := unlockSrcPaths([]string{srcPath})
+	if err != nil {
+		fmt.Println(err)
+		util.WaitForExit()
+		return
+	}
 	splitLine, splitFile, splitDir, splitExt, err := getTarget(srcPath)
 	if err != nil {
 		fmt.Println(err)
@@ -246,7 +361,7 @@ func main() {
 		return
 	}
 
-	err = split(srcPath, splitLine, splitFile, splitDir, splitExt)
+	err = split(srcPath, unlocked, splitLine, splitFile, splitDir, splitExt)
 	if err != nil {
 		fmt.Println(err)
 		if errors.Is(err, context.Canceled) {
@@ -16,13 +16,41 @@ import (
 	"syscall"
 
 	"gitee.com/nguaduot/split-xlsx-go/internal/csv"
+	"gitee.com/nguaduot/split-xlsx-go/internal/jsonl"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/parquet"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
 	"gitee.com/nguaduot/split-xlsx-go/internal/xlsx"
 	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
 	"github.com/fatih/color"
 )
 
+const (
+	envPassword    = "XLSX_PASSWORD"     // 数据文件打开密码
+	envOutPassword = "XLSX_OUT_PASSWORD" // 导出文件加密密码
+)
+
 var (
 	reader       = bufio.NewReader(os.Stdin)
+	argPassword  = flag.String("password", "", "加密数据文件的打开密码，也可通过环境变量 XLSX_PASSWORD 指定，均未指定则运行时输入")
+	argOutPass   = flag.String("out-password", "", "拆分所得每个 xlsx 的加密密码，也可通过环境变量 XLSX_OUT_PASSWORD 指定，不填则不加密")
+	argOutRand   = flag.Bool("out-password-random", false, "拆分所得每个 xlsx 使用独立随机密码，并在拆分文件夹旁生成密码清单 -passwords.csv")
+	argFormula   = flag.String("formula", "value", "公式的处理方式：value 按计算结果写入，keep 保留公式（引用按新行号改写）")
+	argSample    = flag.Int("sample-rows", 1000, "解析各列数据格式时采样的数据行数，按多数决定各列样式和类型")
+	argCellSty   = flag.Bool("cell-style", false, "拆分为 xlsx 时逐单元格保留样式（高亮行、红色负数、加粗合计等），较慢")
+	argTextCols  = flag.String("text-cols", "", "拆分为 xlsx、Parquet 时按文本写入的列（身份证号、订单号等），逗号分隔，可填列名（A、B…）或行首文字；不填则仅超出 15 位有效数字的数值按文本写入")
+	argDelim     = flag.String("delimiter", ",", "拆分为 CSV 的分隔符，如 ,、;、|，tab 为制表符")
+	argQuoteAll  = flag.Bool("quote-all", false, "拆分为 CSV 时所有字段均加引号")
+	argCRLF      = flag.Bool("crlf", false, "拆分为 CSV 时以 CRLF 换行，默认 LF")
+	argBOM       = flag.Bool("bom", true, "拆分为 CSV 时写 UTF-8 BOM（Windows Excel 需要），-bom=false 不写")
+	argNull      = flag.String("null", "", "拆分为 CSV 时空值的表示，如 NULL、\\N，默认留空")
+	argEncoding  = flag.String("encoding", "utf-8", "拆分为 CSV 的编码：utf-8、gbk、gb18030（供只认本地编码的旧系统），无法编码的字符替换为 ? 并列出")
+	argDateFmt   = flag.String("date-format", "iso", "拆分为 CSV 的日期格式，如 yyyy/m/d，iso 即 yyyy-mm-dd")
+	argDTFmt     = flag.String("datetime-format", "iso", "拆分为 CSV 的日期时间格式，如 yyyy/m/d hh:mm，iso 即 yyyy-mm-dd hh:mm:ss")
+	argCompress  = flag.String("compression", "snappy", "拆分为 Parquet 的压缩算法：none、snappy、gzip、zstd")
+	argRowGroup  = flag.Int("row-group-mb", 64, "拆分为 Parquet 的行组大小（MB，按未压缩数据估算），行组在内存中攒满后写出")
+	argMerged    = flag.String("merged", "none", "合并单元格的处理方式：none 仅首格有值，fill 以首格的值填充区域内各单元格，keep 拆分为 xlsx 时保留合并（区域须完整落在同一份内）")
+	argFormat    = flag.String("format", "xlsx", "拆分格式：xlsx、csv、tsv（制表符分隔）、jsonl（每行一个 JSON 对象，数值、布尔列写为数字、布尔值）、parquet（列类型由采样确定，之后出现不符的取值则放宽）")
 	defSplitLine = 20000
 	defSplitFile = 2
 	defSplitExt  = ".xlsx"
@@ -33,8 +61,8 @@ func getSrcPath() (string, error) {
 	flag.Parse()
 	args := flag.Args() // 所有非 flag 参数
 	for _, arg := range args {
-		if !util.IsExcelFile(arg) {
-			fmt.Printf("该文件不存在或非 Excel 文件：%s\n", arg)
+		if err := source.Check(arg); err != nil {
+			fmt.Println(err)
 			continue
 		}
 		file = arg
@@ -48,8 +76,10 @@ func getSrcPath() (string, error) {
 			return "", err
 		}
 		file = strings.Trim(strings.TrimSpace(input), "\"'")
-		if file != "" && !util.IsExcelFile(file) {
-			return "", fmt.Errorf("该文件不存在或非 Excel 文件：%s\n", file)
+		if file != "" {
+			if err := source.Check(file); err != nil {
+				return "", err
+			}
 		}
 	}
 	return file, nil
@@ -181,10 +211,26 @@ func getTarget(srcPath string) (int, int, string, string, error) {
 	// } else {
 	// 	splitExt = ".xlsx"
 	// }
-	return splitLine, splitFile, dirTarget, defSplitExt, nil
+	splitExt, err := option.ParseFormat(*argFormat, defSplitExt)
+	if err != nil {
+		return 0, 0, "", "", err
+	}
+	return splitLine, splitFile, dirTarget, splitExt, nil
 }
 
-func split(srcPath string, splitLine int, splitFile int, splitDir string, splitExt string) error {
+// splitList
+// 拆分逗号分隔的参数值，忽略空项
+func splitList(s string) []string {
+	res := []string{}
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func split(srcPath string, unlocked map[string][]byte, splitLine int, splitFile int, splitDir string, splitExt string) error {
 	// 用于响应用户 Ctrl+C 打断
 	ctx, stop := signal.NotifyContext(
 		context.Background(),
@@ -192,20 +238,121 @@ func split(srcPath string, splitLine int, splitFile int, splitDir string, splitE
 		syscall.SIGTERM,
 	)
 	defer stop()
-	if splitExt == ".csv" {
+	opts := option.Options{
+		Protect: option.Protect{
+			Password: *argOutPass,
+			Random:   *argOutRand,
+		},
+		Source: option.Source{Unlocked: unlocked},
+	}
+	if opts.Protect.Password == "" {
+		opts.Protect.Password = os.Getenv(envOutPassword)
+	}
+	formula, err := option.ParseFormula(*argFormula)
+	if err != nil {
+		return err
+	}
+	opts.Formula = formula
+	if *argSample < 1 {
+		return fmt.Errorf("采样行数须大于0：%d", *argSample)
+	}
+	opts.Sample = *argSample
+	opts.CellStyle = *argCellSty
+	opts.TextCols = splitList(*argTextCols)
+	if opts.Merged, err = option.ParseMerged(*argMerged); err != nil {
+		return err
+	}
+	opts.Source.FillMerged = opts.Merged == option.MergedFill
+	if opts.Source.FillMerged && source.IsXls(srcPath) {
+		fmt.Println("xls 文件不支持拆分填充合并单元格，已按 none 处理")
+	}
+	if opts.CSV, err = option.ParseCSVDialect(*argDelim, *argQuoteAll, *argCRLF, *argBOM, *argNull, *argEncoding); err != nil {
+		return err
+	}
+	if opts.Parquet, err = option.ParseParquet(*argCompress, *argRowGroup); err != nil {
+		return err
+	}
+	if opts.Date.Date, err = option.ParseDateLayout(*argDateFmt); err != nil {
+		return err
+	}
+	if opts.Date.DateTime, err = option.ParseDateLayout(*argDTFmt); err != nil {
+		return err
+	}
+	if splitExt == ".parquet" {
+		if splitFile > 0 {
+			return parquet.SplitXlsx2parquetByFile(srcPath, splitDir, splitFile, opts, ctx)
+		} else {
+			return parquet.SplitXlsx2parquetByLine(srcPath, splitDir, splitLine, opts, ctx)
+		}
+	}
+	if splitExt == ".jsonl" {
 		if splitFile > 0 {
-			return csv.SplitXlsx2csvByFile(srcPath, splitDir, splitFile, ctx)
+			return jsonl.SplitXlsx2jsonlByFile(srcPath, splitDir, splitFile, opts, ctx)
 		} else {
-			return csv.SplitXlsx2csvByLine(srcPath, splitDir, splitLine, ctx)
+			return jsonl.SplitXlsx2jsonlByLine(srcPath, splitDir, splitLine, opts, ctx)
+		}
+	}
+	if splitExt == ".csv" || splitExt == ".tsv" {
+		if splitExt == ".tsv" { // 制表符分隔
+			opts.CSV.Comma = '\t'
+		}
+		if opts.Merged == option.MergedKeep {
+			fmt.Println("CSV 文件不支持合并单元格，已按 none 处理")
+		}
+		if splitFile > 0 {
+			return csv.SplitXlsx2csvByFile(srcPath, splitDir, splitFile, opts, ctx)
+		} else {
+			return csv.SplitXlsx2csvByLine(srcPath, splitDir, splitLine, opts, ctx)
 		}
 	}
 	if splitFile > 0 {
-		return xlsx.SplitXlsx2xlsxByFile(srcPath, splitDir, splitFile, ctx)
+		return xlsx.SplitXlsx2xlsxByFile(srcPath, splitDir, splitFile, opts, ctx)
 	} else {
-		return xlsx.SplitXlsx2xlsxByLine(srcPath, splitDir, splitLine, ctx)
+		return xlsx.SplitXlsx2xlsxByLine(srcPath, splitDir, splitLine, opts, ctx)
 	}
 }
 
+// unlockSrcPaths
+// 加密的数据文件依次尝试 -password、环境变量 XLSX_PASSWORD 及此前输入过的密码，均不符则提示输入；
+// 返回各加密文件在内存中解密所得的工作簿
+func unlockSrcPaths(files []string) (map[string][]byte, error) {
+	unlocked := make(map[string][]byte)
+	tried := []string{*argPassword, os.Getenv(envPassword)}
+	for _, file := range files {
+		if !source.IsEncrypted(file) {
+			continue
+		}
+		for _, password := range tried {
+			if password == "" {
+				continue
+			}
+			if buf, err := source.Unlock(file, password); err == nil {
+				unlocked[file] = buf
+				break
+			}
+		}
+		for unlocked[file] == nil {
+			fmt.Printf("%s 已加密，请输入密码 %s：", filepath.Base(file), color.HiBlackString("(直接回车放弃)"))
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				return nil, err
+			}
+			input = strings.TrimRight(input, "\r\n")
+			if input == "" {
+				return nil, fmt.Errorf("未提供密码，无法打开：%s", filepath.Base(file))
+			}
+			buf, err := source.Unlock(file, input)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			tried = append(tried, input)
+			unlocked[file] = buf
+		}
+	}
+	return unlocked, nil
+}
+
 func welcome() {
 	fmt.Println("====", color.HiCyanString("Excel Split"), "=====================================")
 	fmt.Println("Version :", color.HiGreenString("v1.2.260113"))
@@ -213,7 +360,7 @@ func welcome() {
 	fmt.Println("Repo    :", color.HiGreenString("https://github.com/nguaduot/xlsx-merge-split"))
 	fmt.Println("======================================================")
 
-	fmt.Printf("提示1：%s\n", color.HiRedString("请选择格式规整、不含公式的纯数据 Excel 文件，避免拆分失败。"))
+	fmt.Printf("提示1：%s\n", color.HiRedString("请选择格式规整的纯数据 Excel 文件，避免拆分失败；公式按计算结果拆分（-formula keep 保留公式）。"))
 	fmt.Printf("提示2：%s\n", color.HiRedString("流式读写，内存占用稳定，支持超大数据文件，但请注意 Excel 最大仅支持 1048576 行。"))
 }
 
@@ -227,11 +374,17 @@ func main() {
 		return
 	}
 	if srcPath == "" {
-		fmt.Println("未选择 Excel 文件，无可拆分")
+		fmt.Println("未选择数据文件，无可拆分")
 		util.WaitForExit()
 		return
 	}
 
+	unlocked, err := unlockSrcPaths([]string{srcPath})
+	if err != nil {
+		fmt.Println(err)
+		util.WaitForExit()
+		return
+	}
 	splitLine, splitFile, splitDir, splitExt, err := getTarget(srcPath)
 	if err != nil {
 		fmt.Println(err)
@@ -246,7 +399,7 @@ func main() {
 		return
 	}
 
-	err = split(srcPath, splitLine, splitFile, splitDir, splitExt)
+	err = split(srcPath, unlocked, splitLine, splitFile, splitDir, splitExt)
 	if err != nil {
 		fmt.Println(err)
 		if errors.Is(err, context.Canceled) {
@@ -3,7 +3,6 @@ package csv
 import (
 	"bufio"
 	"context"
-	"encoding/csv"
 	"fmt"
 	"math"
 	"os"
@@ -12,37 +11,20 @@ import (
 	"strings"
 	"time"
 
+	"gitee.com/nguaduot/split-xlsx-go/internal/dedup"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
 	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
 	"github.com/fatih/color"
-	"github.com/xuri/excelize/v2"
 )
 
-func getRows(file string) (int, error) {
-	f, err := excelize.OpenFile(file, excelize.Options{
-		UnzipSizeLimit:    8 << 30, // 8GB
-		UnzipXMLSizeLimit: 4 << 30, // 4GB
-	})
-	if err != nil {
-		return 0, err
-	}
-	defer f.Close()
-	rows, err := f.Rows(f.GetSheetName(0))
-	if err != nil {
-		return 0, err
-	}
-	count := 0
-	for rows.Next() {
-		count++
-	}
-	if count > 0 { // 减去行首
-		count--
-	}
-	return count, nil
+func getRows(file string, opts option.Source) (int, error) {
+	return source.Count(file, opts)
 }
 
 // MergeXlsx2csv
 // Excel 本身是 zip + XML，磁盘和解压是瓶颈，并发通常收益不大，因此不采用并发读
-func MergeXlsx2csv(srcPaths []string, tarPath string, ctx context.Context) error {
+func MergeXlsx2csv(srcPaths []string, tarPath string, opts option.Options, ctx context.Context) error {
 	start := time.Now()
 	fmt.Println("正在解析…")
 
@@ -57,22 +39,31 @@ func MergeXlsx2csv(srcPaths []string, tarPath string, ctx context.Context) error
 		fmt.Printf("数据文件%d：%s，%s\n", i+1, color.HiYellowString(filepath.Base(file)), util.SizeReadable(srcSizes[i]))
 	}
 
+	// 去重需预先扫描全部数据文件
+	var filter *dedup.Filter
+	if opts.Dedup.Enabled {
+		f, err := dedup.Scan(srcPaths, opts.Dedup, opts.Source, ctx)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		filter = f
+	}
+
 	tarFile, err := os.Create(tarPath)
 	if err != nil {
 		return err
 	}
-	// Go 全局默认 UTF-8，写 UTF-8 BOM，确保 Windows Excel 能正常打开
-	tarFile.Write([]byte{0xEF, 0xBB, 0xBF})
+	writeBOM(tarFile, opts.CSV)
 
 	// 不直接使用 writer := csv.NewWriter(tarFile)
 	// 使用 bufio.Writer 减少 syscall
 	bufWriter := bufio.NewWriterSize(tarFile, 1<<20)
-	writer := csv.NewWriter(bufWriter)
-	// writer.Comma = '\t' // 默认为 ,
-	// writer.UseCRLF = true // 默认为 LF
+	unmapped := newUnmappedChars(opts.CSV)
+	writer := newWriter(bufWriter, tarPath, opts.CSV, unmapped)
 
 	fmt.Printf("正在合并… %s\n", color.HiBlackString("(停止：Ctrl+C)"))
-	wroteHeader := false
+	var rowHeader []string // 首个数据文件的行首，nil 表示尚未写入
 	totalRows := 0
 	for i, file := range srcPaths {
 		select {
@@ -83,31 +74,19 @@ func MergeXlsx2csv(srcPaths []string, tarPath string, ctx context.Context) error
 			return ctx.Err()
 		default:
 		} // 响应 Ctrl+C 打断
-		f, err := excelize.OpenFile(file, excelize.Options{
-			UnzipSizeLimit:    8 << 30, // 8GB
-			UnzipXMLSizeLimit: 4 << 30, // 4GB
-		})
+		iter, err := source.Open(file, opts.Source) // 流式读取（不会一次性加载整表）
 		if err != nil {
 			writer.Flush()
 			bufWriter.Flush()
 			tarFile.Close()
 			return err
 		}
-		// defer f.Close() // 循环中不使用该方法
-		sheet := f.GetSheetName(0) // 只读第一张表
-		iter, err := f.Rows(sheet) // 流式读取（不会一次性加载整表）
-		if err != nil {
-			f.Close()
-			writer.Flush()
-			bufWriter.Flush()
-			tarFile.Close()
-			return err
-		}
+		// defer iter.Close() // 循环中不使用该方法
 		fileRows := 0
 		for iter.Next() {
 			select {
 			case <-ctx.Done():
-				f.Close()
+				iter.Close()
 				writer.Flush()
 				bufWriter.Flush()
 				tarFile.Close()
@@ -118,20 +97,40 @@ func MergeXlsx2csv(srcPaths []string, tarPath string, ctx context.Context) error
 			totalRows++
 			row, err := iter.Columns()
 			if err != nil {
-				f.Close()
+				iter.Close()
 				writer.Flush()
 				bufWriter.Flush()
 				tarFile.Close()
 				return err
 			}
 			if fileRows == 1 { // 控制只写一次行首
-				if wroteHeader {
+				if rowHeader != nil {
+					continue
+				}
+				rowHeader = row
+				if err = writer.Write(rowHeader); err != nil {
+					iter.Close()
+					writer.Flush()
+					bufWriter.Flush()
+					tarFile.Close()
+					return err
+				}
+				continue
+			} else if filter != nil { // 去除重复行
+				drop, err := filter.Drop(i)
+				if err != nil {
+					iter.Close()
+					writer.Flush()
+					bufWriter.Flush()
+					tarFile.Close()
+					return err
+				}
+				if drop {
 					continue
 				}
-				wroteHeader = true
 			}
-			if err = writer.Write(row); err != nil {
-				f.Close()
+			if err = writer.Write(formatRow(row, len(rowHeader), opts)); err != nil {
+				iter.Close()
 				writer.Flush()
 				bufWriter.Flush()
 				tarFile.Close()
@@ -145,7 +144,7 @@ func MergeXlsx2csv(srcPaths []string, tarPath string, ctx context.Context) error
 				}
 			}
 		}
-		f.Close()
+		iter.Close()
 		sizeDone, sizeTodo := int64(0), int64(0)
 		for j := range srcSizes {
 			if j <= i {
@@ -164,18 +163,24 @@ func MergeXlsx2csv(srcPaths []string, tarPath string, ctx context.Context) error
 	writer.Flush()
 	bufWriter.Flush()
 	tarFile.Close()
+	dropped := 0
+	if filter != nil {
+		filter.Report(srcPaths)
+		dropped = filter.Total()
+	}
+	unmapped.Report()
 	info, err := os.Stat(tarPath)
 	if err != nil {
 		return err
 	}
 	fmt.Printf("合并完成，%s，共%s数据，耗时%s\n",
-		util.SizeReadable(info.Size()), color.HiYellowString("%d行", totalRows-len(srcPaths)), util.Cost(start))
+		util.SizeReadable(info.Size()), color.HiYellowString("%d行", totalRows-len(srcPaths)-dropped), util.Cost(start))
 	fmt.Printf("合并文件：%s%s\n", strings.TrimSuffix(tarPath, filepath.Base(tarPath)),
 		color.HiYellowString(filepath.Base(tarPath)))
 	return nil
 }
 
-func SplitXlsx2csvByLine(srcPath string, tarDir string, lineCount int, ctx context.Context) error {
+func SplitXlsx2csvByLine(srcPath string, tarDir string, lineCount int, opts option.Options, ctx context.Context) error {
 	start := time.Now()
 	info, err := os.Stat(srcPath)
 	if err != nil {
@@ -184,25 +189,21 @@ func SplitXlsx2csvByLine(srcPath string, tarDir string, lineCount int, ctx conte
 	fmt.Printf("数据文件：%s，%s\n", color.HiYellowString(filepath.Base(srcPath)), util.SizeReadable(info.Size()))
 
 	fmt.Printf("正在按每%s拆分… %s\n", color.HiYellowString("%d行", lineCount), color.HiBlackString("(停止：Ctrl+C)"))
-	srcFile, err := excelize.OpenFile(srcPath, excelize.Options{
-		UnzipSizeLimit:    8 << 30, // 8GB
-		UnzipXMLSizeLimit: 4 << 30, // 4GB
-	})
+	iter, err := source.Open(srcPath, opts.Source)
 	if err != nil {
 		return err
 	}
-	srcSheet := srcFile.GetSheetName(0)
-	iter, err := srcFile.Rows(srcSheet)
 	iter.Next()
 	rowHeader, err := iter.Columns()
 	if err != nil {
-		srcFile.Close()
+		iter.Close()
 		return err
 	}
 	var (
 		tarFile    *os.File
 		bufWriter  *bufio.Writer
-		writer     *csv.Writer
+		writer     *rowWriter
+		unmapped   = newUnmappedChars(opts.CSV)
 		tarPath    string
 		tarPathIdx int
 		totalRows  int
@@ -217,20 +218,19 @@ func SplitXlsx2csvByLine(srcPath string, tarDir string, lineCount int, ctx conte
 				fmt.Printf("数据文件%d：写入完成，共%d行\n", tarPathIdx, fileRows)
 			}
 			tarPathIdx++
-			tarPath = filepath.Join(tarDir, fmt.Sprintf("%s-%d.csv", filepath.Base(tarDir), tarPathIdx))
+			tarPath = filepath.Join(tarDir, fmt.Sprintf("%s-%d%s", filepath.Base(tarDir), tarPathIdx, fileExt(opts.CSV)))
 			tarFile, err = os.Create(tarPath)
 			if err != nil {
 				return err
 			}
-			// Go 全局默认 UTF-8，写 UTF-8 BOM，确保 Windows Excel 能正常打开
-			tarFile.Write([]byte{0xEF, 0xBB, 0xBF})
+			writeBOM(tarFile, opts.CSV)
 			bufWriter = bufio.NewWriterSize(tarFile, 1<<20)
-			writer = csv.NewWriter(bufWriter)
+			writer = newWriter(bufWriter, tarPath, opts.CSV, unmapped)
 			if err = writer.Write(rowHeader); err != nil {
 				writer.Flush()
 				bufWriter.Flush()
 				tarFile.Close()
-				srcFile.Close()
+				iter.Close()
 				return err
 			}
 			fileRows = 0
@@ -240,7 +240,7 @@ func SplitXlsx2csvByLine(srcPath string, tarDir string, lineCount int, ctx conte
 			writer.Flush()
 			bufWriter.Flush()
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return ctx.Err()
 		default:
 		} // 响应 Ctrl+C 打断
@@ -251,14 +251,14 @@ func SplitXlsx2csvByLine(srcPath string, tarDir string, lineCount int, ctx conte
 			writer.Flush()
 			bufWriter.Flush()
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return err
 		}
-		if err = writer.Write(row); err != nil {
+		if err = writer.Write(formatRow(row, len(rowHeader), opts)); err != nil {
 			writer.Flush()
 			bufWriter.Flush()
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return err
 		}
 		if totalRows%10000 == 0 {
@@ -275,19 +275,20 @@ func SplitXlsx2csvByLine(srcPath string, tarDir string, lineCount int, ctx conte
 		tarFile.Close()
 		fmt.Printf("数据文件%d：写入完成，共%d行\n", tarPathIdx, fileRows)
 	}
-	srcFile.Close()
+	iter.Close()
 	fmt.Printf("拆分完成，共%s，分为%s文件，耗时%s\n",
 		color.HiYellowString("%d行", totalRows), color.HiYellowString("%d个", tarPathIdx), util.Cost(start))
 	fmt.Printf("拆分文件夹：%s%s\n", strings.TrimSuffix(tarDir, filepath.Base(tarDir)),
 		color.HiYellowString(filepath.Base(tarDir)))
+	unmapped.Report()
 	return nil
 }
 
-func SplitXlsx2csvByFile(srcPath string, tarDir string, fileCount int, ctx context.Context) error {
+func SplitXlsx2csvByFile(srcPath string, tarDir string, fileCount int, opts option.Options, ctx context.Context) error {
 	start := time.Now()
 	fmt.Println("正在解析…")
 
-	srcRows, err := getRows(srcPath)
+	srcRows, err := getRows(srcPath, opts.Source)
 	if err != nil {
 		return err
 	}
@@ -303,25 +304,21 @@ func SplitXlsx2csvByFile(srcPath string, tarDir string, fileCount int, ctx conte
 		color.HiYellowString(filepath.Base(srcPath)), util.SizeReadable(info.Size()), srcRows)
 
 	fmt.Printf("正在拆分为%s文件… %s\n", color.HiYellowString("%d个", fileCount), color.HiBlackString("(停止：Ctrl+C)"))
-	srcFile, err := excelize.OpenFile(srcPath, excelize.Options{
-		UnzipSizeLimit:    8 << 30, // 8GB
-		UnzipXMLSizeLimit: 4 << 30, // 4GB
-	})
+	iter, err := source.Open(srcPath, opts.Source)
 	if err != nil {
 		return err
 	}
-	srcSheet := srcFile.GetSheetName(0)
-	iter, err := srcFile.Rows(srcSheet)
 	iter.Next()
 	rowHeader, err := iter.Columns()
 	if err != nil {
-		srcFile.Close()
+		iter.Close()
 		return err
 	}
 	var (
 		tarFile    *os.File
 		bufWriter  *bufio.Writer
-		writer     *csv.Writer
+		writer     *rowWriter
+		unmapped   = newUnmappedChars(opts.CSV)
 		tarPath    string
 		tarPathIdx int
 		totalRows  int
@@ -339,21 +336,20 @@ func SplitXlsx2csvByFile(srcPath string, tarDir string, fileCount int, ctx conte
 			}
 			startFile = time.Now()
 			tarPathIdx++
-			nameFmt := fmt.Sprintf("%%s-%%0%dd.csv", len(strconv.Itoa(fileCount)))
+			nameFmt := fmt.Sprintf("%%s-%%0%dd%s", len(strconv.Itoa(fileCount)), fileExt(opts.CSV))
 			tarPath = filepath.Join(tarDir, fmt.Sprintf(nameFmt, filepath.Base(tarDir), tarPathIdx))
 			tarFile, err = os.Create(tarPath)
 			if err != nil {
 				return err
 			}
-			// Go 全局默认 UTF-8，写 UTF-8 BOM，确保 Windows Excel 能正常打开
-			tarFile.Write([]byte{0xEF, 0xBB, 0xBF})
+			writeBOM(tarFile, opts.CSV)
 			bufWriter = bufio.NewWriterSize(tarFile, 1<<20)
-			writer = csv.NewWriter(bufWriter)
+			writer = newWriter(bufWriter, tarPath, opts.CSV, unmapped)
 			if err = writer.Write(rowHeader); err != nil {
 				writer.Flush()
 				bufWriter.Flush()
 				tarFile.Close()
-				srcFile.Close()
+				iter.Close()
 				return err
 			}
 			fileRows = 0
@@ -363,7 +359,7 @@ func SplitXlsx2csvByFile(srcPath string, tarDir string, fileCount int, ctx conte
 			writer.Flush()
 			bufWriter.Flush()
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return ctx.Err()
 		default:
 		} // 响应 Ctrl+C 打断
@@ -374,14 +370,14 @@ func SplitXlsx2csvByFile(srcPath string, tarDir string, fileCount int, ctx conte
 			writer.Flush()
 			bufWriter.Flush()
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return err
 		}
-		if err = writer.Write(row); err != nil {
+		if err = writer.Write(formatRow(row, len(rowHeader), opts)); err != nil {
 			writer.Flush()
 			bufWriter.Flush()
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return err
 		}
 		if totalRows%10000 == 0 {
@@ -398,10 +394,11 @@ func SplitXlsx2csvByFile(srcPath string, tarDir string, fileCount int, ctx conte
 		tarFile.Close()
 		fmt.Printf("数据文件%d：写入完成，共%s\n", tarPathIdx, color.HiYellowString("%d行", fileRows))
 	}
-	srcFile.Close()
+	iter.Close()
 	fmt.Printf("拆分完成，%d个文件，每个%s，耗时%s\n",
 		tarPathIdx, color.HiYellowString("%d行", lineCount), util.Cost(start))
 	fmt.Printf("拆分文件夹：%s%s\n", strings.TrimSuffix(tarDir, filepath.Base(tarDir)),
 		color.HiYellowString(filepath.Base(tarDir)))
+	unmapped.Report()
 	return nil
 }
@@ -0,0 +1,117 @@
+package csv
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestSplitLeadingZeros(t *testing.T) {
+	f := excelize.NewFile()
+	zipFmt := "000000"
+	zip, err := f.NewStyle(&excelize.Style{CustomNumFmt: &zipFmt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := f.NewStyle(&excelize.Style{NumFmt: 49})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := []struct {
+		axis  string
+		value any
+		style int
+	}{
+		{"A1", "zip", 0}, {"B1", "code", 0}, {"C1", "id", 0},
+		{"A2", 10020, zip}, {"B2", "0012", text}, {"C2", "110101199003071234", 0},
+	}
+	for _, c := range cells {
+		if err := f.SetCellValue("Sheet1", c.axis, c.value); err != nil {
+			t.Fatal(err)
+		}
+		if c.style != 0 {
+			if err := f.SetCellStyle("Sheet1", c.axis, c.axis, c.style); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	src := filepath.Join(t.TempDir(), "src.xlsx")
+	if err := f.SaveAs(src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tarDir := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(tarDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	opts := option.Options{CSV: option.CSVDialect{NoBOM: true}}
+	if err := SplitXlsx2csvByLine(src, tarDir, 10, opts, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(tarDir, "out-1.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "zip,code,id\n010020,0012,110101199003071234\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSplitNumFmt(t *testing.T) {
+	f := excelize.NewFile()
+	rateFmt := "0.0%"
+	rate, err := f.NewStyle(&excelize.Style{CustomNumFmt: &rateFmt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount, err := f.NewStyle(&excelize.Style{NumFmt: 4}) // #,##0.00
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := []struct {
+		axis  string
+		value any
+		style int
+	}{
+		{"A1", "rate", 0}, {"B1", "amount", 0},
+		{"A2", 0.125, rate}, {"B2", 1234.5, amount},
+		{"A3", 1, rate}, {"B3", -0.5, amount},
+	}
+	for _, c := range cells {
+		if err := f.SetCellValue("Sheet1", c.axis, c.value); err != nil {
+			t.Fatal(err)
+		}
+		if c.style != 0 {
+			if err := f.SetCellStyle("Sheet1", c.axis, c.axis, c.style); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	src := filepath.Join(t.TempDir(), "src.xlsx")
+	if err := f.SaveAs(src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tarDir := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(tarDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	opts := option.Options{CSV: option.CSVDialect{NoBOM: true}}
+	if err := SplitXlsx2csvByLine(src, tarDir, 10, opts, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(tarDir, "out-1.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与 Excel 中的显示一致
+	if want := "rate,amount\n12.5%,\"1,234.50\"\n100.0%,-0.50\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package csv
+
+import (
+	"slices"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+)
+
+// formatDates
+// ISO 格式的日期、日期时间按指定格式输出，未指定则保持原样；有改动时返回副本，不修改原行
+func formatDates(row []string, format option.DateFormat) []string {
+	if format.Date == "" && format.DateTime == "" {
+		return row
+	}
+	var res []string
+	for c, val := range row {
+		t, kind := source.ParseDate(val)
+		layout := ""
+		switch kind {
+		case source.DateOnly:
+			layout = format.Date
+		case source.DateWithTime:
+			layout = format.DateTime
+		}
+		if layout == "" {
+			continue
+		}
+		if res == nil {
+			res = slices.Clone(row)
+		}
+		res[c] = t.Format(layout)
+	}
+	if res == nil {
+		return row
+	}
+	return res
+}
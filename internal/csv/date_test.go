@@ -0,0 +1,37 @@
+package csv
+
+import (
+	"slices"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+)
+
+func TestFormatDates(t *testing.T) {
+	row := []string{"2026-01-02", "2026-01-02 03:04:05", "13:30:00", "x", "", "20260102"}
+	tests := []struct {
+		name   string
+		format option.DateFormat
+		want   []string
+	}{
+		{"保持 ISO", option.DateFormat{}, row},
+		{"仅日期", option.DateFormat{Date: "2006/1/2"},
+			[]string{"2026/1/2", "2026-01-02 03:04:05", "13:30:00", "x", "", "20260102"}},
+		{"仅日期时间", option.DateFormat{DateTime: "2006/01/02 15:04"},
+			[]string{"2026-01-02", "2026/01/02 03:04", "13:30:00", "x", "", "20260102"}},
+		{"日期与日期时间", option.DateFormat{Date: "02.01.2006", DateTime: "02.01.2006 15:04:05"},
+			[]string{"02.01.2026", "02.01.2026 03:04:05", "13:30:00", "x", "", "20260102"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := slices.Clone(row)
+			got := formatDates(row, tt.format)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(row, orig) {
+				t.Errorf("原行被修改：%q", row)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package csv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/join"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+)
+
+// JoinXlsx2csv
+// 按键列横向拼接左右两表（替代 VLOOKUP）
+func JoinXlsx2csv(leftPath string, rightPath string, tarPath string, opts option.Options, ctx context.Context) error {
+	start := time.Now()
+	fmt.Println("正在解析…")
+
+	for i, file := range []string{leftPath, rightPath} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s：%s，%s\n", []string{"左表", "右表"}[i],
+			color.HiYellowString(filepath.Base(file)), util.SizeReadable(info.Size()))
+	}
+
+	joiner, err := join.Open(leftPath, rightPath, opts.Join, opts.Source, ctx)
+	if err != nil {
+		return err
+	}
+	defer joiner.Close()
+
+	tarFile, err := os.Create(tarPath)
+	if err != nil {
+		return err
+	}
+	writeBOM(tarFile, opts.CSV)
+	bufWriter := bufio.NewWriterSize(tarFile, 1<<20)
+	unmapped := newUnmappedChars(opts.CSV)
+	writer := newWriter(bufWriter, tarPath, opts.CSV, unmapped)
+	closeAll := func() {
+		writer.Flush()
+		bufWriter.Flush()
+		tarFile.Close()
+	}
+	if err = writer.Write(joiner.Header); err != nil {
+		closeAll()
+		return err
+	}
+
+	fmt.Printf("正在按%s拼接… %s\n", color.HiYellowString(opts.Join.Key), color.HiBlackString("(停止：Ctrl+C)"))
+	dataRows := 0
+	for joiner.Next() {
+		select {
+		case <-ctx.Done():
+			closeAll()
+			return ctx.Err()
+		default:
+		} // 响应 Ctrl+C 打断
+		if err = writer.Write(formatRow(joiner.Row(), len(joiner.Header), opts)); err != nil {
+			closeAll()
+			return err
+		}
+		dataRows++
+		if dataRows%10000 == 0 {
+			fmt.Printf("已拼接%d行，耗时%s\n", dataRows, util.Cost(start))
+		}
+	}
+	if err := joiner.Err(); err != nil {
+		closeAll()
+		return err
+	}
+	closeAll()
+	joiner.Report()
+	unmapped.Report()
+	info, err := os.Stat(tarPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("拼接完成，%s，共%s数据，耗时%s\n",
+		util.SizeReadable(info.Size()), color.HiYellowString("%d行", dataRows), util.Cost(start))
+	fmt.Printf("拼接文件：%s%s\n", strings.TrimSuffix(tarPath, filepath.Base(tarPath)),
+		color.HiYellowString(filepath.Base(tarPath)))
+	return nil
+}
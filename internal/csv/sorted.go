@@ -0,0 +1,119 @@
+package csv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/dedup"
+	"gitee.com/nguaduot/split-xlsx-go/internal/kmerge"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+)
+
+// MergeXlsx2csvSorted
+// 有序合并：各数据文件已按键列排序，并发流式读取并按键列交错写入，合并结果整体有序
+func MergeXlsx2csvSorted(srcPaths []string, tarPath string, opts option.Options, ctx context.Context) error {
+	start := time.Now()
+	fmt.Println("正在解析…")
+
+	for i, file := range srcPaths {
+		f, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("数据文件%d：%s，%s\n", i+1, color.HiYellowString(filepath.Base(file)), util.SizeReadable(f.Size()))
+	}
+
+	// 去重需预先扫描全部数据文件
+	var filter *dedup.Filter
+	if opts.Dedup.Enabled {
+		f, err := dedup.Scan(srcPaths, opts.Dedup, opts.Source, ctx)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		filter = f
+	}
+
+	merger, err := kmerge.Open(srcPaths, opts.Sort, opts.Source, ctx)
+	if err != nil {
+		return err
+	}
+	defer merger.Close()
+
+	tarFile, err := os.Create(tarPath)
+	if err != nil {
+		return err
+	}
+	writeBOM(tarFile, opts.CSV)
+	bufWriter := bufio.NewWriterSize(tarFile, 1<<20)
+	unmapped := newUnmappedChars(opts.CSV)
+	writer := newWriter(bufWriter, tarPath, opts.CSV, unmapped)
+	closeAll := func() {
+		writer.Flush()
+		bufWriter.Flush()
+		tarFile.Close()
+	}
+	if err = writer.Write(merger.Header); err != nil {
+		closeAll()
+		return err
+	}
+
+	fmt.Printf("正在按%s有序合并… %s\n", color.HiYellowString(opts.Sort.Key), color.HiBlackString("(停止：Ctrl+C)"))
+	dataRows := 0
+	for merger.Next() {
+		select {
+		case <-ctx.Done():
+			closeAll()
+			return ctx.Err()
+		default:
+		} // 响应 Ctrl+C 打断
+		i, _, row := merger.Row()
+		if filter != nil { // 去除重复行
+			drop, err := filter.Drop(i)
+			if err != nil {
+				closeAll()
+				return err
+			}
+			if drop {
+				continue
+			}
+		}
+		if err = writer.Write(formatRow(row, len(merger.Header), opts)); err != nil {
+			closeAll()
+			return err
+		}
+		dataRows++
+		if dataRows%10000 == 0 {
+			fmt.Printf("已合并%d行，耗时%s\n", dataRows, util.Cost(start))
+		}
+	}
+	if err := merger.Err(); err != nil {
+		closeAll()
+		return err
+	}
+	closeAll()
+	for i, rows := range merger.FileRows() {
+		fmt.Printf("数据文件%d：读取完成，共%s\n", i+1, color.HiYellowString("%d行", rows))
+	}
+	merger.Report()
+	if filter != nil {
+		filter.Report(srcPaths)
+	}
+	unmapped.Report()
+	info, err := os.Stat(tarPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("合并完成，%s，共%s数据，耗时%s\n",
+		util.SizeReadable(info.Size()), color.HiYellowString("%d行", dataRows), util.Cost(start))
+	fmt.Printf("合并文件：%s%s\n", strings.TrimSuffix(tarPath, filepath.Base(tarPath)),
+		color.HiYellowString(filepath.Base(tarPath)))
+	return nil
+}
@@ -0,0 +1,229 @@
+package csv
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/fatih/color"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// 无法编码的字符的替换字符
+const unmappedSub = "?"
+
+// 无法编码的字符最多逐个输出的日志数
+const unmappedLogLimit = 10
+
+// rowWriter
+// 按导出格式写入 CSV 行：分隔符、换行符沿用 encoding/csv，所有字段加引号时自行转义（空值的表示除外）；
+// 非 UTF-8 编码时逐字段检查，无法编码的字符替换为 ? 并计入 unmapped
+type rowWriter struct {
+	w        *bufio.Writer
+	csv      *csv.Writer
+	dialect  option.CSVDialect
+	encoder  *encoding.Encoder // 检查字段能否编码，UTF-8 为 nil
+	unmapped *unmappedChars
+	path     string
+	rows     int // 已写入行数（含行首）
+}
+
+func newWriter(w *bufio.Writer, path string, dialect option.CSVDialect, unmapped *unmappedChars) *rowWriter {
+	r := &rowWriter{dialect: dialect, unmapped: unmapped, path: path}
+	if enc := textEncoding(dialect.Encoding); enc != nil {
+		r.encoder = enc.NewEncoder()
+		w = bufio.NewWriterSize(transform.NewWriter(w, enc.NewEncoder()), 64<<10)
+	}
+	r.w, r.csv = w, csv.NewWriter(w)
+	if dialect.Comma != 0 {
+		r.csv.Comma = dialect.Comma
+	}
+	r.csv.UseCRLF = dialect.CRLF
+	return r
+}
+
+// textEncoding
+// 导出编码对应的编码器，UTF-8 为 nil
+func textEncoding(name string) encoding.Encoding {
+	switch name {
+	case "GBK":
+		return simplifiedchinese.GBK
+	case "GB18030":
+		return simplifiedchinese.GB18030
+	}
+	return nil
+}
+
+func (r *rowWriter) Write(row []string) error {
+	r.rows++
+	if r.encoder != nil {
+		row = r.encodable(row)
+	}
+	if !r.dialect.QuoteAll {
+		return r.csv.Write(row)
+	}
+	for c, field := range row {
+		if c > 0 {
+			if _, err := r.w.WriteRune(r.csv.Comma); err != nil {
+				return err
+			}
+		}
+		if field != "" && field == r.dialect.Null { // 空值不加引号，以便导入时与文本区分
+			if _, err := r.w.WriteString(field); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := r.w.WriteString(`"` + strings.ReplaceAll(field, `"`, `""`) + `"`); err != nil {
+			return err
+		}
+	}
+	if r.dialect.CRLF {
+		_, err := r.w.WriteString("\r\n")
+		return err
+	}
+	return r.w.WriteByte('\n')
+}
+
+// encodable
+// 无法以导出编码表示的字符替换为 ?；有改动时返回副本，不修改原行
+func (r *rowWriter) encodable(row []string) []string {
+	var res []string
+	for c, field := range row {
+		if isASCII(field) {
+			continue
+		}
+		if _, err := r.encoder.String(field); err == nil {
+			continue
+		}
+		var sb strings.Builder
+		for i := 0; i < len(field); {
+			ch, size := utf8.DecodeRuneInString(field[i:])
+			s := field[i : i+size]
+			i += size
+			if _, err := r.encoder.String(s); err != nil {
+				r.unmapped.add(ch, r.path, r.rows, c+1)
+				s = unmappedSub
+			}
+			sb.WriteString(s)
+		}
+		if res == nil {
+			res = slices.Clone(row)
+		}
+		res[c] = sb.String()
+	}
+	if res == nil {
+		return row
+	}
+	return res
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *rowWriter) Flush() {
+	r.csv.Flush()
+	r.w.Flush()
+}
+
+// fileExt
+// 拆分文件的扩展名：制表符分隔为 .tsv
+func fileExt(dialect option.CSVDialect) string {
+	if dialect.Comma == '\t' {
+		return ".tsv"
+	}
+	return ".csv"
+}
+
+// writeBOM
+// Go 全局默认 UTF-8，写 UTF-8 BOM，确保 Windows Excel 能正常打开；部分导入系统不接受 BOM，可关闭，GBK、GB18030 编码不写
+func writeBOM(f *os.File, dialect option.CSVDialect) {
+	if !dialect.NoBOM && dialect.Encoding == "" {
+		f.Write([]byte{0xEF, 0xBB, 0xBF})
+	}
+}
+
+// unmappedChars
+// 无法以导出编码表示而替换为 ? 的字符，同一导出任务的各文件共用
+type unmappedChars struct {
+	encoding string
+	counts   map[rune]int
+	order    []rune
+	total    int
+}
+
+func newUnmappedChars(dialect option.CSVDialect) *unmappedChars {
+	return &unmappedChars{encoding: dialect.Encoding, counts: make(map[rune]int)}
+}
+
+func (u *unmappedChars) add(ch rune, path string, line int, col int) {
+	if u.counts[ch] == 0 {
+		u.order = append(u.order, ch)
+		if len(u.order) <= unmappedLogLimit {
+			log.Printf("%s：第%d行第%d列，字符 %q（U+%04X）无法以 %s 编码，已替换为 %s",
+				filepath.Base(path), line, col, ch, ch, u.encoding, unmappedSub)
+		}
+	}
+	u.counts[ch]++
+	u.total++
+}
+
+// Report
+// 输出无法编码的字符及次数
+func (u *unmappedChars) Report() {
+	if u.total == 0 {
+		return
+	}
+	items := make([]string, 0, len(u.order))
+	for _, ch := range u.order[:min(len(u.order), unmappedLogLimit)] {
+		items = append(items, fmt.Sprintf("%q %d次", ch, u.counts[ch]))
+	}
+	if len(u.order) > unmappedLogLimit {
+		items = append(items, fmt.Sprintf("等%d种", len(u.order)))
+	}
+	hint := ""
+	if u.encoding == "GBK" {
+		hint = "，可改用 -encoding gb18030"
+	}
+	fmt.Printf("%s%s个字符无法以 %s 编码，已替换为 %s%s：%s\n", color.HiRedString("注意："),
+		color.HiRedString("%d", u.total), u.encoding, unmappedSub, hint, strings.Join(items, "，"))
+}
+
+// formatRow
+// 数据行按导出选项格式化：日期格式、空值表示（行尾不足行首列数 width 的列补齐后同样写为空值）；有改动时返回副本，不修改原行
+func formatRow(row []string, width int, opts option.Options) []string {
+	row = formatDates(row, opts.Date)
+	if opts.CSV.Null == "" {
+		return row
+	}
+	res, cloned := row, false
+	if len(row) < width {
+		res, cloned = make([]string, width), true
+		copy(res, row)
+	}
+	for c, val := range res {
+		if val != "" {
+			continue
+		}
+		if !cloned {
+			res, cloned = slices.Clone(row), true
+		}
+		res[c] = opts.CSV.Null
+	}
+	return res
+}
@@ -0,0 +1,197 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"gitee.com/nguaduot/split-xlsx-go/internal/testutil"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestFormatRow(t *testing.T) {
+	null := option.Options{CSV: option.CSVDialect{Null: "NULL"}}
+	tests := []struct {
+		name  string
+		row   []string
+		width int
+		opts  option.Options
+		want  []string
+	}{
+		{"未设空值", []string{"a", ""}, 3, option.Options{}, []string{"a", ""}},
+		{"空值", []string{"a", "", "c"}, 3, null, []string{"a", "NULL", "c"}},
+		{"行尾不足补齐", []string{"a"}, 3, null, []string{"a", "NULL", "NULL"}},
+		{"空行", nil, 2, null, []string{"NULL", "NULL"}},
+		{"超出行首的列保留", []string{"a", "", ""}, 2, null, []string{"a", "NULL", "NULL"}},
+		{"无空值", []string{"a", "b"}, 2, null, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := slices.Clone(tt.row)
+			got := formatRow(tt.row, tt.width, tt.opts)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("formatRow = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(tt.row, orig) {
+				t.Errorf("原行被修改：%q", tt.row)
+			}
+		})
+	}
+}
+
+func TestMergeDialect(t *testing.T) {
+	dir := t.TempDir()
+	a := testutil.WriteFile(t, dir, "a.csv", "k,v,\n1,x\n2,\"y;z\",3\n")
+	b := testutil.WriteFile(t, dir, "b.csv", "k,v,\n3\n")
+	tests := []struct {
+		name    string
+		dialect option.CSVDialect
+		want    string
+	}{
+		{"缺省", option.CSVDialect{NoBOM: true}, "k,v,\n1,x\n2,y;z,3\n3\n"},
+		{"BOM", option.CSVDialect{}, "\xEF\xBB\xBFk,v,\n1,x\n2,y;z,3\n3\n"},
+		{"分号与 CRLF", option.CSVDialect{Comma: ';', CRLF: true, NoBOM: true}, "k;v;\r\n1;x\r\n2;\"y;z\";3\r\n3\r\n"},
+		{"全部加引号", option.CSVDialect{QuoteAll: true, NoBOM: true}, "\"k\",\"v\",\"\"\n\"1\",\"x\"\n\"2\",\"y;z\",\"3\"\n\"3\"\n"},
+		{"空值按行首列数补齐", option.CSVDialect{Null: `\N`, NoBOM: true}, "k,v,\n1,x,\\N\n2,y;z,3\n3,\\N,\\N\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tarPath := filepath.Join(t.TempDir(), "out.csv")
+			if err := MergeXlsx2csv([]string{a, b}, tarPath, option.Options{CSV: tt.dialect}, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(tarPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteEncoding(t *testing.T) {
+	rows := [][]string{{"名称", "备注"}, {"中文", "€ 😀"}, {"𠀀", `"引号"`}}
+	tests := []struct {
+		encoding string
+		decoder  *encoding.Decoder
+		want     string
+		unmapped int
+	}{
+		{"", nil, "名称,备注\n中文,€ 😀\n𠀀,\"\"\"引号\"\"\"\n", 0},
+		{"GBK", simplifiedchinese.GBK.NewDecoder(), "名称,备注\n中文,€ ?\n?,\"\"\"引号\"\"\"\n", 2},
+		{"GB18030", simplifiedchinese.GB18030.NewDecoder(), "名称,备注\n中文,€ 😀\n𠀀,\"\"\"引号\"\"\"\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			var buf bytes.Buffer
+			bw := bufio.NewWriter(&buf)
+			dialect := option.CSVDialect{Encoding: tt.encoding}
+			unmapped := newUnmappedChars(dialect)
+			w := newWriter(bw, "out.csv", dialect, unmapped)
+			for _, row := range rows {
+				if err := w.Write(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w.Flush()
+			bw.Flush()
+			got := buf.Bytes()
+			if tt.decoder != nil {
+				var err error
+				if got, err = tt.decoder.Bytes(got); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if unmapped.total != tt.unmapped {
+				t.Errorf("unmapped = %d, want %d", unmapped.total, tt.unmapped)
+			}
+		})
+	}
+}
+
+func TestUnmappedChars(t *testing.T) {
+	u := newUnmappedChars(option.CSVDialect{Encoding: "GBK"})
+	for _, ch := range []rune("😀𠀀😀😀") {
+		u.add(ch, "out.csv", 2, 1)
+	}
+	if u.total != 4 || !slices.Equal(u.order, []rune("😀𠀀")) || u.counts['😀'] != 3 {
+		t.Errorf("total %d order %q counts %v", u.total, u.order, u.counts)
+	}
+	u.Report()
+}
+
+func TestWriteBOM(t *testing.T) {
+	tests := []struct {
+		dialect option.CSVDialect
+		want    string
+	}{
+		{option.CSVDialect{}, "\xEF\xBB\xBF"},
+		{option.CSVDialect{NoBOM: true}, ""},
+		{option.CSVDialect{Encoding: "GBK"}, ""},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "out.csv")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeBOM(f, tt.dialect)
+		f.Close()
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("writeBOM(%+v) = %q, want %q", tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestMergeEncodingRoundTrip(t *testing.T) {
+	want := [][]string{{"编号", "名称"}, {"1", "甲,乙"}, {"2", "丙"}}
+	src := testutil.WriteFile(t, t.TempDir(), "a.csv", "编号,名称\n1,\"甲,乙\"\n2,丙\n")
+	for _, enc := range []string{"GBK", "GB18030"} {
+		t.Run(enc, func(t *testing.T) {
+			tarPath := filepath.Join(t.TempDir(), "out.csv")
+			opts := option.Options{CSV: option.CSVDialect{Encoding: enc}}
+			if err := MergeXlsx2csv([]string{src}, tarPath, opts, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			d, err := source.DetectCSV(tarPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Encoding != "GB18030" { // GBK 为 GB18030 的子集，按 GB18030 读取
+				t.Errorf("读取编码 = %s, want GB18030", d.Encoding)
+			}
+			rows, err := source.Open(tarPath, option.Source{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var got [][]string
+			for rows.Next() {
+				row, err := rows.Columns()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, row)
+			}
+			if !slices.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("rows = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package dedup
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+)
+
+const (
+	hashSize  = 16               // FNV-128a
+	recSize   = hashSize + 4 + 8 // 哈希 + 文件序号 + 行序号
+	hashBatch = 1 << 20          // 每批 1M 条记录，约 28MB
+)
+
+// Filter
+// 两遍扫描去重：首遍计算每行键值哈希，外部排序后确定需丢弃的行序号；次遍合并时按序号过滤
+// 只在磁盘上保存哈希与行序号，支持远超内存的数据文件
+type Filter struct {
+	files   []*fileDrops
+	rows    int   // 首遍扫描的数据行数
+	drops   int   // 需去除的重复行数
+	Dropped []int // 各数据文件去除的重复行数
+}
+
+// fileDrops
+// 单个数据文件需丢弃的行序号，升序
+type fileDrops struct {
+	drops   *sorter
+	merger  *merger
+	next    uint64 // 下一个需丢弃的行序号
+	hasNext bool
+	seq     uint64 // 当前数据行序号（不含行首）
+}
+
+// Scan
+// 首遍扫描，文件内行序号连续编号，次遍须在各文件内以相同顺序逐行调用 Drop
+// 重复行按数据文件顺序、文件内行顺序判定先后，与次遍各文件交错读取（有序合并）无关
+func Scan(srcPaths []string, opts option.Dedup, srcOpts option.Source, ctx context.Context) (*Filter, error) {
+	start := time.Now()
+	if len(opts.Keys) > 0 {
+		fmt.Printf("正在按%s查找重复行…\n", color.HiYellowString(strings.Join(opts.Keys, "、")))
+	} else {
+		fmt.Println("正在按整行查找重复行…")
+	}
+	hashes := newSorter(recSize, hashBatch)
+	defer hashes.Close()
+	var (
+		keys  []int
+		total int
+		rec   = make([]byte, recSize)
+	)
+	for i, file := range srcPaths {
+		iter, err := source.Open(file, srcOpts)
+		if err != nil {
+			return nil, err
+		}
+		fileRows := 0
+		for iter.Next() {
+			select {
+			case <-ctx.Done():
+				iter.Close()
+				return nil, ctx.Err()
+			default:
+			} // 响应 Ctrl+C 打断
+			fileRows++
+			row, err := iter.Columns()
+			if err != nil {
+				iter.Close()
+				return nil, err
+			}
+			if fileRows == 1 { // 行首
+				if i == 0 {
+					for _, key := range opts.Keys {
+						idx, err := util.ColumnIndexFromKey(row, key)
+						if err != nil {
+							iter.Close()
+							return nil, err
+						}
+						keys = append(keys, idx)
+					}
+				}
+				continue
+			}
+			total++
+			if !blankKey(row, keys) { // 键列全部为空的行不参与去重，均保留
+				copy(rec, hashRow(row, keys))
+				binary.BigEndian.PutUint32(rec[hashSize:], uint32(i))
+				binary.BigEndian.PutUint64(rec[hashSize+4:], uint64(fileRows-2))
+				if err := hashes.Add(rec); err != nil {
+					iter.Close()
+					return nil, err
+				}
+			}
+			if total%100000 == 0 {
+				fmt.Printf("数据文件%d：已扫描%d行；累计耗时%s\n", i+1, fileRows-1, util.Cost(start))
+			}
+		}
+		iter.Close()
+	}
+
+	// 哈希相同的记录按文件序号、行序号升序相邻，保留首行则丢弃其后各行，保留末行则丢弃其前各行
+	m, err := hashes.Sort()
+	if err != nil {
+		return nil, err
+	}
+	filter := &Filter{
+		files:   make([]*fileDrops, len(srcPaths)),
+		rows:    total,
+		Dropped: make([]int, len(srcPaths)),
+	}
+	for i := range filter.files {
+		filter.files[i] = &fileDrops{drops: newSorter(8, hashBatch)}
+	}
+	var (
+		prev    = make([]byte, recSize)
+		hasPrev bool
+	)
+	for {
+		r, err := m.Next()
+		if err != nil {
+			filter.Close()
+			return nil, err
+		}
+		if r == nil {
+			break
+		}
+		if hasPrev && string(r[:hashSize]) == string(prev[:hashSize]) {
+			drop := r[hashSize:]
+			if opts.Keep == option.KeepLast {
+				drop = prev[hashSize:]
+			}
+			file := filter.files[binary.BigEndian.Uint32(drop)]
+			if err := file.drops.Add(drop[4:]); err != nil {
+				filter.Close()
+				return nil, err
+			}
+			filter.drops++
+		}
+		copy(prev, r)
+		hasPrev = true
+	}
+	for _, file := range filter.files {
+		if file.merger, err = file.drops.Sort(); err != nil {
+			filter.Close()
+			return nil, err
+		}
+		if err := file.advance(); err != nil {
+			filter.Close()
+			return nil, err
+		}
+	}
+	fmt.Printf("查找完成，共%d行，耗时%s\n", total, util.Cost(start))
+	return filter, nil
+}
+
+// blankKey
+// 按列去重时键列是否全部为空（去除首尾空格），空键值不视为相同，同拼接时空键值不匹配任何行
+func blankKey(row []string, keys []int) bool {
+	if len(keys) == 0 {
+		return false
+	}
+	for _, k := range keys {
+		if k < len(row) && strings.TrimSpace(row[k]) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// hashRow
+// 逐格写入长度与内容，避免拼接歧义；整行去重时忽略末尾空格
+func hashRow(row []string, keys []int) []byte {
+	h := fnv.New128a()
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	write := func(cell string) {
+		n := binary.PutUvarint(lenBuf, uint64(len(cell)))
+		h.Write(lenBuf[:n])
+		h.Write([]byte(cell))
+	}
+	if len(keys) > 0 {
+		for _, k := range keys {
+			if k < len(row) {
+				write(row[k])
+			} else {
+				write("")
+			}
+		}
+	} else {
+		n := len(row)
+		for n > 0 && row[n-1] == "" {
+			n--
+		}
+		for _, cell := range row[:n] {
+			write(cell)
+		}
+	}
+	return h.Sum(nil)
+}
+
+func (f *fileDrops) advance() error {
+	r, err := f.merger.Next()
+	if err != nil {
+		return err
+	}
+	f.hasNext = r != nil
+	if f.hasNext {
+		f.next = binary.BigEndian.Uint64(r)
+	}
+	return nil
+}
+
+// Drop
+// 在数据文件内按首遍顺序逐个数据行调用（不含行首），返回该行是否为需去除的重复行
+func (f *Filter) Drop(file int) (bool, error) {
+	d := f.files[file]
+	seq := d.seq
+	d.seq++
+	if !d.hasNext || d.next != seq {
+		return false, nil
+	}
+	f.Dropped[file]++
+	return true, d.advance()
+}
+
+// NextDrop
+// 数据文件中下一个需去除的行的行号（含行首，从1开始），已无则返回 false；nil 视为不去重
+func (f *Filter) NextDrop(file int) (int, bool) {
+	if f == nil {
+		return 0, false
+	}
+	d := f.files[file]
+	if !d.hasNext {
+		return 0, false
+	}
+	return int(d.next) + 2, true
+}
+
+// Remaining
+// 去除重复行后的数据行数，首遍扫描后即可确定
+func (f *Filter) Remaining() int {
+	return f.rows - f.drops
+}
+
+// Total
+// 已去除的重复行总数
+func (f *Filter) Total() int {
+	total := 0
+	for _, n := range f.Dropped {
+		total += n
+	}
+	return total
+}
+
+// Report
+// 输出各数据文件去除的重复行数
+func (f *Filter) Report(srcPaths []string) {
+	for i, file := range srcPaths {
+		fmt.Printf("数据文件%d：去除重复%s\n", i+1, color.HiYellowString("%d行", f.Dropped[i]))
+		log.Printf("%s：去除重复 %d 行", filepath.Base(file), f.Dropped[i])
+	}
+}
+
+// Close
+// 删除临时文件
+func (f *Filter) Close() {
+	for _, file := range f.files {
+		file.drops.Close()
+	}
+}
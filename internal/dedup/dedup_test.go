@@ -0,0 +1,111 @@
+package dedup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+)
+
+func TestFilter(t *testing.T) {
+	dir := t.TempDir()
+	srcs := []string{filepath.Join(dir, "a.csv"), filepath.Join(dir, "b.csv")}
+	contents := []string{
+		"id,name,note\n1,a,x\n2,b,\n1,a,x\n3,c,y\n",
+		"id,name,note\n2,b\n1,a,z\n4,d,w\n",
+	}
+	for i, src := range srcs {
+		if err := os.WriteFile(src, []byte(contents[i]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name string
+		opts option.Dedup
+		want [][]bool // 各文件各数据行是否去除
+	}{
+		{
+			"整行保留首行（忽略末尾空格）",
+			option.Dedup{Enabled: true},
+			[][]bool{{false, false, true, false}, {true, false, false}},
+		},
+		{
+			"整行保留末行",
+			option.Dedup{Enabled: true, Keep: option.KeepLast},
+			[][]bool{{true, true, false, false}, {false, false, false}},
+		},
+		{
+			"按列跨文件保留首行",
+			option.Dedup{Enabled: true, Keys: []string{"id"}},
+			[][]bool{{false, false, true, false}, {true, true, false}},
+		},
+		{
+			"按列跨文件保留末行",
+			option.Dedup{Enabled: true, Keys: []string{"id", "name"}, Keep: option.KeepLast},
+			[][]bool{{true, true, true, false}, {false, false, false}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Scan(srcs, tt.opts, option.Source{}, context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			total := 0
+			for i, want := range tt.want {
+				got := make([]bool, len(want))
+				for j := range got {
+					if got[j], err = f.Drop(i); err != nil {
+						t.Fatal(err)
+					}
+					if got[j] {
+						total++
+					}
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("文件%d：got %v, want %v", i+1, got, want)
+				}
+			}
+			if f.Total() != total {
+				t.Errorf("Total() = %d, want %d", f.Total(), total)
+			}
+		})
+	}
+}
+
+func TestFilterBlankKey(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a.csv")
+	if err := os.WriteFile(src, []byte("id,code,name\n,,a\n,,b\n1,,c\n1,,d\n, ,e\n,x,f\n,x,g\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		keys []string
+		want []bool
+	}{
+		{"单个键列为空均保留", []string{"id"}, []bool{false, false, false, true, false, false, false}},
+		{"多个键列全部为空均保留", []string{"id", "code"}, []bool{false, false, false, true, false, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Scan([]string{src}, option.Dedup{Enabled: true, Keys: tt.keys}, option.Source{}, context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got := make([]bool, len(tt.want))
+			for j := range got {
+				if got[j], err = f.Drop(0); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package dedup
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"io"
+	"os"
+	"sort"
+)
+
+// sorter
+// 定长记录外部排序：内存攒满一批即排序落盘，最终多路归并，内存占用与数据量无关；
+// 缓冲按需增长，记录不足一批时不落盘，直接在内存中排序（去重时每个数据文件一个，多数只有少量记录）
+// 记录按字节序比较，整数请以大端序编码
+type sorter struct {
+	size  int // 单条记录字节数
+	batch int // 每批记录数
+	buf   []byte
+	runs  []*os.File
+}
+
+func newSorter(size int, batch int) *sorter {
+	return &sorter{size: size, batch: batch}
+}
+
+func (s *sorter) Add(rec []byte) error {
+	s.buf = append(s.buf, rec...)
+	if len(s.buf) >= s.size*s.batch {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill
+// 当前批次排序后写入临时文件
+func (s *sorter) spill() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	s.sortBuf()
+	f, err := os.CreateTemp("", "xlsx-dedup-*.tmp")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, f)
+	if _, err := f.Write(s.buf); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.buf = s.buf[:0]
+	return nil
+}
+
+func (s *sorter) sortBuf() {
+	sort.Sort(&records{buf: s.buf, size: s.size, tmp: make([]byte, s.size)})
+}
+
+// Sort
+// 结束写入，返回按字节序升序的记录迭代器
+func (s *sorter) Sort() (*merger, error) {
+	m := &merger{size: s.size}
+	if len(s.runs) == 0 { // 未落盘，在内存中排序
+		s.sortBuf()
+		r := &run{reader: bufio.NewReader(bytes.NewReader(s.buf)), rec: make([]byte, s.size)}
+		s.buf = nil
+		return m, m.add(r)
+	}
+	if err := s.spill(); err != nil {
+		return nil, err
+	}
+	s.buf = nil
+	for _, f := range s.runs {
+		if err := m.add(&run{reader: bufio.NewReaderSize(f, 1<<20), rec: make([]byte, s.size)}); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Close
+// 删除临时文件
+func (s *sorter) Close() {
+	for _, f := range s.runs {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	s.runs = nil
+}
+
+type records struct {
+	buf  []byte
+	size int
+	tmp  []byte
+}
+
+func (r *records) Len() int {
+	return len(r.buf) / r.size
+}
+
+func (r *records) Less(i, j int) bool {
+	return bytes.Compare(r.buf[i*r.size:(i+1)*r.size], r.buf[j*r.size:(j+1)*r.size]) < 0
+}
+
+func (r *records) Swap(i, j int) {
+	a, b := r.buf[i*r.size:(i+1)*r.size], r.buf[j*r.size:(j+1)*r.size]
+	copy(r.tmp, a)
+	copy(a, b)
+	copy(b, r.tmp)
+}
+
+type run struct {
+	reader *bufio.Reader
+	rec    []byte
+}
+
+func (r *run) next() (bool, error) {
+	_, err := io.ReadFull(r.reader, r.rec)
+	if err == io.EOF {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type runHeap []*run
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return bytes.Compare(h[i].rec, h[j].rec) < 0 }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// merger
+// 多路归并各批次，依次输出最小记录
+type merger struct {
+	size int
+	heap runHeap
+	rec  []byte
+}
+
+// add
+// 加入一个有序批次
+func (m *merger) add(r *run) error {
+	ok, err := r.next()
+	if err != nil || !ok {
+		return err
+	}
+	heap.Push(&m.heap, r)
+	return nil
+}
+
+// Next
+// 返回下一条记录，无记录时返回 nil；返回值在下次调用前有效
+func (m *merger) Next() ([]byte, error) {
+	if len(m.heap) == 0 {
+		return nil, nil
+	}
+	if m.rec == nil {
+		m.rec = make([]byte, m.size)
+	}
+	r := m.heap[0]
+	copy(m.rec, r.rec)
+	ok, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		heap.Fix(&m.heap, 0)
+	} else {
+		heap.Pop(&m.heap)
+	}
+	return m.rec, nil
+}
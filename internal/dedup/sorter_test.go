@@ -0,0 +1,60 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestSorter(t *testing.T) {
+	tests := []struct {
+		name  string
+		batch int
+		count int
+		runs  int // 落盘批次数
+	}{
+		{"空", 4, 0, 0},
+		{"内存", 100, 50, 0},
+		{"恰好一批", 10, 10, 1},
+		{"多批归并", 7, 100, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSorter(8, tt.batch)
+			defer s.Close()
+			rnd := rand.New(rand.NewSource(1))
+			want := make([]uint64, tt.count)
+			rec := make([]byte, 8)
+			for i := range want {
+				want[i] = uint64(rnd.Intn(30)) // 含重复值
+				binary.BigEndian.PutUint64(rec, want[i])
+				if err := s.Add(rec); err != nil {
+					t.Fatal(err)
+				}
+			}
+			slices.Sort(want)
+			m, err := s.Sort()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(s.runs) != tt.runs {
+				t.Errorf("落盘%d批，期望%d批", len(s.runs), tt.runs)
+			}
+			var got []uint64
+			for {
+				r, err := m.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if r == nil {
+					break
+				}
+				got = append(got, binary.BigEndian.Uint64(r))
+			}
+			if !slices.Equal(got, want) && len(got)+len(want) > 0 {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
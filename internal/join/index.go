@@ -0,0 +1,248 @@
+package join
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+)
+
+// index
+// 右表索引：键值 → 行，行号按写入顺序从0编号
+type index interface {
+	add(key string, row []string) error
+	lookup(key string) ([]int, [][]string, error)
+	scan() func() (int, []string, error) // 按写入顺序遍历全部行，遍历结束返回 io.EOF
+	close()
+}
+
+// memIndex
+// 右表较小时全部载入内存
+type memIndex struct {
+	rows [][]string
+	keys map[string][]int
+	size int64 // 各行估算占用内存
+}
+
+func newMemIndex() *memIndex {
+	return &memIndex{keys: make(map[string][]int)}
+}
+
+func (x *memIndex) add(key string, row []string) error {
+	x.keys[key] = append(x.keys[key], len(x.rows))
+	x.rows = append(x.rows, row)
+	x.size += int64(24 + 8 + 16*len(row)) // 行切片、行号及各列字符串头
+	for _, cell := range row {
+		x.size += int64(len(cell))
+	}
+	return nil
+}
+
+func (x *memIndex) lookup(key string) ([]int, [][]string, error) {
+	ids := x.keys[key]
+	rows := make([][]string, len(ids))
+	for i, id := range ids {
+		rows[i] = x.rows[id]
+	}
+	return ids, rows, nil
+}
+
+func (x *memIndex) scan() func() (int, []string, error) {
+	id := -1
+	return func() (int, []string, error) {
+		id++
+		if id >= len(x.rows) {
+			return 0, nil, io.EOF
+		}
+		return id, x.rows[id], nil
+	}
+}
+
+func (x *memIndex) close() {}
+
+// diskIndex
+// 右表较大时写入磁盘：数据文件顺序存放各行，哈希表文件以开放寻址记录各键值最后一行的位置，
+// 同键值的行经 prev 串成链表，内存占用与数据量无关
+//
+// 数据文件记录：prev(8) id(4) 列数(uvarint) [列长度(uvarint) 列值]…
+// 哈希表槽位：hash(8) 记录位置+1(8)，hash 为0表示空槽
+type diskIndex struct {
+	keyCol int
+	data   *os.File
+	writer *bufio.Writer
+	size   int64 // 数据文件已写入字节数
+	table  *os.File
+	slots  uint64
+	rows   int
+	slot   []byte
+}
+
+const slotSize = 16
+
+// newDiskIndex
+// rows 为预计行数，用于确定哈希表大小
+func newDiskIndex(keyCol int, rows int) (*diskIndex, error) {
+	data, err := os.CreateTemp("", "xlsx-join-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	table, err := os.CreateTemp("", "xlsx-join-*.tmp")
+	if err != nil {
+		data.Close()
+		os.Remove(data.Name())
+		return nil, err
+	}
+	slots := uint64(1024)
+	for slots < uint64(rows)*2 {
+		slots <<= 1
+	}
+	if err := table.Truncate(int64(slots * slotSize)); err != nil {
+		data.Close()
+		table.Close()
+		os.Remove(data.Name())
+		os.Remove(table.Name())
+		return nil, err
+	}
+	return &diskIndex{
+		keyCol: keyCol,
+		data:   data,
+		writer: bufio.NewWriterSize(data, 1<<20),
+		table:  table,
+		slots:  slots,
+		slot:   make([]byte, slotSize),
+	}, nil
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64() | 1 // 保留0表示空槽
+}
+
+// find
+// 线性探测，返回键值哈希所在槽位（或首个空槽）及其中记录的位置+1
+func (x *diskIndex) find(hash uint64) (uint64, uint64, error) {
+	for i := hash & (x.slots - 1); ; i = (i + 1) & (x.slots - 1) {
+		if _, err := x.table.ReadAt(x.slot, int64(i*slotSize)); err != nil {
+			return 0, 0, err
+		}
+		h := binary.BigEndian.Uint64(x.slot)
+		if h == 0 || h == hash {
+			return i, binary.BigEndian.Uint64(x.slot[8:]), nil
+		}
+	}
+}
+
+func (x *diskIndex) add(key string, row []string) error {
+	if uint64(x.rows+1)*2 > x.slots {
+		return fmt.Errorf("右表行数超出预计：%d", x.rows)
+	}
+	hash := hashKey(key)
+	i, head, err := x.find(hash)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 0, 64)
+	buf = binary.BigEndian.AppendUint64(buf, head)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(x.rows))
+	buf = binary.AppendUvarint(buf, uint64(len(row)))
+	for _, cell := range row {
+		buf = binary.AppendUvarint(buf, uint64(len(cell)))
+		buf = append(buf, cell...)
+	}
+	if _, err := x.writer.Write(buf); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint64(x.slot, hash)
+	binary.BigEndian.PutUint64(x.slot[8:], uint64(x.size)+1)
+	if _, err := x.table.WriteAt(x.slot, int64(i*slotSize)); err != nil {
+		return err
+	}
+	x.size += int64(len(buf))
+	x.rows++
+	return nil
+}
+
+// readRecord
+// 读取指定位置的记录
+func readRecord(r *bufio.Reader) (uint64, int, []string, error) {
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, 0, nil, err
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	row := make([]string, n)
+	for i := range row {
+		l, err := binary.ReadUvarint(r)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		cell := make([]byte, l)
+		if _, err := io.ReadFull(r, cell); err != nil {
+			return 0, 0, nil, err
+		}
+		row[i] = string(cell)
+	}
+	return binary.BigEndian.Uint64(head), int(binary.BigEndian.Uint32(head[8:])), row, nil
+}
+
+func (x *diskIndex) lookup(key string) ([]int, [][]string, error) {
+	if err := x.writer.Flush(); err != nil {
+		return nil, nil, err
+	}
+	_, pos, err := x.find(hashKey(key))
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		ids  []int
+		rows [][]string
+	)
+	for pos > 0 {
+		r := bufio.NewReaderSize(io.NewSectionReader(x.data, int64(pos-1), x.size-int64(pos-1)), 4096)
+		prev, id, row, err := readRecord(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if x.keyCol < len(row) && strings.TrimSpace(row[x.keyCol]) == key { // 排除哈希冲突
+			ids = append(ids, id)
+			rows = append(rows, row)
+		}
+		pos = prev
+	}
+	// 链表为倒序，恢复写入顺序
+	for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+		ids[i], ids[j] = ids[j], ids[i]
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	return ids, rows, nil
+}
+
+func (x *diskIndex) scan() func() (int, []string, error) {
+	var r *bufio.Reader
+	return func() (int, []string, error) {
+		if r == nil {
+			if err := x.writer.Flush(); err != nil {
+				return 0, nil, err
+			}
+			r = bufio.NewReaderSize(io.NewSectionReader(x.data, 0, x.size), 1<<20)
+		}
+		_, id, row, err := readRecord(r)
+		return id, row, err
+	}
+}
+
+// close
+// 删除临时文件
+func (x *diskIndex) close() {
+	x.data.Close()
+	x.table.Close()
+	os.Remove(x.data.Name())
+	os.Remove(x.table.Name())
+}
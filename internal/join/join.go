@@ -0,0 +1,365 @@
+package join
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+)
+
+// 右表各行占用内存不超过该大小则全部载入内存建立索引，超出则改为建立磁盘索引
+const memIndexSize = 64 << 20 // 64MB
+
+// joined
+// 拼接行及左右两表的来源行号（含行首），无该侧时为0
+type joined struct {
+	row   []string
+	left  int
+	right int
+}
+
+// Joiner
+// 按键列横向拼接左右两表：先为右表建立索引，再流式读取左表逐行查找匹配，
+// 一行匹配多行则输出多行；full 连接最后输出右表中未匹配的行
+type Joiner struct {
+	Header      []string // 左表各列 + 右表除键列外各列
+	RightCols   []int    // 输出中右表部分对应的右表列序号（从0开始）
+	RightKey    int      // 右表键列序号（从0开始）
+	leftPath    string
+	opts        option.Join
+	srcOpts     option.Source
+	leftCols    int
+	rightHeader []string
+	leftKey     int
+	rightKey    int
+	index       index
+	matched     []bool
+	iter        source.Rows // 左表
+	line        int         // 左表已读取的行号（含行首）
+	pending     []joined
+	done        bool
+	rest        func() (int, []string, error) // full 连接遍历右表未匹配的行
+	row         joined
+	err         error
+	ctx         context.Context
+
+	Matched   int // 匹配的左表行数
+	LeftOnly  int // 未匹配的左表行数
+	RightOnly int // 未匹配的右表行数
+}
+
+// Open
+// 读取右表建立索引，并打开左表
+func Open(leftPath string, rightPath string, opts option.Join, srcOpts option.Source, ctx context.Context) (*Joiner, error) {
+	start := time.Now()
+	if opts.RightKey == "" {
+		opts.RightKey = opts.Key
+	}
+	j := &Joiner{
+		leftPath: leftPath,
+		opts:     opts,
+		srcOpts:  srcOpts,
+		ctx:      ctx,
+	}
+	if err := j.buildIndex(rightPath, start); err != nil {
+		j.Close()
+		return nil, err
+	}
+
+	iter, err := source.Open(leftPath, srcOpts)
+	if err != nil {
+		j.Close()
+		return nil, err
+	}
+	j.iter = iter
+	if !iter.Next() {
+		j.Close()
+		return nil, fmt.Errorf("%s：无数据", filepath.Base(leftPath))
+	}
+	j.line++
+	header, err := iter.Columns()
+	if err != nil {
+		j.Close()
+		return nil, err
+	}
+	if j.leftKey, err = util.ColumnIndexFromKey(header, opts.Key); err != nil {
+		j.Close()
+		return nil, fmt.Errorf("%s：%w", filepath.Base(leftPath), err)
+	}
+	j.leftCols = max(len(header), j.leftKey+1)
+	j.Header = j.joinRow(header, j.rightHeader)
+	return j, nil
+}
+
+func (j *Joiner) buildIndex(rightPath string, start time.Time) error {
+	iter, err := source.Open(rightPath, j.srcOpts)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	if !iter.Next() {
+		return fmt.Errorf("%s：无数据", filepath.Base(rightPath))
+	}
+	header, err := iter.Columns()
+	if err != nil {
+		return err
+	}
+	if j.rightKey, err = util.ColumnIndexFromKey(header, j.opts.RightKey); err != nil {
+		return fmt.Errorf("%s：%w", filepath.Base(rightPath), err)
+	}
+	j.RightKey = j.rightKey
+	j.rightHeader = header
+	for c := range header {
+		if c != j.rightKey {
+			j.RightCols = append(j.RightCols, c)
+		}
+	}
+	fmt.Println("正在读取右表…")
+	mem := newMemIndex()
+	j.index = mem
+	rows := 0
+	for iter.Next() {
+		select {
+		case <-j.ctx.Done():
+			return j.ctx.Err()
+		default:
+		} // 响应 Ctrl+C 打断
+		row, err := iter.Columns()
+		if err != nil {
+			return err
+		}
+		if err := j.index.add(indexKey(row, j.rightKey), row); err != nil {
+			return err
+		}
+		if mem != nil && mem.size > memIndexSize {
+			if err := j.spillIndex(rightPath, mem); err != nil {
+				return err
+			}
+			mem = nil
+		}
+		rows++
+		if rows%100000 == 0 {
+			fmt.Printf("右表：已读取%d行；累计耗时%s\n", rows, util.Cost(start))
+		}
+	}
+	j.matched = make([]bool, rows)
+	fmt.Printf("右表：读取完成，共%s\n", color.HiYellowString("%d行", rows))
+	return nil
+}
+
+// spillIndex
+// 内存索引超出 memIndexSize 时改为磁盘索引，已读取的行转存至磁盘
+func (j *Joiner) spillIndex(rightPath string, mem *memIndex) error {
+	fmt.Printf("右表较大（已读取%s），正在改为磁盘索引…\n", util.SizeReadable(mem.size))
+	rows, err := source.Count(rightPath, j.srcOpts)
+	if err != nil {
+		return err
+	}
+	disk, err := newDiskIndex(j.rightKey, rows)
+	if err != nil {
+		return err
+	}
+	j.index = disk
+	next := mem.scan()
+	for {
+		_, row, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := disk.add(indexKey(row, j.rightKey), row); err != nil {
+			return err
+		}
+	}
+}
+
+// indexKey
+// 键列的值（去除首尾空格），缺该列为空
+func indexKey(row []string, col int) string {
+	if col < len(row) {
+		return strings.TrimSpace(row[col])
+	}
+	return ""
+}
+
+// joinRow
+// 拼接左表行与右表行，任一侧为 nil 则该侧留空；仅右表时键值写入左表键列
+func (j *Joiner) joinRow(left []string, right []string) []string {
+	res := make([]string, j.leftCols+len(j.RightCols))
+	copy(res, left)
+	if right != nil {
+		if left == nil && j.rightKey < len(right) {
+			res[j.leftKey] = right[j.rightKey]
+		}
+		for k, c := range j.RightCols {
+			if c < len(right) {
+				res[j.leftCols+k] = right[c]
+			}
+		}
+	}
+	return res
+}
+
+// Next
+// 读取下一拼接行
+func (j *Joiner) Next() bool {
+	for len(j.pending) == 0 {
+		if j.err != nil || j.done {
+			return false
+		}
+		if j.rest != nil {
+			id, row, err := j.rest()
+			if err == io.EOF {
+				j.done = true
+				continue
+			}
+			if err != nil {
+				j.err = err
+				return false
+			}
+			if !j.matched[id] {
+				j.pending = append(j.pending, joined{row: j.joinRow(nil, row), right: id + 2})
+				j.RightOnly++
+			}
+			continue
+		}
+		if !j.iter.Next() {
+			if j.opts.Type == option.JoinFull {
+				j.rest, j.line = j.index.scan(), 0
+			} else {
+				j.done = true
+			}
+			continue
+		}
+		j.line++
+		row, err := j.iter.Columns()
+		if err != nil {
+			j.err = err
+			return false
+		}
+		var (
+			ids    []int
+			rights [][]string
+		)
+		if key := indexKey(row, j.leftKey); key != "" { // 空键值不匹配任何行，同 VLOOKUP
+			if ids, rights, err = j.index.lookup(key); err != nil {
+				j.err = err
+				return false
+			}
+		}
+		if len(ids) == 0 {
+			j.LeftOnly++
+			if j.opts.Type != option.JoinInner {
+				j.pending = append(j.pending, joined{row: j.joinRow(row, nil), left: j.line})
+			}
+			continue
+		}
+		j.Matched++
+		for k, id := range ids {
+			j.matched[id] = true
+			j.pending = append(j.pending, joined{row: j.joinRow(row, rights[k]), left: j.line, right: id + 2})
+		}
+	}
+	j.row, j.pending = j.pending[0], j.pending[1:]
+	return true
+}
+
+// Count
+// 预先读取左表，按右表索引统计拼接后的数据行数（不含行首），不影响随后的 Next
+func (j *Joiner) Count() (int, error) {
+	iter, err := source.Open(j.leftPath, j.srcOpts)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+	iter.Next() // 行首
+	var (
+		rows    int
+		matched = make([]bool, len(j.matched))
+	)
+	for iter.Next() {
+		select {
+		case <-j.ctx.Done():
+			return 0, j.ctx.Err()
+		default:
+		} // 响应 Ctrl+C 打断
+		row, err := iter.Columns()
+		if err != nil {
+			return 0, err
+		}
+		var ids []int
+		if key := indexKey(row, j.leftKey); key != "" { // 空键值不匹配任何行，同 VLOOKUP
+			if ids, _, err = j.index.lookup(key); err != nil {
+				return 0, err
+			}
+		}
+		if len(ids) == 0 {
+			if j.opts.Type != option.JoinInner {
+				rows++
+			}
+			continue
+		}
+		rows += len(ids)
+		for _, id := range ids {
+			matched[id] = true
+		}
+	}
+	if j.opts.Type == option.JoinFull {
+		for _, m := range matched {
+			if !m {
+				rows++
+			}
+		}
+	}
+	return rows, nil
+}
+
+// Row
+// 当前拼接行
+func (j *Joiner) Row() []string {
+	return j.row.row
+}
+
+// Line
+// 当前行在左表的行号（含行首），仅右表有的行（full 连接）为0
+func (j *Joiner) Line() int {
+	return j.row.left
+}
+
+// RightLine
+// 当前行在右表的行号（含行首），未匹配右表的行为0
+func (j *Joiner) RightLine() int {
+	return j.row.right
+}
+
+func (j *Joiner) Err() error {
+	return j.err
+}
+
+// Report
+// 输出匹配情况
+func (j *Joiner) Report() {
+	fmt.Printf("左表匹配%s，未匹配%s", color.HiYellowString("%d行", j.Matched), color.HiYellowString("%d行", j.LeftOnly))
+	if j.opts.Type == option.JoinFull {
+		fmt.Printf("；右表未匹配%s", color.HiYellowString("%d行", j.RightOnly))
+	}
+	fmt.Println()
+}
+
+func (j *Joiner) Close() {
+	if j.iter != nil {
+		j.iter.Close()
+	}
+	if j.index != nil {
+		j.index.close()
+	}
+}
@@ -0,0 +1,192 @@
+package join
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/testutil"
+)
+
+func TestJoiner(t *testing.T) {
+	left := "id,name\n1,a\n2,b\n,blank\n3,c\n"
+	right := "code,score\n2,80\n1,90\n,x\n2,85\n,y\n4,70\n"
+	tests := []struct {
+		name  string
+		typ   option.JoinType
+		rows  []string // 各拼接行，逗号连接
+		lines [][2]int // 各行的左、右表行号
+		// 匹配、左表未匹配、右表未匹配行数
+		matched, leftOnly, rightOnly int
+	}{
+		{
+			"left",
+			option.JoinLeft,
+			[]string{"1,a,90", "2,b,80", "2,b,85", ",blank,", "3,c,"},
+			[][2]int{{2, 3}, {3, 2}, {3, 5}, {4, 0}, {5, 0}},
+			2, 2, 0,
+		},
+		{
+			"inner",
+			option.JoinInner,
+			[]string{"1,a,90", "2,b,80", "2,b,85"},
+			[][2]int{{2, 3}, {3, 2}, {3, 5}},
+			2, 2, 0,
+		},
+		{
+			"full（空键值不互相匹配）",
+			option.JoinFull,
+			[]string{"1,a,90", "2,b,80", "2,b,85", ",blank,", "3,c,", ",,x", ",,y", "4,,70"},
+			[][2]int{{2, 3}, {3, 2}, {3, 5}, {4, 0}, {5, 0}, {0, 4}, {0, 6}, {0, 7}},
+			2, 2, 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			leftPath := testutil.WriteFile(t, dir, "left.csv", left)
+			rightPath := testutil.WriteFile(t, dir, "right.csv", right)
+			j, err := Open(leftPath, rightPath, option.Join{Key: "id", RightKey: "code", Type: tt.typ}, option.Source{}, context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer j.Close()
+			if got := strings.Join(j.Header, ","); got != "id,name,score" {
+				t.Errorf("Header = %s", got)
+			}
+			count, err := j.Count()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != len(tt.rows) {
+				t.Errorf("Count() = %d, want %d", count, len(tt.rows))
+			}
+			var (
+				rows  []string
+				lines [][2]int
+			)
+			for j.Next() {
+				rows = append(rows, strings.Join(j.Row(), ","))
+				lines = append(lines, [2]int{j.Line(), j.RightLine()})
+			}
+			if err := j.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(rows, tt.rows) {
+				t.Errorf("rows = %q, want %q", rows, tt.rows)
+			}
+			if !slices.Equal(lines, tt.lines) {
+				t.Errorf("lines = %v, want %v", lines, tt.lines)
+			}
+			if j.Matched != tt.matched || j.LeftOnly != tt.leftOnly || j.RightOnly != tt.rightOnly {
+				t.Errorf("匹配 %d/%d/%d，期望 %d/%d/%d", j.Matched, j.LeftOnly, j.RightOnly, tt.matched, tt.leftOnly, tt.rightOnly)
+			}
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	rows := [][]string{{"a", "1"}, {"b", "2"}, {"a", "3"}, {" b ", "4"}, {"", "5"}, {"c"}}
+	tests := []struct {
+		name  string
+		index func(t *testing.T) index
+	}{
+		{"内存", func(t *testing.T) index { return newMemIndex() }},
+		{"磁盘", func(t *testing.T) index {
+			x, err := newDiskIndex(0, len(rows))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return x
+		}},
+		{"磁盘（最小哈希表）", func(t *testing.T) index {
+			x, err := newDiskIndex(0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return x
+		}},
+	}
+	lookups := []struct {
+		key  string
+		ids  []int
+		vals []string
+	}{
+		{"a", []int{0, 2}, []string{"1", "3"}},
+		{"b", []int{1, 3}, []string{"2", "4"}},
+		{"c", []int{5}, []string{""}},
+		{"d", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := tt.index(t)
+			defer x.close()
+			for _, row := range rows {
+				if err := x.add(indexKey(row, 0), row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, l := range lookups {
+				ids, got, err := x.lookup(l.key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var vals []string
+				for _, row := range got {
+					v := ""
+					if len(row) > 1 {
+						v = row[1]
+					}
+					vals = append(vals, v)
+				}
+				if !slices.Equal(ids, l.ids) || !slices.Equal(vals, l.vals) {
+					t.Errorf("lookup(%q) = %v %q, want %v %q", l.key, ids, vals, l.ids, l.vals)
+				}
+			}
+			next := x.scan()
+			for id := range rows {
+				got, row, err := next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != id || !slices.Equal(row, rows[id]) {
+					t.Errorf("scan %d = %d %q", id, got, row)
+				}
+			}
+			if _, _, err := next(); err != io.EOF {
+				t.Errorf("scan 结束返回 %v", err)
+			}
+		})
+	}
+}
+
+func TestSpillIndex(t *testing.T) {
+	rightPath := testutil.WriteFile(t, t.TempDir(), "right.csv", "k,v\na,1\nb,2\na,3\n")
+	mem := newMemIndex()
+	for _, row := range [][]string{{"a", "1"}, {"b", "2"}, {"a", "3"}} {
+		if err := mem.add(row[0], row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mem.size <= 0 {
+		t.Fatalf("size = %d", mem.size)
+	}
+	j := &Joiner{index: mem}
+	if err := j.spillIndex(rightPath, mem); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if _, ok := j.index.(*diskIndex); !ok {
+		t.Fatalf("index = %T", j.index)
+	}
+	ids, _, err := j.index.lookup("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []int{0, 2}) {
+		t.Errorf("lookup(a) = %v", ids)
+	}
+}
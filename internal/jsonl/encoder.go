@@ -0,0 +1,115 @@
+package jsonl
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/xlsx"
+	"github.com/xuri/excelize/v2"
+)
+
+// rowEncoder
+// 数据行按行首转为 JSON 对象，字段顺序与列顺序一致；取值按各列的数据格式转换为数字、布尔值或字符串，空值为 null
+type rowEncoder struct {
+	keys []string
+	meta map[int]xlsx.CellMeta
+	buf  bytes.Buffer
+	enc  *json.Encoder
+}
+
+func newRowEncoder(keys []string, meta map[int]xlsx.CellMeta) *rowEncoder {
+	e := &rowEncoder{keys: keys, meta: meta}
+	e.enc = json.NewEncoder(&e.buf)
+	e.enc.SetEscapeHTML(false)
+	return e
+}
+
+// Encode
+// 数据行转为一行 JSON（含换行符），超出行首的列以列名为字段名；返回值在下次调用前有效
+func (e *rowEncoder) Encode(row []string) ([]byte, error) {
+	e.buf.Reset()
+	e.buf.WriteByte('{')
+	for c, val := range row {
+		if c >= len(e.keys) && val == "" {
+			continue
+		}
+		if e.buf.Len() > 1 {
+			e.buf.WriteByte(',')
+		}
+		key := ""
+		if c < len(e.keys) {
+			key = e.keys[c]
+		} else {
+			key, _ = excelize.ColumnNumberToName(c + 1)
+		}
+		if err := e.write(key); err != nil {
+			return nil, err
+		}
+		e.buf.WriteByte(':')
+		if err := e.write(e.value(c+1, val)); err != nil {
+			return nil, err
+		}
+	}
+	for c := len(row); c < len(e.keys); c++ { // 行尾的空单元格
+		if e.buf.Len() > 1 {
+			e.buf.WriteByte(',')
+		}
+		if err := e.write(e.keys[c]); err != nil {
+			return nil, err
+		}
+		e.buf.WriteString(":null")
+	}
+	e.buf.WriteString("}\n")
+	return e.buf.Bytes(), nil
+}
+
+// write
+// 写入 JSON 值，去掉 json.Encoder 附加的换行符
+func (e *rowEncoder) write(v any) error {
+	if err := e.enc.Encode(v); err != nil {
+		return err
+	}
+	e.buf.Truncate(e.buf.Len() - 1)
+	return nil
+}
+
+// value
+// 数值列中的数值为数字（含前导零、超出 15 位有效数字的仍为字符串），布尔列为布尔值，日期列、文本列为字符串
+func (e *rowEncoder) value(col int, s string) any {
+	if s == "" {
+		return nil
+	}
+	m := e.meta[col]
+	if m.Date {
+		return s
+	}
+	switch m.TypeIdx {
+	case excelize.CellTypeBool:
+		switch strings.ToUpper(s) {
+		case "TRUE", "1":
+			return true
+		case "FALSE", "0":
+			return false
+		}
+	case excelize.CellTypeNumber, excelize.CellTypeUnset, excelize.CellTypeError:
+		if n, ok := number(s); ok && !xlsx.KeepText(s) {
+			return n
+		}
+	}
+	return s
+}
+
+// number
+// 可解析为数值则转为 JSON 数字，排除 NaN、Inf、十六进制等写法；.5、1. 等 JSON 不接受的写法重新格式化
+func number(s string) (json.Number, bool) {
+	if !xlsx.IsNumber(s) {
+		return "", false
+	}
+	if json.Valid([]byte(s)) {
+		return json.Number(s), true
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), true
+}
@@ -0,0 +1,312 @@
+package jsonl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/dedup"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"gitee.com/nguaduot/split-xlsx-go/internal/xlsx"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+)
+
+// MergeXlsx2jsonl
+// 合并为 JSON Lines：每个数据行一个 JSON 对象，字段名取自首个数据文件的行首；
+// 各数据文件分别解析数据格式，数值列、布尔列的值写为数字、布尔值。UTF-8 编码，不写 BOM
+func MergeXlsx2jsonl(srcPaths []string, tarPath string, opts option.Options, ctx context.Context) error {
+	start := time.Now()
+	fmt.Println("正在解析…")
+
+	// 获取文件大小，用于估算进度
+	srcSizes := make([]int64, len(srcPaths))
+	metas := make([]map[int]xlsx.CellMeta, len(srcPaths))
+	for i, file := range srcPaths {
+		f, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		srcSizes[i] = f.Size()
+		fmt.Printf("数据文件%d：%s，%s\n", i+1, color.HiYellowString(filepath.Base(file)), util.SizeReadable(srcSizes[i]))
+		if metas[i], err = xlsx.ReadCellMeta(file, opts.Sample, opts.Source); err != nil {
+			return err
+		}
+	}
+
+	// 去重需预先扫描全部数据文件
+	var filter *dedup.Filter
+	if opts.Dedup.Enabled {
+		f, err := dedup.Scan(srcPaths, opts.Dedup, opts.Source, ctx)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		filter = f
+	}
+
+	tarFile, err := os.Create(tarPath)
+	if err != nil {
+		return err
+	}
+	bufWriter := bufio.NewWriterSize(tarFile, 1<<20)
+
+	fmt.Printf("正在合并… %s\n", color.HiBlackString("(停止：Ctrl+C)"))
+	var keys []string
+	totalRows := 0
+	for i, file := range srcPaths {
+		select {
+		case <-ctx.Done():
+			bufWriter.Flush()
+			tarFile.Close()
+			return ctx.Err()
+		default:
+		} // 响应 Ctrl+C 打断
+		iter, err := source.Open(file, opts.Source) // 流式读取（不会一次性加载整表）
+		if err != nil {
+			bufWriter.Flush()
+			tarFile.Close()
+			return err
+		}
+		var encoder *rowEncoder
+		fileRows := 0
+		for iter.Next() {
+			select {
+			case <-ctx.Done():
+				iter.Close()
+				bufWriter.Flush()
+				tarFile.Close()
+				return ctx.Err()
+			default:
+			} // 响应 Ctrl+C 打断
+			fileRows++
+			totalRows++
+			row, err := iter.Columns()
+			if err != nil {
+				iter.Close()
+				bufWriter.Flush()
+				tarFile.Close()
+				return err
+			}
+			if fileRows == 1 { // 字段名取自首个数据文件的行首
+				if keys == nil {
+					keys = util.FieldNames(row)
+				}
+				encoder = newRowEncoder(keys, metas[i])
+				continue
+			}
+			if filter != nil { // 去除重复行
+				drop, err := filter.Drop(i)
+				if err != nil {
+					iter.Close()
+					bufWriter.Flush()
+					tarFile.Close()
+					return err
+				}
+				if drop {
+					continue
+				}
+			}
+			line, err := encoder.Encode(row)
+			if err == nil {
+				_, err = bufWriter.Write(line)
+			}
+			if err != nil {
+				iter.Close()
+				bufWriter.Flush()
+				tarFile.Close()
+				return err
+			}
+			if totalRows-i-1 > 0 && (totalRows-i-1)%10000 == 0 {
+				if i > 0 {
+					fmt.Printf("数据文件%d：已读取%d行；累计合并%d行，耗时%s\n", i+1, fileRows-1, totalRows-i-1, util.Cost(start))
+				} else {
+					fmt.Printf("数据文件%d：已读取%d行；累计耗时%s\n", i+1, fileRows-1, util.Cost(start))
+				}
+			}
+		}
+		iter.Close()
+		sizeDone, sizeTodo := int64(0), int64(0)
+		for j := range srcSizes {
+			if j <= i {
+				sizeDone += srcSizes[j]
+			} else {
+				sizeTodo += srcSizes[j]
+			}
+		}
+		if sizeTodo > 0 {
+			fmt.Printf("数据文件%d：读取完成，共%s；预计剩余%s\n", i+1, color.HiYellowString("%d行", fileRows-1),
+				util.CostReadable(float64(sizeTodo)/float64(sizeDone)*time.Since(start).Seconds()))
+		} else {
+			fmt.Printf("数据文件%d：读取完成，共%s\n", i+1, color.HiYellowString("%d行", fileRows-1))
+		}
+	}
+	bufWriter.Flush()
+	tarFile.Close()
+	dropped := 0
+	if filter != nil {
+		filter.Report(srcPaths)
+		dropped = filter.Total()
+	}
+	info, err := os.Stat(tarPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("合并完成，%s，共%s数据，耗时%s\n",
+		util.SizeReadable(info.Size()), color.HiYellowString("%d行", totalRows-len(srcPaths)-dropped), util.Cost(start))
+	fmt.Printf("合并文件：%s%s\n", strings.TrimSuffix(tarPath, filepath.Base(tarPath)),
+		color.HiYellowString(filepath.Base(tarPath)))
+	return nil
+}
+
+func SplitXlsx2jsonlByLine(srcPath string, tarDir string, lineCount int, opts option.Options, ctx context.Context) error {
+	start := time.Now()
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("数据文件：%s，%s\n", color.HiYellowString(filepath.Base(srcPath)), util.SizeReadable(info.Size()))
+
+	fmt.Printf("正在按每%s拆分… %s\n", color.HiYellowString("%d行", lineCount), color.HiBlackString("(停止：Ctrl+C)"))
+	parts, totalRows, err := split(srcPath, tarDir, lineCount, "%s-%d.jsonl", 0, opts, ctx, start)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("拆分完成，共%s，分为%s文件，耗时%s\n",
+		color.HiYellowString("%d行", totalRows), color.HiYellowString("%d个", parts), util.Cost(start))
+	fmt.Printf("拆分文件夹：%s%s\n", strings.TrimSuffix(tarDir, filepath.Base(tarDir)),
+		color.HiYellowString(filepath.Base(tarDir)))
+	return nil
+}
+
+func SplitXlsx2jsonlByFile(srcPath string, tarDir string, fileCount int, opts option.Options, ctx context.Context) error {
+	start := time.Now()
+	fmt.Println("正在解析…")
+
+	srcRows, err := source.Count(srcPath, opts.Source)
+	if err != nil {
+		return err
+	}
+	if srcRows < fileCount {
+		return fmt.Errorf("数据行数（%d）小于拆分文件数（%d），无法拆分", srcRows, fileCount)
+	}
+	lineCount := int(math.Ceil(float64(srcRows) / float64(fileCount)))
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("数据文件：%s，%s，%d行\n",
+		color.HiYellowString(filepath.Base(srcPath)), util.SizeReadable(info.Size()), srcRows)
+
+	fmt.Printf("正在拆分为%s文件… %s\n", color.HiYellowString("%d个", fileCount), color.HiBlackString("(停止：Ctrl+C)"))
+	nameFmt := fmt.Sprintf("%%s-%%0%dd.jsonl", len(strconv.Itoa(fileCount)))
+	parts, _, err := split(srcPath, tarDir, lineCount, nameFmt, fileCount, opts, ctx, start)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("拆分完成，%d个文件，每个%s，耗时%s\n",
+		parts, color.HiYellowString("%d行", lineCount), util.Cost(start))
+	fmt.Printf("拆分文件夹：%s%s\n", strings.TrimSuffix(tarDir, filepath.Base(tarDir)),
+		color.HiYellowString(filepath.Base(tarDir)))
+	return nil
+}
+
+// split
+// 按每 lineCount 行拆分，返回拆分文件数、数据行数；fileCount 为拆分文件数（按行数拆分时为 0），用于估算剩余耗时
+func split(srcPath string, tarDir string, lineCount int, nameFmt string, fileCount int,
+	opts option.Options, ctx context.Context, start time.Time) (int, int, error) {
+	meta, err := xlsx.ReadCellMeta(srcPath, opts.Sample, opts.Source)
+	if err != nil {
+		return 0, 0, err
+	}
+	iter, err := source.Open(srcPath, opts.Source)
+	if err != nil {
+		return 0, 0, err
+	}
+	iter.Next()
+	rowHeader, err := iter.Columns()
+	if err != nil {
+		iter.Close()
+		return 0, 0, err
+	}
+	encoder := newRowEncoder(util.FieldNames(rowHeader), meta)
+	var (
+		tarFile    *os.File
+		bufWriter  *bufio.Writer
+		tarPathIdx int
+		totalRows  int
+		fileRows   int
+		startFile  time.Time
+	)
+	for iter.Next() {
+		if totalRows%lineCount == 0 {
+			if tarPathIdx > 0 {
+				bufWriter.Flush()
+				tarFile.Close()
+				if fileCount > 0 {
+					fmt.Printf("数据文件%d：写入完成，共%s；预计剩余%s\n", tarPathIdx, color.HiYellowString("%d行", fileRows),
+						util.CostReadable(time.Since(startFile).Seconds()*float64(fileCount-tarPathIdx)))
+				} else {
+					fmt.Printf("数据文件%d：写入完成，共%d行\n", tarPathIdx, fileRows)
+				}
+			}
+			startFile = time.Now()
+			tarPathIdx++
+			tarFile, err = os.Create(filepath.Join(tarDir, fmt.Sprintf(nameFmt, filepath.Base(tarDir), tarPathIdx)))
+			if err != nil {
+				iter.Close()
+				return 0, 0, err
+			}
+			bufWriter = bufio.NewWriterSize(tarFile, 1<<20)
+			fileRows = 0
+		}
+		select {
+		case <-ctx.Done():
+			bufWriter.Flush()
+			tarFile.Close()
+			iter.Close()
+			return 0, 0, ctx.Err()
+		default:
+		} // 响应 Ctrl+C 打断
+		totalRows++
+		fileRows++
+		row, err := iter.Columns()
+		if err != nil {
+			bufWriter.Flush()
+			tarFile.Close()
+			iter.Close()
+			return 0, 0, err
+		}
+		line, err := encoder.Encode(row)
+		if err == nil {
+			_, err = bufWriter.Write(line)
+		}
+		if err != nil {
+			bufWriter.Flush()
+			tarFile.Close()
+			iter.Close()
+			return 0, 0, err
+		}
+		if totalRows%10000 == 0 {
+			if tarPathIdx > 1 {
+				fmt.Printf("数据文件%d：已写入%d行；累计拆分%d行，耗时%s\n", tarPathIdx, fileRows, totalRows, util.Cost(start))
+			} else {
+				fmt.Printf("数据文件%d：已写入%d行；累计耗时%s\n", tarPathIdx, fileRows, util.Cost(start))
+			}
+		}
+	}
+	if tarPathIdx > 0 {
+		bufWriter.Flush()
+		tarFile.Close()
+		fmt.Printf("数据文件%d：写入完成，共%s\n", tarPathIdx, color.HiYellowString("%d行", fileRows))
+	}
+	iter.Close()
+	return tarPathIdx, totalRows, nil
+}
@@ -0,0 +1,128 @@
+package jsonl
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/xlsx"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want json.Number
+		ok   bool
+	}{
+		{"12", "12", true},
+		{"-0.5", "-0.5", true},
+		{"1e3", "1e3", true},
+		{".5", "0.5", true}, // JSON 不接受的写法重新格式化
+		{"1.", "1", true},
+		{"+7", "7", true},
+		{"NaN", "", false},
+		{"Inf", "", false},
+		{"0x1F", "", false},
+		{"1_000", "", false},
+		{"abc", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := number(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("number(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRowEncoder(t *testing.T) {
+	meta := map[int]xlsx.CellMeta{
+		1: {TypeIdx: excelize.CellTypeUnset},
+		2: {TypeIdx: excelize.CellTypeSharedString},
+		3: {TypeIdx: excelize.CellTypeBool},
+		4: {TypeIdx: excelize.CellTypeNumber, Date: true},
+		5: {TypeIdx: excelize.CellTypeError},
+	}
+	keys := []string{"n", "s", "b", "d", "e"}
+	tests := []struct {
+		name string
+		row  []string
+		want string
+	}{
+		{"各列类型", []string{"1.5", "12", "TRUE", "2026-01-02", "#N/A"},
+			`{"n":1.5,"s":"12","b":true,"d":"2026-01-02","e":"#N/A"}`},
+		{"错误值列中的数值", []string{"", "", "0", "", "3"},
+			`{"n":null,"s":null,"b":false,"d":null,"e":3}`},
+		{"前导零与长数字按字符串", []string{"007", "", "yes", "", "12345678901234567"},
+			`{"n":"007","s":null,"b":"yes","d":null,"e":"12345678901234567"}`},
+		{"数值列中的文本", []string{"n/a", "<b>&</b>"},
+			`{"n":"n/a","s":"<b>&</b>","b":null,"d":null,"e":null}`},
+		{"超出行首的列", []string{"1", "a", "", "", "", "", "x"},
+			`{"n":1,"s":"a","b":null,"d":null,"e":null,"G":"x"}`},
+	}
+	e := newRowEncoder(keys, meta)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Encode(tt.row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want+"\n" {
+				t.Errorf("got %s\nwant %s", got, tt.want)
+			}
+			if !json.Valid(got) {
+				t.Errorf("无效的 JSON：%s", got)
+			}
+		})
+	}
+}
+
+func TestMergeJSONL(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.csv")
+	b := filepath.Join(dir, "b.csv")
+	if err := os.WriteFile(a, []byte("id,name,note\n1,甲,x\n2,乙,y\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("id,name,note\n2,乙,y\n3,丙\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		dedup option.Dedup
+		want  []string
+	}{
+		{"合并", option.Dedup{}, []string{
+			`{"id":1,"name":"甲","note":"x"}`,
+			`{"id":2,"name":"乙","note":"y"}`,
+			`{"id":2,"name":"乙","note":"y"}`,
+			`{"id":3,"name":"丙","note":null}`,
+		}},
+		{"去重", option.Dedup{Enabled: true}, []string{
+			`{"id":1,"name":"甲","note":"x"}`,
+			`{"id":2,"name":"乙","note":"y"}`,
+			`{"id":3,"name":"丙","note":null}`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tarPath := filepath.Join(t.TempDir(), "out.jsonl")
+			if err := MergeXlsx2jsonl([]string{a, b}, tarPath, option.Options{Dedup: tt.dedup}, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(tarPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package kmerge
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+	"github.com/xuri/excelize/v2"
+)
+
+// 可识别的日期格式，含 excelize 默认日期格式 mm-dd-yy
+var dateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006/1/2",
+	"01-02-06 15:04",
+	"01-02-06",
+}
+
+type row struct {
+	line   int // 文件内行号（含行首）
+	cells  []string
+	styles []int // 各单元格样式 ID（仅 xlsx 类数据文件）
+	err    error
+}
+
+// input
+// 单个数据文件，独立协程流式读取，经缓冲通道送至归并
+type input struct {
+	idx      int
+	path     string
+	ch       chan row
+	cur      row
+	key      key
+	rows     int // 已读取数据行数
+	disorder int // 未按键列有序的行数
+}
+
+type key struct {
+	text   string
+	num    float64
+	isNum  bool
+	date   time.Time
+	isDate bool
+}
+
+// Merger
+// 多路归并已各自按键列排序的数据文件，各文件并发流式读取，内存占用与数据量无关
+type Merger struct {
+	Header       []string
+	HeaderStyles []int
+	opts         option.Sort
+	col          int
+	srcs         []*input
+	heap         srcHeap
+	cur          *input
+	err          error
+	cancel       context.CancelFunc
+}
+
+func Open(srcPaths []string, opts option.Sort, srcOpts option.Source, ctx context.Context) (*Merger, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	m := &Merger{
+		opts:   opts,
+		cancel: cancel,
+	}
+	for i, path := range srcPaths {
+		src := &input{
+			idx:  i,
+			path: path,
+			ch:   make(chan row, 1024),
+		}
+		m.srcs = append(m.srcs, src)
+		go src.read(srcOpts, ctx)
+	}
+	for _, src := range m.srcs {
+		r, ok := <-src.ch
+		if !ok {
+			continue // 空文件
+		}
+		if r.err != nil {
+			m.Close()
+			return nil, fmt.Errorf("%s：%w", filepath.Base(src.path), r.err)
+		}
+		if m.Header == nil {
+			m.Header, m.HeaderStyles = r.cells, r.styles
+			col, err := util.ColumnIndexFromKey(r.cells, opts.Key)
+			if err != nil {
+				m.Close()
+				return nil, err
+			}
+			m.col = col
+		}
+		if m.err = src.pull(m); m.err != nil {
+			m.Close()
+			return nil, m.err
+		}
+		if src.cur.cells != nil {
+			m.heap.srcs = append(m.heap.srcs, src)
+		}
+	}
+	m.heap.m = m
+	heap.Init(&m.heap)
+	return m, nil
+}
+
+func (src *input) read(opts option.Source, ctx context.Context) {
+	defer close(src.ch)
+	send := func(r row) bool {
+		select {
+		case src.ch <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	iter, err := source.Open(src.path, opts)
+	if err != nil {
+		send(row{err: err})
+		return
+	}
+	defer iter.Close()
+	sr, styled := iter.(source.StyledRows)
+	line := 0
+	for iter.Next() {
+		line++
+		cells, err := iter.Columns()
+		if err != nil {
+			send(row{err: err})
+			return
+		}
+		r := row{line: line, cells: cells}
+		if styled {
+			r.styles = append([]int{}, sr.Styles()...) // 非 nil 表示可读取样式
+		}
+		if !send(r) {
+			return
+		}
+	}
+}
+
+// pull
+// 读取下一数据行并解析键值，文件读完则 cur.cells 为 nil
+func (src *input) pull(m *Merger) error {
+	prev := src.key
+	hasPrev := src.cur.cells != nil && src.cur.line > 1
+	r, ok := <-src.ch
+	if !ok {
+		src.cur = row{}
+		return nil
+	}
+	if r.err != nil {
+		return fmt.Errorf("%s：%w", filepath.Base(src.path), r.err)
+	}
+	src.cur = r
+	src.rows++
+	src.key = m.parseKey(r.cells)
+	if hasPrev && m.less(src.key, prev) {
+		src.disorder++
+		col, _ := excelize.ColumnNumberToName(m.col + 1)
+		if m.opts.Strict {
+			return fmt.Errorf("%s：第%d行，%s列未按键列有序 %s（前一行 %s）", filepath.Base(src.path), r.line, col, src.key.text, prev.text)
+		}
+		log.Printf("%s：第%d行，%s列未按键列有序 %s", filepath.Base(src.path), r.line, col, src.key.text)
+	}
+	return nil
+}
+
+func (m *Merger) parseKey(cells []string) key {
+	var k key
+	if m.col < len(cells) {
+		k.text = cells[m.col]
+	}
+	s := strings.TrimSpace(k.text)
+	if m.opts.Type == option.KeyAuto || m.opts.Type == option.KeyNumber {
+		if v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64); err == nil {
+			k.num, k.isNum = v, true
+		}
+	}
+	if m.opts.Type == option.KeyAuto || m.opts.Type == option.KeyDate {
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				k.date, k.isDate = t, true
+				break
+			}
+		}
+		if !k.isDate && m.opts.Type == option.KeyDate {
+			if v, err := strconv.ParseFloat(s, 64); err == nil { // Excel 日期序列号
+				if t, err := excelize.ExcelDateToTime(v, false); err == nil {
+					k.date, k.isDate = t, true
+				}
+			}
+		}
+	}
+	return k
+}
+
+// rank
+// 自动类型下键值的类别：数值、日期、文本依次排列，保证比较满足传递性
+func (k key) rank() int {
+	switch {
+	case k.isNum:
+		return 0
+	case k.isDate:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// compare
+// 升序比较，无法解析的值排在可解析的值之后；自动类型先按类别（数值、日期、文本）比较，同类再比较值
+func (m *Merger) compare(a, b key) int {
+	if m.opts.Type == option.KeyAuto {
+		if c := cmp.Compare(a.rank(), b.rank()); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case m.opts.Type == option.KeyText:
+		return strings.Compare(a.text, b.text)
+	case m.opts.Type == option.KeyNumber || (m.opts.Type == option.KeyAuto && a.isNum && b.isNum):
+		if a.isNum != b.isNum {
+			if a.isNum {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.num, b.num)
+	case m.opts.Type == option.KeyDate || (m.opts.Type == option.KeyAuto && a.isDate && b.isDate):
+		if a.isDate != b.isDate {
+			if a.isDate {
+				return -1
+			}
+			return 1
+		}
+		return a.date.Compare(b.date)
+	default:
+		return strings.Compare(a.text, b.text)
+	}
+}
+
+func (m *Merger) less(a, b key) bool {
+	if m.opts.Desc {
+		return m.compare(a, b) > 0
+	}
+	return m.compare(a, b) < 0
+}
+
+// Next
+// 取出键值最小（降序则最大）的行，键值相同按数据文件顺序
+func (m *Merger) Next() bool {
+	if m.err != nil {
+		return false
+	}
+	if m.cur != nil {
+		if m.err = m.cur.pull(m); m.err != nil {
+			return false
+		}
+		if m.cur.cur.cells != nil {
+			heap.Push(&m.heap, m.cur)
+		}
+		m.cur = nil
+	}
+	if len(m.heap.srcs) == 0 {
+		return false
+	}
+	m.cur = heap.Pop(&m.heap).(*input)
+	return true
+}
+
+// Row
+// 当前行所在数据文件序号、文件内行号（含行首）及各列值
+func (m *Merger) Row() (int, int, []string) {
+	return m.cur.idx, m.cur.cur.line, m.cur.cur.cells
+}
+
+// Styles
+// 当前行各单元格的样式 ID，非 xlsx 类数据文件为 nil
+func (m *Merger) Styles() []int {
+	return m.cur.cur.styles
+}
+
+// FileRows
+// 各数据文件已读取的数据行数
+func (m *Merger) FileRows() []int {
+	res := make([]int, len(m.srcs))
+	for i, src := range m.srcs {
+		res[i] = src.rows
+	}
+	return res
+}
+
+// Report
+// 输出各数据文件未按键列有序的行数
+func (m *Merger) Report() {
+	for i, src := range m.srcs {
+		if src.disorder > 0 {
+			fmt.Printf("%s数据文件%d有%s未按键列有序，合并结果可能局部无序\n", color.HiRedString("注意："), i+1, color.HiRedString("%d行", src.disorder))
+			log.Printf("%s：%d 行未按键列有序", filepath.Base(src.path), src.disorder)
+		}
+	}
+}
+
+func (m *Merger) Err() error {
+	return m.err
+}
+
+// Close
+// 停止各读取协程并释放文件
+func (m *Merger) Close() {
+	m.cancel()
+	for _, src := range m.srcs {
+		for range src.ch {
+		}
+	}
+}
+
+type srcHeap struct {
+	m    *Merger
+	srcs []*input
+}
+
+func (h srcHeap) Len() int { return len(h.srcs) }
+func (h srcHeap) Less(i, j int) bool {
+	a, b := h.srcs[i], h.srcs[j]
+	if h.m.less(a.key, b.key) {
+		return true
+	}
+	if h.m.less(b.key, a.key) {
+		return false
+	}
+	return a.idx < b.idx
+}
+func (h srcHeap) Swap(i, j int) { h.srcs[i], h.srcs[j] = h.srcs[j], h.srcs[i] }
+func (h *srcHeap) Push(x any)   { h.srcs = append(h.srcs, x.(*input)) }
+func (h *srcHeap) Pop() any {
+	old := h.srcs
+	src := old[len(old)-1]
+	h.srcs = old[:len(old)-1]
+	return src
+}
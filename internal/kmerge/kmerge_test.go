@@ -0,0 +1,130 @@
+package kmerge
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/testutil"
+)
+
+func TestCompareTotalOrder(t *testing.T) {
+	m := &Merger{opts: option.Sort{Type: option.KeyAuto}}
+	values := []string{"2", "10", "1a", "-3.5", "2024-01-02", "2023/12/31", "abc", "", "1,000"}
+	keys := make([]key, len(values))
+	for i, v := range values {
+		keys[i] = m.parseKey([]string{v})
+	}
+	for _, a := range keys {
+		for _, b := range keys {
+			if m.compare(a, b) != -m.compare(b, a) {
+				t.Errorf("compare(%q, %q) 不对称", a.text, b.text)
+			}
+			for _, c := range keys {
+				if m.compare(a, b) <= 0 && m.compare(b, c) <= 0 && m.compare(a, c) > 0 {
+					t.Errorf("compare 不满足传递性：%q <= %q <= %q", a.text, b.text, c.text)
+				}
+			}
+		}
+	}
+	slices.SortFunc(keys, m.compare)
+	var got []string
+	for _, k := range keys {
+		got = append(got, k.text)
+	}
+	want := []string{"-3.5", "2", "10", "1,000", "2023/12/31", "2024-01-02", "", "1a", "abc"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMerger(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     option.Sort
+		files    []string
+		want     []string // 各行键列值
+		disorder []int
+		wantErr  bool
+	}{
+		{
+			"数值",
+			option.Sort{Key: "k"},
+			[]string{"k,v\n1,a\n5,b\n10,c\n", "k,v\n2,d\n5,e\n"},
+			[]string{"1", "2", "5", "5", "10"},
+			[]int{0, 0},
+			false,
+		},
+		{
+			"降序文本",
+			option.Sort{Key: "A", Type: option.KeyText, Desc: true},
+			[]string{"k\nc\na\n", "k\nd\nb\n"},
+			[]string{"d", "c", "b", "a"},
+			[]int{0, 0},
+			false,
+		},
+		{
+			"数值排在文本之前",
+			option.Sort{Key: "k"},
+			[]string{"k\n3\nx\n", "k\n20\ny\n"},
+			[]string{"3", "20", "x", "y"},
+			[]int{0, 0},
+			false,
+		},
+		{
+			"无序行计数",
+			option.Sort{Key: "k"},
+			[]string{"k\n1\n3\n2\n4\n", "k\n5\n"},
+			[]string{"1", "3", "2", "4", "5"},
+			[]int{1, 0},
+			false,
+		},
+		{
+			"无序行终止",
+			option.Sort{Key: "k", Strict: true},
+			[]string{"k\n1\n3\n2\n"},
+			nil,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var srcs []string
+			for i, content := range tt.files {
+				srcs = append(srcs, testutil.WriteFile(t, dir, string(rune('a'+i))+".csv", content))
+			}
+			m, err := Open(srcs, tt.opts, option.Source{}, context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+			var got []string
+			for m.Next() {
+				_, _, row := m.Row()
+				got = append(got, row[0])
+			}
+			if tt.wantErr {
+				if m.Err() == nil {
+					t.Error("期望返回错误")
+				}
+				return
+			}
+			if err := m.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			var disorder []int
+			for _, src := range m.srcs {
+				disorder = append(disorder, src.disorder)
+			}
+			if !slices.Equal(disorder, tt.disorder) {
+				t.Errorf("disorder = %v, want %v", disorder, tt.disorder)
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package option
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Overflow
+// 合并结果超出 Excel 最大行数（1048576）时的处理方式
+type Overflow int
+
+const (
+	OverflowSheet  Overflow = iota // 续写至新表 data_2、data_3…
+	OverflowFile                   // 续写至新文件 -merge-2.xlsx、-merge-3.xlsx…
+	OverflowRefuse                 // 合并前统计行数，超出则拒绝合并
+)
+
+func ParseOverflow(s string) (Overflow, error) {
+	switch s {
+	case "", "sheet":
+		return OverflowSheet, nil
+	case "file":
+		return OverflowFile, nil
+	case "refuse":
+		return OverflowRefuse, nil
+	default:
+		return OverflowSheet, fmt.Errorf("不支持的超行处理方式：%s（可选 sheet、file、refuse）", s)
+	}
+}
+
+// Keep
+// 去重时保留重复行中的哪一行
+type Keep int
+
+const (
+	KeepFirst Keep = iota // 保留首次出现的行
+	KeepLast              // 保留最后出现的行
+)
+
+func ParseKeep(s string) (Keep, error) {
+	switch s {
+	case "", "first":
+		return KeepFirst, nil
+	case "last":
+		return KeepLast, nil
+	default:
+		return KeepFirst, fmt.Errorf("不支持的去重保留方式：%s（可选 first、last）", s)
+	}
+}
+
+// Dedup
+// 合并去重，Keys 为空则按整行去重
+type Dedup struct {
+	Enabled bool
+	Keys    []string // 键列，列名（A、B…）或行首文字
+	Keep    Keep
+}
+
+// KeyType
+// 有序合并时键列的比较方式
+type KeyType int
+
+const (
+	KeyAuto   KeyType = iota // 数值、日期、文本依次排列，同类按数值、日期或文本比较
+	KeyNumber                // 按数值
+	KeyText                  // 按文本
+	KeyDate                  // 按日期
+)
+
+func ParseKeyType(s string) (KeyType, error) {
+	switch s {
+	case "", "auto":
+		return KeyAuto, nil
+	case "number":
+		return KeyNumber, nil
+	case "text":
+		return KeyText, nil
+	case "date":
+		return KeyDate, nil
+	default:
+		return KeyAuto, fmt.Errorf("不支持的键列类型：%s（可选 auto、number、text、date）", s)
+	}
+}
+
+// Sort
+// 有序合并，各数据文件已按键列排序，合并结果整体有序
+type Sort struct {
+	Enabled bool
+	Key     string // 键列，列名（A、B…）或行首文字
+	Type    KeyType
+	Desc    bool // 降序
+	Strict  bool // 数据文件未按键列有序时终止合并
+}
+
+// JoinType
+// 按键列横向拼接时的连接方式
+type JoinType int
+
+const (
+	JoinLeft  JoinType = iota // 保留左表全部行
+	JoinInner                 // 仅保留两表均匹配的行
+	JoinFull                  // 保留两表全部行
+)
+
+func ParseJoinType(s string) (JoinType, error) {
+	switch s {
+	case "", "left":
+		return JoinLeft, nil
+	case "inner":
+		return JoinInner, nil
+	case "full":
+		return JoinFull, nil
+	default:
+		return JoinLeft, fmt.Errorf("不支持的连接方式：%s（可选 left、inner、full）", s)
+	}
+}
+
+// Join
+// 按键列横向拼接左右两表，RightKey 为空则与 Key 相同
+type Join struct {
+	Key      string // 左表键列，列名（A、B…）或行首文字
+	RightKey string // 右表键列
+	Type     JoinType
+}
+
+// Protect
+// 导出 xlsx 加密，Password 为空且 Random 为 false 则不加密
+type Protect struct {
+	Password string // 所有导出文件使用同一密码
+	Random   bool   // 每个导出文件（含拆分的每一份）使用独立随机密码，并生成密码清单
+}
+
+func (p Protect) Enabled() bool {
+	return p.Password != "" || p.Random
+}
+
+// DateFormat
+// 导出 CSV 时日期、日期时间的格式（Go 时间格式），为空则保持 ISO 8601
+type DateFormat struct {
+	Date     string // 日期，如 2026-01-02
+	DateTime string // 日期时间，如 2026-01-02 15:04:05
+}
+
+// CSVDialect
+// 导出 CSV 的格式，零值为 UTF-8 编码、逗号分隔、按需加引号、LF 换行、写 BOM、空值留空
+type CSVDialect struct {
+	Comma    rune   // 分隔符，0 为逗号
+	QuoteAll bool   // 所有字段均加引号
+	CRLF     bool   // 以 CRLF 换行
+	NoBOM    bool   // 不写 UTF-8 BOM
+	Null     string // 数据行中空值的表示，如 NULL、\N
+	Encoding string // 编码：空（UTF-8）、GBK、GB18030
+}
+
+// ParseEncoding
+// 解析导出 CSV 的编码，GBK、GB18030 供只认本地编码的旧系统使用
+func ParseEncoding(s string) (string, error) {
+	switch strings.ToUpper(strings.ReplaceAll(s, "-", "")) {
+	case "", "UTF8":
+		return "", nil
+	case "GBK", "CP936":
+		return "GBK", nil
+	case "GB18030":
+		return "GB18030", nil
+	default:
+		return "", fmt.Errorf("不支持的编码：%s（可选 utf-8、gbk、gb18030）", s)
+	}
+}
+
+// ParseFormat
+// 解析导出格式（xlsx、csv、tsv、jsonl、parquet，可带点号），返回扩展名；空则为 def
+func ParseFormat(s string, def string) (string, error) {
+	switch ext := "." + strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "."); ext {
+	case ".":
+		return def, nil
+	case ".xlsx", ".csv", ".tsv", ".jsonl", ".parquet":
+		return ext, nil
+	default:
+		return "", fmt.Errorf("不支持的导出格式：%s（可选 xlsx、csv、tsv、jsonl、parquet）", s)
+	}
+}
+
+// ParseCSVDialect
+// 由命令行参数组装导出 CSV 的格式
+func ParseCSVDialect(delimiter string, quoteAll bool, crlf bool, bom bool, null string, enc string) (CSVDialect, error) {
+	comma, err := ParseDelimiter(delimiter)
+	if err != nil {
+		return CSVDialect{}, err
+	}
+	encoding, err := ParseEncoding(enc)
+	if err != nil {
+		return CSVDialect{}, err
+	}
+	return CSVDialect{Comma: comma, QuoteAll: quoteAll, CRLF: crlf, NoBOM: !bom, Null: null, Encoding: encoding}, nil
+}
+
+// Parquet
+// 导出 Parquet 的格式
+type Parquet struct {
+	Compression string // 压缩算法：UNCOMPRESSED、SNAPPY、GZIP、ZSTD
+	RowGroupMB  int    // 行组大小（MB，按未压缩数据估算），行组在内存中攒满后写出
+}
+
+// ParseParquet
+// 解析导出 Parquet 的压缩算法（none、snappy、gzip、zstd）和行组大小
+func ParseParquet(compression string, rowGroupMB int) (Parquet, error) {
+	var res Parquet
+	switch strings.ToLower(compression) {
+	case "none", "uncompressed":
+		res.Compression = "UNCOMPRESSED"
+	case "", "snappy":
+		res.Compression = "SNAPPY"
+	case "gzip", "zstd":
+		res.Compression = strings.ToUpper(compression)
+	default:
+		return res, fmt.Errorf("不支持的压缩算法：%s（可选 none、snappy、gzip、zstd）", compression)
+	}
+	if rowGroupMB < 1 {
+		return res, fmt.Errorf("行组大小须大于0：%d", rowGroupMB)
+	}
+	res.RowGroupMB = rowGroupMB
+	return res, nil
+}
+
+// ParseDelimiter
+// 解析分隔符：单个字符，tab 或 \t 为制表符
+func ParseDelimiter(s string) (rune, error) {
+	switch strings.ToLower(s) {
+	case "", ",":
+		return ',', nil
+	case "tab", `\t`, "\t":
+		return '\t', nil
+	}
+	r := []rune(s)
+	if len(r) != 1 || r[0] == '"' || r[0] == '\r' || r[0] == '\n' || r[0] == utf8.RuneError {
+		return 0, fmt.Errorf("不支持的分隔符：%s（可选 ,、;、tab、| 等单个字符）", s)
+	}
+	return r[0], nil
+}
+
+// ParseDateLayout
+// 将 Excel 风格的日期格式（yyyy、yy、m、mm、d、dd、h、hh、s、ss，m、mm 紧随 h 或后接 s 时为分钟）
+// 转换为 Go 时间格式，如 yyyy/m/d hh:mm → 2006/1/2 15:04；为空或 iso 则返回空，即保持 ISO 8601
+func ParseDateLayout(s string) (string, error) {
+	if s == "" || strings.EqualFold(s, "iso") {
+		return "", nil
+	}
+	type token struct {
+		c byte   // 占位符字母，0 为普通字符
+		n int    // 占位符长度
+		s string // 普通字符
+	}
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i] | 0x20 // 转小写
+		if !strings.ContainsRune("ymdhs", rune(c)) {
+			if s[i] >= '0' && s[i] <= '9' {
+				return "", fmt.Errorf("日期格式不能含数字：%s", s)
+			}
+			tokens = append(tokens, token{s: s[i : i+1]})
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && s[j]|0x20 == c {
+			j++
+		}
+		tokens = append(tokens, token{c: c, n: j - i})
+		i = j
+	}
+	var b strings.Builder
+	found, prev := false, byte(0)
+	for i, t := range tokens {
+		if t.c == 0 {
+			b.WriteString(t.s)
+			continue
+		}
+		found = true
+		next := byte(0)
+		for _, u := range tokens[i+1:] {
+			if u.c != 0 {
+				next = u.c
+				break
+			}
+		}
+		switch {
+		case t.c == 'y' && t.n == 2:
+			b.WriteString("06")
+		case t.c == 'y' && t.n >= 4:
+			b.WriteString("2006")
+		case t.c == 'y':
+			return "", fmt.Errorf("不支持的年份格式：%s", strings.Repeat("y", t.n))
+		case t.c == 'm' && (prev == 'h' || next == 's'): // 分钟
+			b.WriteString([]string{"4", "04"}[min(t.n, 2)-1])
+		case t.c == 'm':
+			b.WriteString([]string{"1", "01", "Jan", "January"}[min(t.n, 4)-1])
+		case t.c == 'd':
+			b.WriteString([]string{"2", "02", "Mon", "Monday"}[min(t.n, 4)-1])
+		case t.c == 'h':
+			b.WriteString("15")
+		case t.c == 's':
+			b.WriteString([]string{"5", "05"}[min(t.n, 2)-1])
+		}
+		prev = t.c
+	}
+	if !found {
+		return "", fmt.Errorf("日期格式缺少 y、m、d、h、s 占位符：%s", s)
+	}
+	return b.String(), nil
+}
+
+// Formula
+// 导出 xlsx 时公式的处理方式
+type Formula int
+
+const (
+	FormulaValue Formula = iota // 按计算结果（文件中缓存的值）写入
+	FormulaKeep                 // 保留公式，引用按新行号改写，无法保留的按计算结果写入
+)
+
+func ParseFormula(s string) (Formula, error) {
+	switch s {
+	case "", "value":
+		return FormulaValue, nil
+	case "keep":
+		return FormulaKeep, nil
+	default:
+		return FormulaValue, fmt.Errorf("不支持的公式处理方式：%s（可选 value、keep）", s)
+	}
+}
+
+// Merged
+// 数据文件中合并单元格的处理方式
+type Merged int
+
+const (
+	MergedNone Merged = iota // 拆分，仅左上角单元格有值
+	MergedFill               // 拆分，区域内各单元格均填充左上角的值
+	MergedKeep               // 导出 xlsx 时保留合并区域（区域各行须连续写入同一表），其余同 none
+)
+
+func ParseMerged(s string) (Merged, error) {
+	switch s {
+	case "", "none":
+		return MergedNone, nil
+	case "fill":
+		return MergedFill, nil
+	case "keep":
+		return MergedKeep, nil
+	default:
+		return MergedNone, fmt.Errorf("不支持的合并单元格处理方式：%s（可选 none、fill、keep）", s)
+	}
+}
+
+// Source
+// 读取数据文件的选项
+type Source struct {
+	Unlocked   map[string][]byte // 已解锁的加密文件及其解密所得的工作簿（仅在内存中）
+	FillMerged bool              // 读取 xlsx 类文件时拆分合并单元格，区域内各单元格填充左上角的值（xls 不支持）
+	RawNumbers bool              // 读取 xlsx 类文件时数值按存储的原始值读出，不套用数字格式（导出 xlsx 沿用样式时使用）
+}
+
+// Options
+// 合并、拆分选项，由命令行参数解析而来，零值即默认行为
+type Options struct {
+	Overflow  Overflow
+	Dedup     Dedup
+	Sort      Sort
+	Join      Join
+	Protect   Protect
+	Date      DateFormat
+	CSV       CSVDialect
+	Parquet   Parquet
+	Formula   Formula
+	Merged    Merged
+	Source    Source
+	Sample    int      // 解析数据格式时采样的数据行数，0 为默认（1000）
+	CellStyle bool     // 逐单元格保留样式，否则各列统一使用解析所得的列样式
+	TextCols  []string // 导出 xlsx 时按文本写入的列，列名（A、B…）或行首文字
+}
+
+// Order
+// 数据文件排序方式
+type Order int
+
+const (
+	OrderArg     Order = iota // 保持输入顺序，目录、通配符展开的文件按名称
+	OrderName                 // 按名称
+	OrderMtime                // 按修改时间
+	OrderNatural              // 按名称自然排序，如 part2 排在 part10 之前
+)
+
+func ParseOrder(s string) (Order, error) {
+	switch s {
+	case "":
+		return OrderArg, nil
+	case "name":
+		return OrderName, nil
+	case "mtime":
+		return OrderMtime, nil
+	case "natural":
+		return OrderNatural, nil
+	default:
+		return OrderArg, fmt.Errorf("不支持的排序方式：%s（可选 name、mtime、natural）", s)
+	}
+}
@@ -0,0 +1,118 @@
+package option
+
+import "testing"
+
+func TestParseOverflow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Overflow
+		wantErr bool
+	}{
+		{"", OverflowSheet, false},
+		{"sheet", OverflowSheet, false},
+		{"file", OverflowFile, false},
+		{"refuse", OverflowRefuse, false},
+		{"Sheet", OverflowSheet, true},
+		{"split", OverflowSheet, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOverflow(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseOverflow(%q) = %v, %v; want %v, err %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseDateLayout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"ISO", "", false},
+		{"yyyy/m/d", "2006/1/2", false},
+		{"yyyy-mm-dd", "2006-01-02", false},
+		{"yy.mm.dd", "06.01.02", false},
+		{"yyyy/m/d hh:mm", "2006/1/2 15:04", false},
+		{"yyyy/mm/dd hh:mm:ss", "2006/01/02 15:04:05", false},
+		{"mm:ss", "04:05", false},
+		{"d mmm yyyy", "2 Jan 2006", false},
+		{"yyy", "", true},
+		{"yyyy2", "", true},
+		{"-", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDateLayout(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDateLayout(%q) = %q, %v; want %q, err %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseCSVDialect(t *testing.T) {
+	tests := []struct {
+		delimiter string
+		bom       bool
+		want      CSVDialect
+		wantErr   bool
+	}{
+		{"", true, CSVDialect{Comma: ','}, false},
+		{";", false, CSVDialect{Comma: ';', NoBOM: true}, false},
+		{"tab", true, CSVDialect{Comma: '\t'}, false},
+		{`\t`, true, CSVDialect{Comma: '\t'}, false},
+		{"|", true, CSVDialect{Comma: '|'}, false},
+		{`"`, true, CSVDialect{}, true},
+		{"\n", true, CSVDialect{}, true},
+		{";;", true, CSVDialect{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCSVDialect(tt.delimiter, false, false, tt.bom, "", "")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseCSVDialect(%q, bom %v) = %+v, %v; want %+v, err %v", tt.delimiter, tt.bom, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"utf-8", "", false},
+		{"UTF8", "", false},
+		{"gbk", "GBK", false},
+		{"cp936", "GBK", false},
+		{"GB-18030", "GB18030", false},
+		{"big5", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEncoding(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseEncoding(%q) = %q, %v; want %q, err %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", ".xlsx", false},
+		{"csv", ".csv", false},
+		{".TSV", ".tsv", false},
+		{" jsonl ", ".jsonl", false},
+		{"parquet", ".parquet", false},
+		{"json", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in, ".xlsx")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q, err %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
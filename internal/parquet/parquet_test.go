@@ -0,0 +1,541 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/testutil"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// 以下为测试用的最小 Parquet 读取端：按规范解析本包写出的文件（Thrift compact 元数据、PLAIN 编码、
+// 位宽 1 的 RLE/位打包定义级别、各压缩算法），用于校验往返结果；读取端本身由独立实现写出的 testdata/reference.parquet 校验
+
+// thriftReader
+// Thrift compact 协议解码：整数为 int64，binary 为 []byte，列表为 []any，结构体为 序号 - 值
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		panic(fmt.Sprintf("位置 %d：varint 无效", r.pos))
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case ctTrue:
+		return true
+	case ctFalse:
+		return false
+	case ctI32, ctI64:
+		return r.zigzag()
+	case ctBinary:
+		n := int(r.uvarint())
+		r.pos += n
+		return r.buf[r.pos-n : r.pos]
+	case ctList:
+		h := r.byte()
+		n, elem := int(h>>4), h&0x0F
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		res := make([]any, n)
+		for i := range res {
+			res[i] = r.value(elem)
+		}
+		return res
+	case ctStruct:
+		return r.fields()
+	}
+	panic(fmt.Sprintf("位置 %d：不支持的字段类型 %d", r.pos, typ))
+}
+
+func (r *thriftReader) fields() map[int16]any {
+	res := make(map[int16]any)
+	var last int16
+	for {
+		h := r.byte()
+		if h == 0 {
+			return res
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		res[id] = r.value(h & 0x0F)
+		last = id
+	}
+}
+
+// parquetFile
+// 解析所得的文件：列名、物理类型、各行组的行数及按行排列的值
+type parquetFile struct {
+	names  []string
+	types  []int64
+	groups []int64
+	rows   [][]any
+}
+
+// readParquet
+// 读取本包写出的 Parquet 文件，空值为 nil，布尔、int64、double、字符串值分别为 bool、int64、float64、string
+func readParquet(t *testing.T, path string) parquetFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 12 || string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatal("缺少首尾标识 PAR1")
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &thriftReader{buf: data[len(data)-8-size : len(data)-8]}
+	meta := footer.fields()
+	if footer.pos != size {
+		t.Fatalf("文件元数据长度 = %d, want %d", footer.pos, size)
+	}
+
+	var res parquetFile
+	schema := meta[2].([]any)
+	if n := schema[0].(map[int16]any)[5].(int64); int(n) != len(schema)-1 {
+		t.Fatalf("根节点 num_children = %d, want %d", n, len(schema)-1)
+	}
+	for _, e := range schema[1:] {
+		e := e.(map[int16]any)
+		res.names = append(res.names, string(e[4].([]byte)))
+		res.types = append(res.types, e[1].(int64))
+	}
+	for _, g := range meta[4].([]any) {
+		g := g.(map[int16]any)
+		rows := g[3].(int64)
+		res.groups = append(res.groups, rows)
+		cols := make([][]any, len(res.names))
+		for c, chunk := range g[1].([]any) {
+			cm := chunk.(map[int16]any)[3].(map[int16]any)
+			cols[c] = readChunk(t, data, cm, res.types[c])
+			if int64(len(cols[c])) != rows || cm[5].(int64) != rows {
+				t.Fatalf("%s：%d 个值，num_values = %d, want %d", res.names[c], len(cols[c]), cm[5], rows)
+			}
+			var nulls int64
+			for _, v := range cols[c] {
+				if v == nil {
+					nulls++
+				}
+			}
+			if got := cm[12].(map[int16]any)[3].(int64); got != nulls {
+				t.Errorf("%s：null_count = %d, want %d", res.names[c], got, nulls)
+			}
+		}
+		for i := range rows {
+			row := make([]any, len(cols))
+			for c := range cols {
+				row[c] = cols[c][i]
+			}
+			res.rows = append(res.rows, row)
+		}
+	}
+	if n := meta[3].(int64); n != int64(len(res.rows)) {
+		t.Errorf("num_rows = %d, want %d", n, len(res.rows))
+	}
+	return res
+}
+
+// readChunk
+// 依次解析列数据中的数据页
+func readChunk(t *testing.T, data []byte, meta map[int16]any, typ int64) []any {
+	t.Helper()
+	pos := int(meta[9].(int64))
+	end := pos + int(meta[7].(int64))
+	var res []any
+	for pos < end {
+		r := &thriftReader{buf: data[pos:end]}
+		h := r.fields()
+		if h[1].(int64) != pageData {
+			t.Fatalf("页类型 = %d", h[1])
+		}
+		compressed := int(h[3].(int64))
+		body := decompress(t, meta[4].(int64), data[pos+r.pos:pos+r.pos+compressed])
+		if int64(len(body)) != h[2].(int64) {
+			t.Fatalf("解压后 %d 字节，页头记录 %d 字节", len(body), h[2])
+		}
+		res = append(res, readPage(t, body, int(h[5].(map[int16]any)[1].(int64)), typ)...)
+		pos += r.pos + compressed
+	}
+	return res
+}
+
+func decompress(t *testing.T, codec int64, b []byte) []byte {
+	t.Helper()
+	var (
+		res []byte
+		err error
+	)
+	switch codec {
+	case int64(codecs["UNCOMPRESSED"]):
+		return b
+	case int64(codecs["SNAPPY"]):
+		res, err = s2.Decode(nil, b)
+	case int64(codecs["GZIP"]):
+		var gr *gzip.Reader
+		if gr, err = gzip.NewReader(bytes.NewReader(b)); err == nil {
+			res, err = io.ReadAll(gr)
+		}
+	case int64(codecs["ZSTD"]):
+		var dec *zstd.Decoder
+		if dec, err = zstd.NewReader(nil); err == nil {
+			res, err = dec.DecodeAll(b, nil)
+			dec.Close()
+		}
+	default:
+		t.Fatalf("未知的压缩算法：%d", codec)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// readPage
+// 解析数据页：定义级别（4 字节长度 + RLE/位打包）及 PLAIN 编码的非空值
+func readPage(t *testing.T, body []byte, n int, typ int64) []any {
+	t.Helper()
+	size := int(binary.LittleEndian.Uint32(body))
+	levels := &thriftReader{buf: body[4 : 4+size]}
+	var defs []byte
+	for levels.pos < size {
+		h := levels.uvarint()
+		if h&1 == 0 { // RLE 段
+			d := levels.byte()
+			for range h >> 1 {
+				defs = append(defs, d)
+			}
+			continue
+		}
+		for range h >> 1 { // 位打包，每组 8 个值
+			b := levels.byte()
+			for i := range 8 {
+				defs = append(defs, b>>i&1)
+			}
+		}
+	}
+	if len(defs) < n {
+		t.Fatalf("定义级别 %d 个，want %d", len(defs), n)
+	}
+	values := body[4+size:]
+	res := make([]any, n)
+	bit := 0
+	for i, d := range defs[:n] {
+		if d == 0 {
+			continue
+		}
+		switch typ {
+		case typeBoolean:
+			res[i] = values[bit/8]>>(bit%8)&1 == 1
+			bit++
+		case typeInt64:
+			res[i] = int64(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case typeDouble:
+			res[i] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case typeByteArray:
+			l := int(binary.LittleEndian.Uint32(values))
+			res[i] = string(values[4 : 4+l])
+			values = values[4+l:]
+		}
+	}
+	if typ != typeBoolean && len(values) > 0 {
+		t.Errorf("数据页末尾多出 %d 字节", len(values))
+	}
+	return res
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	cols := []column{
+		{"id", kindInt64}, {"flag", kindBool}, {"amount", kindDouble},
+		{"at", kindTimestamp}, {"name", kindString}, {"empty", kindString},
+	}
+	// 各列的空值间隔不同，部分行短于列数（缺少的列为空值）
+	var (
+		rows [][]string
+		want [][]any
+	)
+	for i := range 100 {
+		row := make([]string, len(cols))
+		exp := make([]any, len(cols))
+		if i%7 != 3 {
+			row[0], exp[0] = fmt.Sprint(i-50), int64(i-50)
+		}
+		switch i % 3 {
+		case 0:
+			row[1], exp[1] = "TRUE", true
+		case 1:
+			row[1], exp[1] = "FALSE", false
+		}
+		if i%5 != 0 {
+			row[2], exp[2] = fmt.Sprint(float64(i)*0.25), float64(i)*0.25
+		}
+		switch i % 4 {
+		case 1:
+			row[3], exp[3] = fmt.Sprintf("2026-01-%02d", i%28+1), time.Date(2026, 1, i%28+1, 0, 0, 0, 0, time.UTC).UnixMilli()
+		case 2:
+			row[3], exp[3] = fmt.Sprintf("1999-12-31 23:59:%02d", i%60), time.Date(1999, 12, 31, 23, 59, i%60, 0, time.UTC).UnixMilli()
+		}
+		if i%6 != 0 {
+			row[4], exp[4] = fmt.Sprintf("名称-%d", i), fmt.Sprintf("名称-%d", i)
+		}
+		if i%10 == 9 {
+			row = row[:2]
+			clear(exp[2:])
+		}
+		rows = append(rows, row)
+		want = append(want, exp)
+	}
+	for _, compression := range []string{"UNCOMPRESSED", "SNAPPY", "GZIP", "ZSTD"} {
+		t.Run(compression, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.parquet")
+			w, err := newFileWriter(path, cols, option.Parquet{Compression: compression, RowGroupMB: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.groupSize = 1 << 9 // 约 20 行一个行组
+			for i, row := range rows {
+				if err := w.Write(row, "a.csv", i+2); err != nil {
+					w.Close()
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			got := readParquet(t, path)
+			if len(got.groups) < 2 {
+				t.Errorf("行组 %v，want 多个", got.groups)
+			}
+			wantNames := []string{"id", "flag", "amount", "at", "name", "empty"}
+			wantTypes := []int64{typeInt64, typeBoolean, typeDouble, typeInt64, typeByteArray, typeByteArray}
+			if !reflect.DeepEqual(got.names, wantNames) || !reflect.DeepEqual(got.types, wantTypes) {
+				t.Errorf("schema = %q %v, want %q %v", got.names, got.types, wantNames, wantTypes)
+			}
+			if len(got.rows) != len(want) {
+				t.Fatalf("%d 行, want %d", len(got.rows), len(want))
+			}
+			for i := range want {
+				if !reflect.DeepEqual(got.rows[i], want[i]) {
+					t.Errorf("第%d行 = %v, want %v", i+1, got.rows[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriterMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.parquet")
+	w, err := newFileWriter(path, []column{{"id", kindInt64}}, option.Parquet{Compression: "UNCOMPRESSED", RowGroupMB: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var mismatch *mismatchError
+	if err := w.Write([]string{"x"}, "a.csv", 5); !errors.As(err, &mismatch) || mismatch.col != 0 {
+		t.Errorf("与列类型不符的值：err = %v, want *mismatchError", err)
+	}
+}
+
+// 与 testdata 中的 Parquet 文件对应的数据：reference.parquet 由独立实现 github.com/xitongsys/parquet-go v1.6.2
+// 按相同结构写出（SNAPPY，单个行组）；golden.parquet 为本包按每 4 行一个行组写出的结果，已用该实现读取校验，
+// 结构、行组、取值均与下列一致。写出格式变更时须重新生成并校验
+var (
+	goldenCols = []column{
+		{"id", kindInt64}, {"flag", kindBool}, {"amount", kindDouble}, {"at", kindTimestamp}, {"name", kindString},
+	}
+	goldenRows = [][]string{
+		{"1", "TRUE", "1.5", "2026-01-02", "甲"},
+		{"-2", "FALSE", "", "1999-12-31 23:59:59", "乙"},
+		{"", "TRUE", "0.25", "", ""},
+		{"123456789012", "", "-3", "2026-10-19 08:30:00", "含,逗号"},
+		{"0", "FALSE", "0.001", "", "x"},
+		{},
+		{"42"},
+		{"7", "TRUE", "100", "2000-02-29", "末行"},
+	}
+	goldenWant = [][]any{
+		{int64(1), true, 1.5, int64(1767312000000), "甲"},
+		{int64(-2), false, nil, int64(946684799000), "乙"},
+		{nil, true, 0.25, nil, nil},
+		{int64(123456789012), nil, -3.0, int64(1792398600000), "含,逗号"},
+		{int64(0), false, 0.001, nil, "x"},
+		{nil, nil, nil, nil, nil},
+		{int64(42), nil, nil, nil, nil},
+		{int64(7), true, 100.0, int64(951782400000), "末行"},
+	}
+)
+
+func TestReadReference(t *testing.T) {
+	got := readParquet(t, filepath.Join("testdata", "reference.parquet"))
+	wantNames := []string{"id", "flag", "amount", "at", "name"}
+	wantTypes := []int64{typeInt64, typeBoolean, typeDouble, typeInt64, typeByteArray}
+	if !reflect.DeepEqual(got.names, wantNames) || !reflect.DeepEqual(got.types, wantTypes) {
+		t.Errorf("schema = %q %v, want %q %v", got.names, got.types, wantNames, wantTypes)
+	}
+	if !reflect.DeepEqual(got.rows, goldenWant) {
+		t.Errorf("rows = %v, want %v", got.rows, goldenWant)
+	}
+}
+
+func TestWriterGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.parquet")
+	w, err := newFileWriter(path, goldenCols, option.Parquet{Compression: "SNAPPY", RowGroupMB: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.groupSize = 100 // 每 4 行一个行组
+	for i, row := range goldenRows {
+		if err := w.Write(row, "a.csv", i+2); err != nil {
+			w.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "golden.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("写出 %d 字节，与 testdata/golden.parquet（%d 字节）不一致", len(got), len(want))
+	}
+	if pf := readParquet(t, path); !reflect.DeepEqual(pf.rows, goldenWant) || !reflect.DeepEqual(pf.groups, []int64{4, 4}) {
+		t.Errorf("rows = %v，行组 %v", pf.rows, pf.groups)
+	}
+}
+
+func TestWiden(t *testing.T) {
+	tests := []struct {
+		a, b kind
+		want kind
+	}{
+		{kindNone, kindInt64, kindInt64},
+		{kindBool, kindNone, kindBool},
+		{kindInt64, kindDouble, kindDouble},
+		{kindDouble, kindInt64, kindDouble},
+		{kindInt64, kindBool, kindString},
+		{kindTimestamp, kindDouble, kindString},
+		{kindString, kindInt64, kindString},
+	}
+	for _, tt := range tests {
+		if got := widen(tt.a, tt.b); got != tt.want {
+			t.Errorf("widen(%s, %s) = %s, want %s", kindNames[tt.a], kindNames[tt.b], kindNames[got], kindNames[tt.want])
+		}
+	}
+}
+
+func TestSampleColumns(t *testing.T) {
+	dir := t.TempDir()
+	a := testutil.WriteFile(t, dir, "a.csv", "id,code,price,note\n1,10,1,\n2,11,2,\n3,x12,3.5,y\n")
+	b := testutil.WriteFile(t, dir, "b.csv", "id,code,price\n4,13,4.5\n")
+	// 采样仅 2 行：a.csv 第 3 个数据行及 b.csv 第 1 个数据行之后的取值不参与
+	cols, err := sampleColumns([]string{a, b}, option.Options{Sample: 2}, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := describeColumns(cols), "id int64、code int64、price double、note string"; got != want {
+		t.Errorf("sampleColumns = %s, want %s", got, want)
+	}
+	// a.csv 第 3 个数据行的 code 不符：自该行起放宽
+	if err := widenColumns(cols, 1, []string{a, b}, 2, option.Options{Sample: 2}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := describeColumns(cols), "id int64、code string、price double、note string"; got != want {
+		t.Errorf("widenColumns = %s, want %s", got, want)
+	}
+}
+
+func TestMergeWiden(t *testing.T) {
+	dir := t.TempDir()
+	// 采样仅 2 行：a.csv 采样之后出现小数、文本，b.csv 中出现小数，合并时放宽列类型后重新写入
+	a := testutil.WriteFile(t, dir, "a.csv", "id,code,price\n1,10,1\n2,11,2\n3,x12,3\n")
+	b := testutil.WriteFile(t, dir, "b.csv", "id,code,price\n4,13,4.5\n")
+	tarPath := filepath.Join(dir, "out.parquet")
+	opts := option.Options{
+		Sample:  2,
+		Parquet: option.Parquet{Compression: "SNAPPY", RowGroupMB: 64},
+	}
+	if err := MergeXlsx2parquet([]string{a, b}, tarPath, opts, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := readParquet(t, tarPath)
+	wantTypes := []int64{typeInt64, typeByteArray, typeDouble}
+	if !reflect.DeepEqual(got.types, wantTypes) {
+		t.Errorf("types = %v, want %v", got.types, wantTypes)
+	}
+	want := [][]any{
+		{int64(1), "10", 1.0},
+		{int64(2), "11", 2.0},
+		{int64(3), "x12", 3.0},
+		{int64(4), "13", 4.5},
+	}
+	if !reflect.DeepEqual(got.rows, want) {
+		t.Errorf("rows = %v, want %v", got.rows, want)
+	}
+}
+
+func TestSplitWiden(t *testing.T) {
+	dir := t.TempDir()
+	src := testutil.WriteFile(t, dir, "src.csv", "id,at\n1,2026-01-01\n2,2026-01-02\n3,2026-01-03\n4,待定\n")
+	tarDir := filepath.Join(dir, "out")
+	if err := os.MkdirAll(tarDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	opts := option.Options{
+		Sample:  2,
+		Parquet: option.Parquet{Compression: "UNCOMPRESSED", RowGroupMB: 64},
+	}
+	if err := SplitXlsx2parquetByLine(src, tarDir, 2, opts, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][][]any{
+		"out-1.parquet": {{int64(1), "2026-01-01"}, {int64(2), "2026-01-02"}},
+		"out-2.parquet": {{int64(3), "2026-01-03"}, {int64(4), "待定"}},
+	}
+	for name, rows := range want {
+		got := readParquet(t, filepath.Join(tarDir, name))
+		if !reflect.DeepEqual(got.types, []int64{typeInt64, typeByteArray}) || !reflect.DeepEqual(got.rows, rows) {
+			t.Errorf("%s：types = %v，rows = %v, want %v", name, got.types, got.rows, rows)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package parquet
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"gitee.com/nguaduot/split-xlsx-go/internal/xlsx"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/xuri/excelize/v2"
+)
+
+// kind
+// 列的 Parquet 类型
+type kind int
+
+const (
+	kindNone      kind = iota // 全部为空
+	kindBool                  // BOOLEAN
+	kindInt64                 // INT64
+	kindDouble                // DOUBLE
+	kindTimestamp             // INT64（TIMESTAMP_MILLIS）
+	kindString                // BYTE_ARRAY（UTF8）
+)
+
+var kindNames = map[kind]string{
+	kindBool:      "boolean",
+	kindInt64:     "int64",
+	kindDouble:    "double",
+	kindTimestamp: "timestamp",
+	kindString:    "string",
+}
+
+// column
+// Parquet 列：列名取自行首，类型由采样确定（采样之后出现不符的取值则放宽），均可为空
+type column struct {
+	name string
+	kind kind
+}
+
+// physicalType
+// 列的物理类型
+func (c column) physicalType() int32 {
+	switch c.kind {
+	case kindBool:
+		return typeBoolean
+	case kindInt64, kindTimestamp:
+		return typeInt64
+	case kindDouble:
+		return typeDouble
+	default:
+		return typeByteArray
+	}
+}
+
+// schema
+// 写入列的 SchemaElement：物理类型、可为空、列名，字符串、时间戳列附旧版注解（ConvertedType）和逻辑类型
+func (c column) schema(t *thrift) {
+	t.i32(1, c.physicalType())
+	t.i32(3, repetitionOptional)
+	t.binary(4, []byte(c.name))
+	switch c.kind {
+	case kindString:
+		t.i32(6, convertedUTF8)
+		t.begin(10) // LogicalType
+		t.begin(1)  // STRING
+		t.end()
+		t.end()
+	case kindTimestamp:
+		t.i32(6, convertedTimestampMillis) // 规范要求本地时间戳同样写入旧版注解，兼容旧的读取端
+		t.begin(10)                        // LogicalType
+		t.begin(8)                         // TIMESTAMP
+		t.bool(1, false)                   // isAdjustedToUTC：Excel 中的日期时间不含时区
+		t.begin(2)                         // unit
+		t.begin(1)                         // MILLIS
+		t.end()
+		t.end()
+		t.end()
+		t.end()
+	}
+}
+
+// valueKind
+// 单个值适用的类型：日期列中的日期、日期时间为 timestamp（时间为 string），布尔列为 boolean，
+// 数值列中的整数为 int64、小数为 double（含前导零、超出 15 位有效数字的为 string），其余为 string
+func valueKind(s string, m xlsx.CellMeta) kind {
+	if s == "" {
+		return kindNone
+	}
+	if m.Date {
+		if _, k := source.ParseDate(s); k == source.DateOnly || k == source.DateWithTime {
+			return kindTimestamp
+		}
+		return kindString
+	}
+	switch m.TypeIdx {
+	case excelize.CellTypeBool:
+		if s == "TRUE" || s == "FALSE" {
+			return kindBool
+		}
+	case excelize.CellTypeNumber, excelize.CellTypeUnset:
+		if xlsx.KeepText(s) {
+			return kindString
+		}
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return kindInt64
+		}
+		if xlsx.IsNumber(s) {
+			return kindDouble
+		}
+	}
+	return kindString
+}
+
+// widen
+// 合并列中已有的类型与新值的类型：整数与小数混合为 double，其余不一致为 string
+func widen(a kind, b kind) kind {
+	switch {
+	case a == kindNone || a == b:
+		return b
+	case b == kindNone:
+		return a
+	case (a == kindInt64 && b == kindDouble) || (a == kindDouble && b == kindInt64):
+		return kindDouble
+	default:
+		return kindString
+	}
+}
+
+// sampleColumns
+// 采样各数据文件的前若干数据行（同解析数据格式的采样行数），按数据格式和取值确定各列类型，列名取自首个数据文件的行首；
+// 采样中全部为空的列及 -text-cols 指定的列为 string
+func sampleColumns(files []string, opts option.Options, ctx context.Context) ([]column, error) {
+	sample := opts.Sample
+	if sample <= 0 {
+		sample = xlsx.SampleRows
+	}
+	var (
+		header []string
+		kinds  []kind
+	)
+	for i, file := range files {
+		h, err := scanKinds(file, 0, sample, &kinds, opts, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			header = h
+		}
+	}
+	names := make([]string, max(len(header), len(kinds)))
+	copy(names, header)
+	names = util.FieldNames(names)
+	cols := make([]column, len(names))
+	for c, name := range names {
+		cols[c] = column{name: name, kind: kindString}
+		if c < len(kinds) && kinds[c] != kindNone {
+			cols[c].kind = kinds[c]
+		}
+	}
+	for _, key := range opts.TextCols {
+		idx, err := util.ColumnIndexFromKey(header, key)
+		if err != nil {
+			return nil, err
+		}
+		if idx < len(cols) {
+			cols[idx].kind = kindString
+		}
+	}
+	return cols, nil
+}
+
+// widenColumns
+// 数据文件 files[0] 第 skip 个数据行（从0开始）出现不符合第 col 列类型的取值：读取此后的全部数据行放宽各列类型，
+// 已写入的数据行均符合原类型，放宽后同样符合；超出列数的值忽略
+func widenColumns(cols []column, col int, files []string, skip int, opts option.Options, ctx context.Context) error {
+	kinds := make([]kind, len(cols))
+	for c := range cols {
+		kinds[c] = cols[c].kind
+	}
+	for i, file := range files {
+		if i > 0 {
+			skip = 0
+		}
+		if _, err := scanKinds(file, skip, 0, &kinds, opts, ctx); err != nil {
+			return err
+		}
+	}
+	if kinds[col] == cols[col].kind { // 按数据格式判断的类型与取值不符，按 string 写入
+		kinds[col] = kindString
+	}
+	for c := range cols {
+		cols[c].kind = kinds[c]
+	}
+	return nil
+}
+
+// scanKinds
+// 读取数据文件第 skip 个数据行（从0开始）起的至多 limit 个数据行（0 为不限），按数据格式和取值放宽 kinds，
+// 数据行长于 kinds 时追加列；返回行首
+func scanKinds(file string, skip int, limit int, kinds *[]kind, opts option.Options, ctx context.Context) ([]string, error) {
+	meta, err := xlsx.ReadCellMeta(file, opts.Sample, opts.Source)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := source.Open(file, opts.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	var header []string
+	line := 0
+	for iter.Next() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		} // 响应 Ctrl+C 打断
+		line++
+		if line > 1 && line-2 < skip {
+			continue
+		}
+		if limit > 0 && line-2 >= skip+limit {
+			break
+		}
+		row, err := iter.Columns()
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 { // 行首
+			header = row
+			continue
+		}
+		for len(*kinds) < len(row) {
+			*kinds = append(*kinds, kindNone)
+		}
+		for c, val := range row {
+			(*kinds)[c] = widen((*kinds)[c], valueKind(val, meta[c+1]))
+		}
+	}
+	return header, nil
+}
+
+// describeColumns
+// 列类型的说明，如 id int64、姓名 string
+func describeColumns(cols []column) string {
+	items := make([]string, len(cols))
+	for c, col := range cols {
+		items[c] = col.name + " " + kindNames[col.kind]
+	}
+	return strings.Join(items, "、")
+}
+
+// convert
+// 按列类型转换取值，空值为 nil；与列类型不符则返回 false
+func convert(s string, k kind) (any, bool) {
+	if s == "" {
+		return nil, true
+	}
+	switch k {
+	case kindBool:
+		switch s {
+		case "TRUE":
+			return true, true
+		case "FALSE":
+			return false, true
+		}
+	case kindInt64:
+		if xlsx.KeepText(s) {
+			return nil, false
+		}
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, true
+		}
+	case kindDouble:
+		if xlsx.KeepText(s) || !xlsx.IsNumber(s) {
+			return nil, false
+		}
+		f, _ := strconv.ParseFloat(s, 64)
+		return f, true
+	case kindTimestamp:
+		if t, k := source.ParseDate(s); k == source.DateOnly || k == source.DateWithTime {
+			return t.UnixMilli(), true
+		}
+	default:
+		return s, true
+	}
+	return nil, false
+}
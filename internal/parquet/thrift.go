@@ -0,0 +1,98 @@
+package parquet
+
+import "encoding/binary"
+
+// Thrift compact 协议的字段类型
+const (
+	ctTrue   = 1
+	ctFalse  = 2
+	ctI32    = 5
+	ctI64    = 6
+	ctBinary = 8
+	ctList   = 9
+	ctStruct = 12
+)
+
+// thrift
+// Thrift compact 协议编码，用于页头和文件元数据；结构体须以 begin 或 push 开始、end 结束，字段按序号递增写入
+type thrift struct {
+	buf   []byte
+	last  int16   // 当前结构体中上一个字段的序号
+	stack []int16 // 外层结构体中上一个字段的序号
+}
+
+func (t *thrift) uvarint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func (t *thrift) zigzag(v int64) {
+	t.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thrift) field(id int16, typ byte) {
+	if d := id - t.last; d > 0 && d <= 15 {
+		t.buf = append(t.buf, byte(d)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.zigzag(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thrift) i32(id int16, v int32) {
+	t.field(id, ctI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thrift) i64(id int16, v int64) {
+	t.field(id, ctI64)
+	t.zigzag(v)
+}
+
+func (t *thrift) binary(id int16, b []byte) {
+	t.field(id, ctBinary)
+	t.uvarint(uint64(len(b)))
+	t.buf = append(t.buf, b...)
+}
+
+func (t *thrift) bool(id int16, v bool) {
+	if v {
+		t.field(id, ctTrue)
+	} else {
+		t.field(id, ctFalse)
+	}
+}
+
+// list
+// 列表头，随后依次写入 n 个元素：结构体以 push、end 包围，i32 以 zigzag 写入
+func (t *thrift) list(id int16, elem byte, n int) {
+	t.field(id, ctList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elem)
+	} else {
+		t.buf = append(t.buf, 0xF0|elem)
+		t.uvarint(uint64(n))
+	}
+}
+
+// begin
+// 结构体类型的字段
+func (t *thrift) begin(id int16) {
+	t.field(id, ctStruct)
+	t.push()
+}
+
+// push
+// 开始结构体（顶层结构体或列表元素）
+func (t *thrift) push() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+// end
+// 结束结构体
+func (t *thrift) end() {
+	t.buf = append(t.buf, 0)
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
@@ -0,0 +1,403 @@
+package parquet
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/xuri/excelize/v2"
+)
+
+// 数据页的目标大小（未压缩），列数据攒满后编码为一页
+const pageSize = 1 << 20
+
+// Parquet 文件的首尾标识
+const magic = "PAR1"
+
+// 写入文件元数据的程序名
+const createdBy = "split-xlsx-go"
+
+// Parquet 枚举值
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	pageData = 0
+)
+
+// 压缩算法在 Parquet 中的编号
+var codecs = map[string]int32{
+	"UNCOMPRESSED": 0,
+	"SNAPPY":       1,
+	"GZIP":         2,
+	"ZSTD":         6,
+}
+
+// columnChunk
+// 一列在当前行组中的数据：当前页尚未编码的值，及已编码、压缩的页
+type columnChunk struct {
+	col    column
+	defs   []byte // 当前页各值的定义级别：0 空值，1 非空
+	bools  []bool // 当前页的非空布尔值
+	values []byte // 当前页 PLAIN 编码的非空值（布尔值除外）
+	pages  bytes.Buffer
+
+	numValues    int64
+	nullCount    int64
+	uncompressed int64 // 已编码页的未压缩大小（含页头）
+}
+
+// chunkMeta
+// 已写出的列数据在文件中的位置和大小
+type chunkMeta struct {
+	offset       int64
+	numValues    int64
+	nullCount    int64
+	uncompressed int64
+	compressed   int64
+}
+
+// rowGroupMeta
+// 已写出的行组
+type rowGroupMeta struct {
+	chunks []chunkMeta
+	rows   int64
+}
+
+// fileWriter
+// 逐行写入 Parquet 文件：各列数据在内存中按页编码、压缩，攒满一个行组（按未压缩大小估算）后依次写出各列；
+// 值均为 PLAIN 编码，空值以定义级别（RLE/位打包）表示，结束时写入文件元数据
+type fileWriter struct {
+	file      *os.File
+	buf       *bufio.Writer
+	chunks    []*columnChunk
+	codec     int32
+	zstd      *zstd.Encoder
+	groupSize int64 // 行组大小上限
+	size      int64 // 当前行组已写入的未压缩大小（估算）
+	rows      int64 // 当前行组的行数
+	offset    int64 // 已写入文件的字节数
+	groups    []rowGroupMeta
+}
+
+func newFileWriter(path string, cols []column, opts option.Parquet) (*fileWriter, error) {
+	w := &fileWriter{codec: codecs[opts.Compression], groupSize: int64(opts.RowGroupMB) << 20}
+	if opts.Compression == "ZSTD" {
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		w.zstd = enc
+	}
+	for _, col := range cols {
+		w.chunks = append(w.chunks, &columnChunk{col: col})
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	// 使用 bufio.Writer 减少 syscall
+	w.file, w.buf = file, bufio.NewWriterSize(file, 1<<20)
+	if err = w.write([]byte(magic)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *fileWriter) write(b []byte) error {
+	n, err := w.buf.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+// mismatchError
+// 取值不符合列类型：列类型由采样确定，采样之后的数据行可能出现其他类型的取值，需放宽列类型后重新写入
+type mismatchError struct {
+	col  int // 列序号（从0开始）
+	file int // 数据文件序号（从0开始），由调用方填写
+	row  int // 数据文件中的数据行序号（不含行首，从0开始），由调用方填写
+	msg  string
+}
+
+func (e *mismatchError) Error() string {
+	return e.msg
+}
+
+// Write
+// 按列类型转换并写入数据行，超出列数的值忽略；line 为数据文件中的行号。
+// 取值与列类型不符则返回 *mismatchError，写入器不可继续使用（须放宽列类型后重新写入）
+func (w *fileWriter) Write(row []string, srcFile string, line int) error {
+	for c, chunk := range w.chunks {
+		var v any
+		if c < len(row) {
+			val, ok := convert(row[c], chunk.col.kind)
+			if !ok {
+				name, _ := excelize.ColumnNumberToName(c + 1)
+				return &mismatchError{col: c, msg: fmt.Sprintf("%s：位置 %s%d，值 %s 不符合采样所得的列类型 %s",
+					filepath.Base(srcFile), name, line, row[c], kindNames[chunk.col.kind])}
+			}
+			v = val
+		}
+		w.size += chunk.add(v)
+		if len(chunk.values)+len(chunk.defs) >= pageSize {
+			if err := w.flushPage(chunk); err != nil {
+				return err
+			}
+		}
+	}
+	w.rows++
+	if w.size >= w.groupSize {
+		return w.flushRowGroup()
+	}
+	return nil
+}
+
+// add
+// 追加一个值到当前页，返回估算的未压缩大小
+func (c *columnChunk) add(v any) int64 {
+	if v == nil {
+		c.defs = append(c.defs, 0)
+		c.nullCount++
+		return 1
+	}
+	c.defs = append(c.defs, 1)
+	switch v := v.(type) {
+	case int64:
+		c.values = binary.LittleEndian.AppendUint64(c.values, uint64(v))
+		return 9
+	case float64:
+		c.values = binary.LittleEndian.AppendUint64(c.values, math.Float64bits(v))
+		return 9
+	case string:
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(v)))
+		c.values = append(c.values, v...)
+		return int64(len(v)) + 5
+	case bool:
+		c.bools = append(c.bools, v)
+	}
+	return 1
+}
+
+// flushPage
+// 当前页编码为数据页（定义级别 + 值）并压缩，追加到列数据
+func (w *fileWriter) flushPage(c *columnChunk) error {
+	if len(c.defs) == 0 {
+		return nil
+	}
+	levels := encodeLevels(c.defs)
+	body := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	body = append(body, levels...)
+	if c.col.kind == kindBool {
+		body = append(body, packBools(c.bools)...)
+	} else {
+		body = append(body, c.values...)
+	}
+	data, err := w.compress(body)
+	if err != nil {
+		return err
+	}
+	var h thrift
+	h.push()
+	h.i32(1, pageData)
+	h.i32(2, int32(len(body)))
+	h.i32(3, int32(len(data)))
+	h.begin(5) // DataPageHeader
+	h.i32(1, int32(len(c.defs)))
+	h.i32(2, encodingPlain)
+	h.i32(3, encodingRLE)
+	h.i32(4, encodingRLE)
+	h.end()
+	h.end()
+	c.pages.Write(h.buf)
+	c.pages.Write(data)
+	c.numValues += int64(len(c.defs))
+	c.uncompressed += int64(len(h.buf) + len(body))
+	c.defs, c.bools, c.values = c.defs[:0], c.bools[:0], c.values[:0]
+	return nil
+}
+
+// encodeLevels
+// 定义级别按 RLE/位打包混合编码（位宽 1）：全部相同时为一个 RLE 段，否则整页位打包
+func encodeLevels(defs []byte) []byte {
+	same := true
+	for _, d := range defs {
+		if d != defs[0] {
+			same = false
+			break
+		}
+	}
+	if same {
+		res := binary.AppendUvarint(nil, uint64(len(defs))<<1)
+		return append(res, defs[0])
+	}
+	groups := (len(defs) + 7) / 8
+	res := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	packed := make([]byte, groups)
+	for i, d := range defs {
+		packed[i/8] |= d << (i % 8)
+	}
+	return append(res, packed...)
+}
+
+// packBools
+// 布尔值 PLAIN 编码：每值 1 位，低位在前
+func packBools(bools []bool) []byte {
+	res := make([]byte, (len(bools)+7)/8)
+	for i, b := range bools {
+		if b {
+			res[i/8] |= 1 << (i % 8)
+		}
+	}
+	return res
+}
+
+func (w *fileWriter) compress(body []byte) ([]byte, error) {
+	switch w.codec {
+	case codecs["SNAPPY"]:
+		return s2.EncodeSnappy(nil, body), nil
+	case codecs["GZIP"]:
+		var b bytes.Buffer
+		gw := gzip.NewWriter(&b)
+		if _, err := gw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := gw.Close(); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case codecs["ZSTD"]:
+		return w.zstd.EncodeAll(body, nil), nil
+	}
+	return body, nil
+}
+
+// flushRowGroup
+// 编码各列剩余的值，依次写出各列数据
+func (w *fileWriter) flushRowGroup() error {
+	if w.rows == 0 {
+		return nil
+	}
+	group := rowGroupMeta{rows: w.rows}
+	for _, c := range w.chunks {
+		if err := w.flushPage(c); err != nil {
+			return err
+		}
+		meta := chunkMeta{offset: w.offset, numValues: c.numValues, nullCount: c.nullCount,
+			uncompressed: c.uncompressed, compressed: int64(c.pages.Len())}
+		if err := w.write(c.pages.Bytes()); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, meta)
+		c.pages.Reset()
+		c.numValues, c.nullCount, c.uncompressed = 0, 0, 0
+	}
+	w.groups = append(w.groups, group)
+	w.size, w.rows = 0, 0
+	return nil
+}
+
+// Close
+// 写出剩余的行组及文件元数据
+func (w *fileWriter) Close() error {
+	err := w.flushRowGroup()
+	if err == nil {
+		footer := w.footer()
+		err = w.write(binary.LittleEndian.AppendUint32(footer, uint32(len(footer))))
+	}
+	if err == nil {
+		err = w.write([]byte(magic))
+	}
+	if e := w.buf.Flush(); err == nil {
+		err = e
+	}
+	if e := w.file.Close(); err == nil {
+		err = e
+	}
+	if w.zstd != nil {
+		w.zstd.Close()
+	}
+	return err
+}
+
+// footer
+// 文件元数据（FileMetaData）：结构、行组及各列数据的位置
+func (w *fileWriter) footer() []byte {
+	var t thrift
+	t.push()
+	t.i32(1, 1) // version
+	t.list(2, ctStruct, len(w.chunks)+1)
+	t.push() // 根节点
+	t.binary(4, []byte("schema"))
+	t.i32(5, int32(len(w.chunks)))
+	t.end()
+	for _, c := range w.chunks {
+		t.push()
+		c.col.schema(&t)
+		t.end()
+	}
+	var rows int64
+	for _, g := range w.groups {
+		rows += g.rows
+	}
+	t.i64(3, rows)
+	t.list(4, ctStruct, len(w.groups))
+	for _, g := range w.groups {
+		var total, compressed int64
+		t.push()
+		t.list(1, ctStruct, len(g.chunks))
+		for i, m := range g.chunks {
+			col := w.chunks[i].col
+			total += m.uncompressed
+			compressed += m.compressed
+			t.push() // ColumnChunk
+			t.i64(2, m.offset)
+			t.begin(3) // ColumnMetaData
+			t.i32(1, col.physicalType())
+			t.list(2, ctI32, 2)
+			t.zigzag(encodingPlain)
+			t.zigzag(encodingRLE)
+			t.list(3, ctBinary, 1)
+			t.uvarint(uint64(len(col.name)))
+			t.buf = append(t.buf, col.name...)
+			t.i32(4, w.codec)
+			t.i64(5, m.numValues)
+			t.i64(6, m.uncompressed)
+			t.i64(7, m.compressed)
+			t.i64(9, m.offset)
+			t.begin(12) // Statistics
+			t.i64(3, m.nullCount)
+			t.end()
+			t.end()
+			t.end()
+		}
+		t.i64(2, total)
+		t.i64(3, g.rows)
+		if len(g.chunks) > 0 {
+			t.i64(5, g.chunks[0].offset)
+		}
+		t.i64(6, compressed)
+		t.end()
+	}
+	t.binary(6, []byte(createdBy))
+	t.end()
+	return t.buf
+}
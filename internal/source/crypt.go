@@ -0,0 +1,86 @@
+package source
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/richardlehane/mscfb"
+	"github.com/xuri/excelize/v2"
+)
+
+// IsEncrypted
+// 是否为加密的 xlsx 类工作簿：加密后为复合文档（CFB），含 EncryptedPackage 流
+func IsEncrypted(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(magicCFB))
+	if _, err := io.ReadFull(f, head); err != nil || !bytes.Equal(head, magicCFB) {
+		return false
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	doc, err := mscfb.New(f)
+	if err != nil {
+		return false
+	}
+	for entry, err := doc.Next(); err == nil; entry, err = doc.Next() {
+		if entry.Name == "EncryptedPackage" {
+			return true
+		}
+	}
+	return false
+}
+
+// Unlock
+// 校验密码并在内存中解密加密文件（每个文件仅解密一次），返回 zip 格式的工作簿；解密结果不写入磁盘。
+// 密码错误时解密所得并非有效 zip
+func Unlock(path string, password string) ([]byte, error) {
+	if password == "" {
+		return nil, fmt.Errorf("%s：文件已加密，请提供密码", filepath.Base(path))
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := excelize.Decrypt(raw, &excelize.Options{Password: password})
+	if err != nil {
+		if errors.Is(err, excelize.ErrWorkbookPassword) {
+			return nil, fmt.Errorf("%s：密码错误", filepath.Base(path))
+		}
+		return nil, fmt.Errorf("%s：解密失败：%w", filepath.Base(path), err)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf))); err != nil {
+		return nil, fmt.Errorf("%s：密码错误", filepath.Base(path))
+	}
+	return buf, nil
+}
+
+// OpenPackage
+// 以 zip 打开 xlsx 类工作簿，已解锁的加密文件从内存中解密所得的工作簿读取
+func OpenPackage(path string, opts option.Source) (*zip.Reader, io.Closer, error) {
+	if buf, ok := opts.Unlocked[path]; ok {
+		r, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+		if err != nil {
+			return nil, nil, err
+		}
+		return r, io.NopCloser(nil), nil
+	}
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		if IsEncrypted(path) {
+			return nil, nil, fmt.Errorf("%s：文件已加密，请提供密码", filepath.Base(path))
+		}
+		return nil, nil, err
+	}
+	return &r.Reader, r, nil
+}
@@ -0,0 +1,92 @@
+package source
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeEncrypted
+// 生成以 password 加密的 xlsx
+func writeEncrypted(t *testing.T, rows [][]any, password string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "secret.xlsx")
+	if err := f.SaveAs(path, excelize.Options{Password: password}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUnlock(t *testing.T) {
+	path := writeEncrypted(t, [][]any{{"id", "name"}, {1, "a"}, {2, "b"}}, "secret")
+	if !IsEncrypted(path) {
+		t.Fatal("IsEncrypted = false")
+	}
+	tests := []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{"未提供密码", "", "请提供密码"},
+		{"密码错误", "wrong", "密码错误"},
+		{"密码正确", "secret", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := Unlock(path, tt.password)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := option.Source{Unlocked: map[string][]byte{path: buf}}
+			rows, err := Open(path, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]string
+			for rows.Next() {
+				row, err := rows.Columns()
+				if err != nil {
+					rows.Close()
+					t.Fatal(err)
+				}
+				got = append(got, row)
+			}
+			rows.Close()
+			want := [][]string{{"id", "name"}, {"1", "a"}, {"2", "b"}}
+			if !slices.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("rows = %q, want %q", got, want)
+			}
+			if n, err := Count(path, opts); err != nil || n != 2 {
+				t.Errorf("Count = %d %v, want 2", n, err)
+			}
+		})
+	}
+}
+
+func TestOpenEncryptedLocked(t *testing.T) {
+	path := writeEncrypted(t, [][]any{{"id"}}, "secret")
+	if _, err := Open(path, option.Source{}); err == nil || !strings.Contains(err.Error(), "请提供密码") {
+		t.Errorf("err = %v, want 请提供密码", err)
+	}
+}
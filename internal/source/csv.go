@@ -0,0 +1,192 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// 嗅探编码和分隔符所读取的样本大小
+const sniffSize = 64 << 10 // 64KB
+
+// 候选分隔符
+var commas = []rune{',', ';', '\t', '|'}
+
+// 已记录日志的文件，同一文件多次打开只记录一次
+var logged sync.Map
+
+// Dialect
+// CSV 文件格式
+type Dialect struct {
+	Encoding string // UTF-8、UTF-16LE、UTF-16BE、GB18030、Windows-1252
+	Comma    rune
+	bom      int // BOM 字节数
+	decoder  *encoding.Decoder
+}
+
+func (d Dialect) String() string {
+	comma := string(d.Comma)
+	if d.Comma == '\t' {
+		comma = "\\t"
+	}
+	return d.Encoding + "，分隔符 " + comma
+}
+
+// DetectCSV
+// 根据 BOM、UTF-8 有效性推断编码（无 BOM 且非 UTF-8 则视为 GB18030，兼容 GBK；按 GB18030 解码仍有无效字节则视为 Windows-1252），
+// 再统计样本各行中候选分隔符的出现次数，取各行一致且最多者
+func DetectCSV(path string) (Dialect, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Dialect{}, err
+	}
+	defer f.Close()
+	sample := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, sample)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return Dialect{}, err
+	}
+	sample = sample[:n]
+
+	d := Dialect{Encoding: "UTF-8", Comma: ','}
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		d.bom = 3
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		d.Encoding, d.bom = "UTF-16LE", 2
+		d.decoder = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		d.Encoding, d.bom = "UTF-16BE", 2
+		d.decoder = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
+	}
+	sample = sample[d.bom:]
+	if d.decoder != nil { // UTF-16 保持偶数字节
+		sample = sample[:len(sample)&^1]
+	} else if n == sniffSize { // 样本截断，只取完整行
+		if i := bytes.LastIndexByte(sample, '\n'); i >= 0 {
+			sample = sample[:i+1]
+		}
+	}
+	if d.decoder == nil && !utf8.Valid(sample) {
+		d.Encoding = "GB18030"
+		d.decoder = simplifiedchinese.GB18030.NewDecoder()
+		if decoded, err := d.decoder.Bytes(sample); err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+			d.Encoding = "Windows-1252" // 西文系统导出的 ANSI 文件
+			d.decoder = charmap.Windows1252.NewDecoder()
+		}
+	}
+	text := string(sample)
+	if d.decoder != nil {
+		if decoded, err := d.decoder.Bytes(sample); err == nil {
+			text = string(decoded)
+		}
+	}
+	d.Comma = sniffComma(text)
+	return d, nil
+}
+
+// sniffComma
+// 忽略引号内字符，统计前20行各候选分隔符的出现次数
+func sniffComma(text string) rune {
+	lines := []string{}
+	for line := range strings.SplitSeq(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+		if len(lines) >= 20 {
+			break
+		}
+	}
+	best, bestScore := ',', 0
+	for _, comma := range commas {
+		consistent, total, prev := true, 0, -1
+		for _, line := range lines {
+			count, quoted := 0, false
+			for _, c := range line {
+				if c == '"' {
+					quoted = !quoted
+				} else if c == comma && !quoted {
+					count++
+				}
+			}
+			if prev >= 0 && count != prev {
+				consistent = false
+			}
+			prev = count
+			total += count
+		}
+		score := total
+		if consistent && total > 0 {
+			score += 1 << 30 // 各行一致优先
+		}
+		if score > bestScore {
+			best, bestScore = comma, score
+		}
+	}
+	return best
+}
+
+type csvRows struct {
+	file   *os.File
+	reader *csv.Reader
+	row    []string
+	err    error
+}
+
+func openCSV(path string) (*csvRows, error) {
+	d, err := DetectCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := logged.LoadOrStore(path, true); !ok {
+		log.Printf("%s：CSV 编码 %s", filepath.Base(path), d)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(int64(d.bom), io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	var r io.Reader = bufio.NewReaderSize(f, 1<<20)
+	if d.decoder != nil {
+		r = d.decoder.Reader(r)
+	}
+	reader := csv.NewReader(r)
+	reader.Comma = d.Comma
+	reader.LazyQuotes = true    // 容忍不规范的引号
+	reader.FieldsPerRecord = -1 // 容忍各行列数不一致
+	return &csvRows{file: f, reader: reader}, nil
+}
+
+func (r *csvRows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	r.row, r.err = r.reader.Read()
+	return r.err != io.EOF
+}
+
+func (r *csvRows) Columns() ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.row, nil
+}
+
+func (r *csvRows) Close() error {
+	return r.file.Close()
+}
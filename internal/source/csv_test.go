@@ -0,0 +1,109 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// writeFile
+// 测试用的数据文件
+func writeFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// encode
+// 按指定编码转换测试文本
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDetectCSV(t *testing.T) {
+	text := "编号,名称\n1,\"甲,乙\"\n2,丙\n"
+	want := [][]string{{"编号", "名称"}, {"1", "甲,乙"}, {"2", "丙"}}
+	tests := []struct {
+		name     string
+		content  []byte
+		encoding string
+		comma    rune
+		want     [][]string
+	}{
+		{"UTF-8", []byte(text), "UTF-8", ',', want},
+		{"UTF-8 BOM", append([]byte{0xEF, 0xBB, 0xBF}, text...), "UTF-8", ',', want},
+		{"UTF-16LE", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), text), "UTF-16LE", ',', want},
+		{"UTF-16BE", encode(t, unicode.UTF16(unicode.BigEndian, unicode.UseBOM), text), "UTF-16BE", ',', want},
+		{"GBK", encode(t, simplifiedchinese.GBK, text), "GB18030", ',', want},
+		{"Windows-1252", encode(t, charmap.Windows1252, "name;price\ncafé;1,5\nnaïve;2\n"),
+			"Windows-1252", ';', [][]string{{"name", "price"}, {"café", "1,5"}, {"naïve", "2"}}},
+		{"分号", []byte("a;b\n1;2,5\n3;4\n"), "UTF-8", ';', [][]string{{"a", "b"}, {"1", "2,5"}, {"3", "4"}}},
+		{"制表符", []byte("a\tb\n1\t\"x\ty\"\n"), "UTF-8", '\t', [][]string{{"a", "b"}, {"1", "x\ty"}}},
+		{"竖线", []byte("a|b|c\n1|2|3\n"), "UTF-8", '|', [][]string{{"a", "b", "c"}, {"1", "2", "3"}}},
+		{"列数不一致", []byte("a,b\n1\n2,3,4\n"), "UTF-8", ',', [][]string{{"a", "b"}, {"1"}, {"2", "3", "4"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "data.csv", tt.content)
+			d, err := DetectCSV(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Encoding != tt.encoding || d.Comma != tt.comma {
+				t.Errorf("DetectCSV = %s, want %s %q", d, tt.encoding, tt.comma)
+			}
+			rows, err := Open(path, option.Source{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var got [][]string
+			for rows.Next() {
+				row, err := rows.Columns()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, row)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 0},
+		{"h\n", 0},
+		{"h\n1\n2\n", 2},
+		{"h\n\"a\nb\"\n", 1}, // 引号内换行
+	}
+	for _, tt := range tests {
+		got, err := Count(writeFile(t, "data.csv", []byte(tt.content)), option.Source{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
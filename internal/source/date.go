@@ -0,0 +1,126 @@
+package source
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 日期、时间统一按 ISO 8601 文本读出
+const (
+	LayoutDate     = "2006-01-02"
+	LayoutDateTime = "2006-01-02 15:04:05"
+	LayoutTime     = "15:04:05"
+)
+
+// DateKind
+// 日期值的种类
+type DateKind int
+
+const (
+	DateNone     DateKind = iota // 非日期
+	DateOnly                     // 日期
+	DateWithTime                 // 日期时间
+	TimeOnly                     // 时间
+)
+
+// FormatSerial
+// Excel 日期序列值按 ISO 格式输出：小于1为时间，整数为日期，其余为日期时间
+func FormatSerial(v float64, date1904 bool) (string, bool) {
+	if v < 0 {
+		return "", false
+	}
+	t, err := excelize.ExcelDateToTime(v, date1904)
+	if err != nil {
+		return "", false
+	}
+	t = t.Round(time.Second)
+	switch {
+	case v < 1:
+		return t.Format(LayoutTime), true
+	case v == math.Trunc(v):
+		return t.Format(LayoutDate), true
+	default:
+		return t.Format(LayoutDateTime), true
+	}
+}
+
+// ParseDate
+// 解析 ISO 格式的日期、日期时间或时间文本，非此格式返回 DateNone
+func ParseDate(s string) (time.Time, DateKind) {
+	var (
+		layout string
+		kind   DateKind
+	)
+	switch {
+	case len(s) == len(LayoutDate) && s[4] == '-':
+		layout, kind = LayoutDate, DateOnly
+	case len(s) == len(LayoutDateTime) && s[4] == '-' && (s[10] == ' ' || s[10] == 'T'):
+		layout, kind = LayoutDateTime, DateWithTime
+		if s[10] == 'T' {
+			layout = "2006-01-02T15:04:05"
+		}
+	case len(s) == len(LayoutTime) && s[2] == ':':
+		layout, kind = LayoutTime, TimeOnly
+	default:
+		return time.Time{}, DateNone
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, DateNone
+	}
+	return t, kind
+}
+
+// parseCellDate
+// 解析 t="d" 单元格的 ISO 8601 值（可带小数秒、时区），按种类输出统一格式
+func parseCellDate(s string) (string, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", LayoutDate, "T15:04:05.999999999", LayoutTime} {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(layout, "T") || layout == LayoutTime:
+			return t.Format(LayoutTime), true
+		case t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0:
+			return t.Format(LayoutDate), true
+		default:
+			return t.Format(LayoutDateTime), true
+		}
+	}
+	return s, false
+}
+
+// isDateFormat
+// 内置日期格式，或自定义格式去除引号、方括号、转义字符后含日期时间占位符
+func isDateFormat(id int, format string) bool {
+	if (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58) {
+		return true
+	}
+	if format == "" {
+		return false
+	}
+	var b strings.Builder
+	quoted, bracket := false, false
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[':
+			bracket = true
+		case c == ']':
+			bracket = false
+		case bracket:
+		case c == '\\' || c == '_' || c == '*':
+			i++ // 跳过转义、占位字符
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.ContainsAny(strings.ToLower(b.String()), "ymdhs")
+}
@@ -0,0 +1,170 @@
+package source
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kind
+// 按内容识别的数据文件类型
+type Kind int
+
+const (
+	KindXlsx Kind = iota // Office Open XML 工作簿：xlsx、xlsm、xltx、xltm
+	KindXls              // Excel 97-2003 工作簿
+	KindCSV              // 文本
+)
+
+// 文件头
+var (
+	magicZip = []byte("PK\x03\x04")
+	magicCFB = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+)
+
+// 工作簿主部件的内容类型
+var workbookTypes = []string{
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml",    // xlsx
+	"application/vnd.ms-excel.sheet.macroEnabled.main+xml",                          // xlsm
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.template.main+xml", // xltx
+	"application/vnd.ms-excel.template.macroEnabled.main+xml",                       // xltm
+}
+
+// 识别文件头所读取的字节数
+const headSize = 4 << 10 // 4KB
+
+// Check
+// 检查文件是否存在且可作为数据来源，否则给出原因
+func Check(path string) error {
+	if path == "" {
+		return errors.New("未选择文件")
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return fmt.Errorf("该文件不存在：%s", path)
+	}
+	_, err = Detect(path)
+	return err
+}
+
+// Detect
+// 按内容而非后缀识别文件类型：
+// 1. zip 且 [Content_Types].xml 声明了工作簿为 xlsx 类（含启用宏、模板）
+// 2. 复合文档（CFB）含 EncryptedPackage 为加密的 xlsx 类，否则为 xls
+// 3. 文本且后缀为 .csv 为 CSV；后缀为 Excel 的文本（改了后缀的 CSV、网页导出的“xls”）给出明确提示
+func Detect(path string) (Kind, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	head := make([]byte, headSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, err
+	}
+	head = head[:n]
+	name := filepath.Base(path)
+
+	switch {
+	case bytes.HasPrefix(head, magicZip):
+		return KindXlsx, checkZip(path)
+	case bytes.HasPrefix(head, magicCFB):
+		if IsEncrypted(path) { // 加密的 xlsx 同为复合文档
+			return KindXlsx, nil
+		}
+		return KindXls, nil
+	}
+	if kind := sniffMarkup(head); kind != "" {
+		return 0, fmt.Errorf("%s：实为 %s，并非真正的 Excel 文件，请用 Excel 打开后另存为 xlsx", name, kind)
+	}
+	if isBinary(head) {
+		return 0, fmt.Errorf("%s：无法识别的文件格式，非 Excel、CSV 文件", name)
+	}
+	if IsCSV(path) {
+		return KindCSV, nil
+	}
+	if isExcelExt(path) {
+		return 0, fmt.Errorf("%s：实为文本文件（可能是改了后缀的 CSV），请将后缀改为 .csv", name)
+	}
+	return 0, fmt.Errorf("%s：非 Excel、CSV 文件", name)
+}
+
+// checkZip
+// 检查 zip 是否为 xlsx 类工作簿
+func checkZip(path string) error {
+	name := filepath.Base(path)
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("%s：文件已损坏，无法解压：%w", name, err)
+	}
+	defer z.Close()
+	for _, zf := range z.File {
+		if zf.Name != "[Content_Types].xml" {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("%s：文件已损坏：%w", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, 1<<20))
+		if err != nil {
+			return fmt.Errorf("%s：文件已损坏：%w", name, err)
+		}
+		for _, typ := range workbookTypes {
+			if bytes.Contains(data, []byte(typ)) {
+				return nil
+			}
+		}
+		if bytes.Contains(data, []byte("application/vnd.ms-excel.sheet.binary.macroEnabled.main")) {
+			return fmt.Errorf("%s：暂不支持 Excel 二进制工作簿（xlsb），请用 Excel 另存为 xlsx", name)
+		}
+		return fmt.Errorf("%s：为 Office 文档但非 Excel 工作簿", name)
+	}
+	return fmt.Errorf("%s：为 zip 压缩包，非 Excel 工作簿", name)
+}
+
+// sniffMarkup
+// 识别网页、XML 导出的伪 Excel 文件，返回其类型描述
+func sniffMarkup(head []byte) string {
+	text := bytes.TrimPrefix(head, []byte{0xEF, 0xBB, 0xBF})
+	text = bytes.ToLower(bytes.TrimSpace(text))
+	if !bytes.HasPrefix(text, []byte("<")) {
+		return ""
+	}
+	switch {
+	case bytes.Contains(text, []byte("urn:schemas-microsoft-com:office:spreadsheet")):
+		return "XML 表格（Excel 2003 XML）"
+	case bytes.Contains(text, []byte("<html")), bytes.Contains(text, []byte("<!doctype html")),
+		bytes.Contains(text, []byte("<table")):
+		return "HTML 网页"
+	case bytes.HasPrefix(text, []byte("<?xml")):
+		return "XML 文件"
+	}
+	return ""
+}
+
+// isBinary
+// 样本含 NUL 字节视为二进制（UTF-16 文本有 BOM 时除外）
+func isBinary(head []byte) bool {
+	if bytes.HasPrefix(head, []byte{0xFF, 0xFE}) || bytes.HasPrefix(head, []byte{0xFE, 0xFF}) {
+		return false
+	}
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// isExcelExt
+// 是否为 Excel 文件后缀
+func isExcelExt(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx", ".xlsm", ".xltx", ".xltm", ".xls":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package source
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// zipFile
+// 生成仅含 [Content_Types].xml 的 zip，contentType 为空则不含该部件
+func zipFile(t *testing.T, contentType string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	name := "[Content_Types].xml"
+	if contentType == "" {
+		name = "readme.txt"
+	}
+	w, err := z.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(`<?xml version="1.0"?><Types><Override PartName="/xl/workbook.xml" ContentType="` + contentType + `"/></Types>`))
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content func(t *testing.T) []byte
+		kind    Kind
+		errText string // 错误信息应含的文字，为空则不应出错
+	}{
+		{"xlsx", "a.xlsx", func(t *testing.T) []byte { return zipFile(t, workbookTypes[0]) }, KindXlsx, ""},
+		{"xlsm", "a.xlsm", func(t *testing.T) []byte { return zipFile(t, workbookTypes[1]) }, KindXlsx, ""},
+		{"xltx", "a.xltx", func(t *testing.T) []byte { return zipFile(t, workbookTypes[2]) }, KindXlsx, ""},
+		{"xltm 改后缀", "a.bin", func(t *testing.T) []byte { return zipFile(t, workbookTypes[3]) }, KindXlsx, ""},
+		{"xlsb", "a.xlsb", func(t *testing.T) []byte {
+			return zipFile(t, "application/vnd.ms-excel.sheet.binary.macroEnabled.main")
+		}, 0, "xlsb"},
+		{"docx", "a.xlsx", func(t *testing.T) []byte {
+			return zipFile(t, "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml")
+		}, 0, "非 Excel 工作簿"},
+		{"zip", "a.xlsx", func(t *testing.T) []byte { return zipFile(t, "") }, 0, "zip 压缩包"},
+		{"损坏的 zip", "a.xlsx", func(t *testing.T) []byte { return []byte("PK\x03\x04broken") }, 0, "无法解压"},
+		{"xls", "a.xls", func(t *testing.T) []byte {
+			b, err := os.ReadFile(writeCFB(t, "book.xls", "Workbook", xlsFixture(false)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return b
+		}, KindXls, ""},
+		{"HTML 导出的 xls", "a.xls", func(t *testing.T) []byte {
+			return []byte("\xEF\xBB\xBF<html><body><table><tr><td>1</td></tr></table></body></html>")
+		}, 0, "HTML"},
+		{"XML 表格", "a.xls", func(t *testing.T) []byte {
+			return []byte(`<?xml version="1.0"?><Workbook xmlns="urn:schemas-microsoft-com:office:spreadsheet"></Workbook>`)
+		}, 0, "Excel 2003 XML"},
+		{"改了后缀的 CSV", "a.xlsx", func(t *testing.T) []byte { return []byte("a,b\n1,2\n") }, 0, "改了后缀的 CSV"},
+		{"CSV", "a.csv", func(t *testing.T) []byte { return []byte("a,b\n1,2\n") }, KindCSV, ""},
+		{"UTF-16 CSV", "a.csv", func(t *testing.T) []byte { return []byte{0xFF, 0xFE, 'a', 0, ',', 0, 'b', 0} }, KindCSV, ""},
+		{"二进制", "a.csv", func(t *testing.T) []byte { return []byte{1, 2, 0, 3} }, 0, "无法识别"},
+		{"文本", "a.txt", func(t *testing.T) []byte { return []byte("a,b\n") }, 0, "非 Excel、CSV 文件"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, err := Detect(writeFile(t, tt.file, tt.content(t)))
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("err = %v, want %q", err, tt.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind != tt.kind {
+				t.Errorf("kind = %d, want %d", kind, tt.kind)
+			}
+		})
+	}
+}
+
+func TestDetectEncrypted(t *testing.T) {
+	path := writeCFB(t, "a.xlsx", "EncryptedPackage", []byte("x"))
+	if !IsEncrypted(path) {
+		t.Error("IsEncrypted = false")
+	}
+	if kind, err := Detect(path); err != nil || kind != KindXlsx {
+		t.Errorf("Detect = %d %v", kind, err)
+	}
+	if IsEncrypted(writeCFB(t, "a.xls", "Workbook", nil)) {
+		t.Error("xls IsEncrypted = true")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(""); err == nil {
+		t.Error("空路径应返回错误")
+	}
+	if err := Check(t.TempDir()); err == nil {
+		t.Error("目录应返回错误")
+	}
+	if err := Check(writeFile(t, "a.csv", []byte("a\n"))); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,268 @@
+package source
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/xuri/efp"
+	"github.com/xuri/excelize/v2"
+)
+
+// RefCell
+// 引用的一端，整列引用行号为0，整行引用列号为0
+type RefCell struct {
+	Col, Row       int
+	AbsCol, AbsRow bool
+}
+
+func (c RefCell) String() string {
+	var b strings.Builder
+	if c.Col > 0 {
+		if c.AbsCol {
+			b.WriteByte('$')
+		}
+		name, _ := excelize.ColumnNumberToName(c.Col)
+		b.WriteString(name)
+	}
+	if c.Row > 0 {
+		if c.AbsRow {
+			b.WriteByte('$')
+		}
+		b.WriteString(strconv.Itoa(c.Row))
+	}
+	return b.String()
+}
+
+// Ref
+// 公式中的引用，Name 非空时为名称（定义的名称、表名），其余字段无意义
+type Ref struct {
+	Sheet string // 工作表前缀（原文，不含 !），为空表示本表
+	Name  string
+	From  RefCell
+	To    RefCell
+	Range bool
+}
+
+func (r Ref) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	s := r.From.String()
+	if r.Range {
+		s += ":" + r.To.String()
+	}
+	if r.Sheet != "" {
+		s = r.Sheet + "!" + s
+	}
+	return s
+}
+
+// RewriteFormula
+// 按 efp 切分公式，逐个引用（含名称）调用 fn，按修改后的引用重写公式；fn 返回错误则中止。
+// 多余的空格不保留，字符串常量、工作表名按需重新加引号
+func RewriteFormula(formula string, fn func(ref *Ref) error) (string, error) {
+	ps := efp.ExcelParser()
+	var (
+		b     strings.Builder
+		stack []string // 未结束的函数名，子表达式为空
+	)
+	for _, t := range ps.Parse(formula) {
+		switch {
+		case t.TSubType == efp.TokenSubTypeStart:
+			name := t.TValue
+			if t.TType == efp.TokenTypeSubexpression {
+				name = ""
+			}
+			stack = append(stack, name)
+			switch {
+			case t.TType == efp.TokenTypeSubexpression:
+				b.WriteByte('(')
+			case name == "ARRAY": // 数组常量 {1,2;3,4}
+				b.WriteByte('{')
+			case name == "ARRAYROW":
+			default:
+				if i := strings.LastIndexByte(name, ':'); i >= 0 { // 区域的一端为函数，如 A1:INDEX(B:B,3)
+					ref, err := rewriteRef(name[:i], fn)
+					if err != nil {
+						return "", err
+					}
+					b.WriteString(ref + ":")
+					name = name[i+1:]
+				}
+				b.WriteString(name)
+				b.WriteByte('(')
+			}
+		case t.TSubType == efp.TokenSubTypeStop:
+			name := ""
+			if len(stack) > 0 {
+				name, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+			switch name {
+			case "ARRAY":
+				b.WriteByte('}')
+			case "ARRAYROW":
+			default:
+				b.WriteByte(')')
+			}
+		case t.TType == efp.TokenTypeArgument:
+			if len(stack) > 0 && stack[len(stack)-1] == "ARRAY" { // 数组常量的行分隔
+				b.WriteByte(';')
+			} else {
+				b.WriteByte(',')
+			}
+		case t.TType == efp.TokenTypeOperand && t.TSubType == efp.TokenSubTypeText:
+			b.WriteString(`"` + strings.ReplaceAll(t.TValue, `"`, `""`) + `"`)
+		case t.TType == efp.TokenTypeOperand && t.TSubType == efp.TokenSubTypeRange:
+			ref, err := rewriteRef(t.TValue, fn)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(ref)
+		case t.TSubType == efp.TokenSubTypeIntersection:
+			b.WriteByte(' ')
+		default:
+			b.WriteString(t.TValue)
+		}
+	}
+	return b.String(), nil
+}
+
+// rewriteRef
+// 按 fn 改写一个引用或名称，无法解析为引用的视为名称（定义的名称、结构化引用等）
+func rewriteRef(s string, fn func(ref *Ref) error) (string, error) {
+	at := ""
+	if strings.HasPrefix(s, "@") { // 隐式交集
+		at, s = "@", s[1:]
+	}
+	ref, ok := parseRef(s)
+	if !ok {
+		if !isName(s) {
+			return at + s, nil
+		}
+		ref = Ref{Name: s}
+	}
+	if err := fn(&ref); err != nil {
+		return "", err
+	}
+	return at + ref.String(), nil
+}
+
+// ShiftFormula
+// 相对引用按行、列偏移（共享公式展开），偏移后越界的引用写为 #REF!
+func ShiftFormula(formula string, rows int, cols int) string {
+	if rows == 0 && cols == 0 {
+		return formula
+	}
+	res, _ := RewriteFormula(formula, func(ref *Ref) error {
+		if ref.Name != "" {
+			return nil
+		}
+		for _, c := range []*RefCell{&ref.From, &ref.To} {
+			if c.Col > 0 && !c.AbsCol {
+				if c.Col += cols; c.Col < 1 || c.Col > excelize.MaxColumns {
+					*ref = Ref{Name: "#REF!"}
+					return nil
+				}
+			}
+			if c.Row > 0 && !c.AbsRow {
+				if c.Row += rows; c.Row < 1 || c.Row > excelize.TotalRows {
+					*ref = Ref{Name: "#REF!"}
+					return nil
+				}
+			}
+		}
+		return nil
+	})
+	return res
+}
+
+// parseRef
+// 解析引用：可带工作表前缀，单元格（区域）、整列或整行；单独的列名、行号不是引用
+func parseRef(s string) (Ref, bool) {
+	var ref Ref
+	if i := strings.LastIndexByte(s, '!'); i >= 0 {
+		ref.Sheet, s = quoteSheet(s[:i]), s[i+1:]
+	}
+	from, to, isRange := strings.Cut(s, ":")
+	var ok bool
+	if ref.From, ok = parseRefCell(from); !ok {
+		return Ref{}, false
+	}
+	if !isRange {
+		return ref, ref.From.Col > 0 && ref.From.Row > 0
+	}
+	if ref.To, ok = parseRefCell(to); !ok {
+		return Ref{}, false
+	}
+	// 两端须同为单元格、整列或整行
+	if (ref.From.Col == 0) != (ref.To.Col == 0) || (ref.From.Row == 0) != (ref.To.Row == 0) {
+		return Ref{}, false
+	}
+	ref.Range = true
+	return ref, true
+}
+
+// parseRefCell
+// 解析引用的一端：列名、行号（可带 $），可缺其一，超出 Excel 范围则不是引用
+func parseRefCell(s string) (RefCell, bool) {
+	var c RefCell
+	if len(s) > 1 && s[0] == '$' && isLetter(s[1]) {
+		c.AbsCol, s = true, s[1:]
+	}
+	n := 0
+	for n < len(s) && isLetter(s[n]) {
+		n++
+	}
+	if n > 3 {
+		return c, false
+	}
+	if n > 0 {
+		col, err := excelize.ColumnNameToNumber(s[:n])
+		if err != nil {
+			return c, false
+		}
+		c.Col, s = col, s[n:]
+	}
+	if s == "" {
+		return c, c.Col > 0
+	}
+	if s[0] == '$' {
+		c.AbsRow, s = true, s[1:]
+	}
+	if s == "" || strings.Trim(s, "0123456789") != "" {
+		return c, false
+	}
+	row, err := strconv.Atoi(s)
+	if err != nil || row < 1 || row > excelize.TotalRows {
+		return c, false
+	}
+	c.Row = row
+	return c, true
+}
+
+// quoteSheet
+// 工作表前缀（efp 已去掉引号）含空格等字符或以数字开头时加单引号，内部单引号加倍
+func quoteSheet(name string) string {
+	plain := name != "" && !(name[0] >= '0' && name[0] <= '9')
+	for i := 0; i < len(name) && plain; i++ {
+		c := name[i]
+		plain = c == '_' || c == '.' || c >= '0' && c <= '9' || isLetter(c) || c >= 0x80
+	}
+	if plain {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// isName
+// 以字母、下划线、反斜杠开头且非逻辑常量的单词视为名称，数字常量除外
+func isName(word string) bool {
+	if word == "" || word[0] >= '0' && word[0] <= '9' || word[0] == '.' {
+		return false
+	}
+	return !strings.EqualFold(word, "TRUE") && !strings.EqualFold(word, "FALSE")
+}
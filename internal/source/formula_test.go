@@ -0,0 +1,81 @@
+package source
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRewriteFormula(t *testing.T) {
+	tests := []struct {
+		name    string
+		formula string
+		want    string   // 引用原样写回的结果
+		refs    []string // 依次识别出的引用、名称
+	}{
+		{"单元格与区域", "SUM(A1:B2)+$C$3", "SUM(A1:B2)+$C$3", []string{"A1:B2", "$C$3"}},
+		{"整列整行", "SUM(A:A,2:3)", "SUM(A:A,2:3)", []string{"A:A", "2:3"}},
+		{"字符串常量", `IF(A1="a""b",A1&"B2",)`, `IF(A1="a""b",A1&"B2",)`, []string{"A1", "A1"}},
+		{"数组常量", "SUM({1,2;3,4})*A1", "SUM({1,2;3,4})*A1", []string{"A1"}},
+		{"带空格的工作表", "'My Sheet'!A1+Sheet2!$B$2", "'My Sheet'!A1+Sheet2!$B$2", []string{"'My Sheet'!A1", "Sheet2!$B$2"}},
+		{"工作表名含单引号", "'It''s'!A1", "'It''s'!A1", []string{"'It''s'!A1"}},
+		{"名称与函数", "Rate*A2+LOG10(A3)", "Rate*A2+LOG10(A3)", []string{"Rate", "A2", "A3"}},
+		{"错误值与百分比", "IFERROR(A2,#N/A)+5%-1E+3", "IFERROR(A2,#N/A)+5%-1E+3", []string{"A2"}},
+		{"交集与多余空格", "SUM( A1:B2  B1:C3 )", "SUM(A1:B2 B1:C3)", []string{"A1:B2", "B1:C3"}},
+		{"区域一端为函数", "SUM(A1:INDEX(B:B,3))", "SUM(A1:INDEX(B:B,3))", []string{"A1", "B:B"}},
+		{"隐式交集", "@A1*2", "@A1*2", []string{"A1"}},
+		{"逻辑常量", "IF(TRUE,A1,FALSE)", "IF(TRUE,A1,FALSE)", []string{"A1"}},
+		{"超出范围的列不是引用", "ABCD1+A1", "ABCD1+A1", []string{"ABCD1", "A1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var refs []string
+			got, err := RewriteFormula(tt.formula, func(ref *Ref) error {
+				refs = append(refs, ref.String())
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(refs, tt.refs) {
+				t.Errorf("refs = %q, want %q", refs, tt.refs)
+			}
+		})
+	}
+}
+
+func TestRewriteFormulaError(t *testing.T) {
+	errStop := errors.New("stop")
+	_, err := RewriteFormula("A1+Sheet2!B1", func(ref *Ref) error {
+		if ref.Sheet != "" {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("err = %v, want %v", err, errStop)
+	}
+}
+
+func TestShiftFormula(t *testing.T) {
+	tests := []struct {
+		formula    string
+		rows, cols int
+		want       string
+	}{
+		{"B4*2+$C$1", 1, 0, "B5*2+$C$1"},
+		{"SUM(A$1:A3)", 2, 1, "SUM(B$1:B5)"},
+		{"A:A+1:1", 1, 1, "B:B+2:2"},
+		{"'My Sheet'!A1&\"A1\"", 1, 0, "'My Sheet'!A2&\"A1\""},
+		{"A2-A1", -1, 0, "A1-#REF!"},
+		{"Rate*A1", 0, 0, "Rate*A1"},
+	}
+	for _, tt := range tests {
+		if got := ShiftFormula(tt.formula, tt.rows, tt.cols); got != tt.want {
+			t.Errorf("ShiftFormula(%q, %d, %d) = %q, want %q", tt.formula, tt.rows, tt.cols, got, tt.want)
+		}
+	}
+}
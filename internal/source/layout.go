@@ -0,0 +1,164 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Layout
+// 工作表版式：列宽、冻结窗格及自动筛选
+type Layout struct {
+	Cols      []ColWidth
+	FreezeCol int // 冻结的列数
+	FreezeRow int // 冻结的行数
+	FilterMin int // 自动筛选的起止列，0 表示无筛选
+	FilterMax int
+}
+
+// ColWidth
+// 连续若干列的列宽
+type ColWidth struct {
+	Min, Max int
+	Width    float64
+}
+
+// Layout
+// 读取第一张工作表的版式
+func (wb *Workbook) Layout() (*Layout, error) {
+	layout := &Layout{}
+	err := wb.scanSheet(func(se xml.StartElement) {
+		switch se.Name.Local {
+		case "pane":
+			if attr(se, "state") == "frozen" || attr(se, "state") == "frozenSplit" {
+				layout.FreezeCol, _ = strconv.Atoi(attr(se, "xSplit"))
+				layout.FreezeRow, _ = strconv.Atoi(attr(se, "ySplit"))
+			}
+		case "col":
+			col := ColWidth{}
+			col.Min, _ = strconv.Atoi(attr(se, "min"))
+			col.Max, _ = strconv.Atoi(attr(se, "max"))
+			width, err := strconv.ParseFloat(attr(se, "width"), 64)
+			if err == nil && col.Min > 0 && col.Max >= col.Min {
+				col.Width = width
+				layout.Cols = append(layout.Cols, col)
+			}
+		}
+	}, func(se xml.StartElement) {
+		if se.Name.Local == "autoFilter" {
+			if from, to, ok := splitRange(attr(se, "ref")); ok {
+				layout.FilterMin, layout.FilterMax = from, to
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// scanSheet
+// 逐个回调第一张工作表数据部分之前（head）、之后（tail）的元素；跳过数据部分逐字节查找，不解析单元格
+func (wb *Workbook) scanSheet(head func(se xml.StartElement), tail func(se xml.StartElement)) error {
+	r, err := wb.Open(wb.Sheet)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	br := bufio.NewReaderSize(r, 1<<16)
+	decoder := xml.NewDecoder(br) // br 实现 io.ByteReader，解析器不会预读
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if se.Name.Local == "sheetData" {
+			break
+		}
+		if head != nil {
+			head(se)
+		}
+	}
+	if err := skipSheetData(br); err != nil {
+		if err == io.EOF { // 无数据（<sheetData/>）或文件不完整
+			return nil
+		}
+		return err
+	}
+	decoder = xml.NewDecoder(br)
+	for {
+		tok, err := decoder.Token()
+		if err != nil { // 含末尾 </worksheet> 无对应开始标签的错误
+			return nil
+		}
+		if se, ok := tok.(xml.StartElement); ok && tail != nil {
+			tail(se)
+		}
+	}
+}
+
+// skipSheetData
+// 跳至 </sheetData> 之后；单元格文本中的 < 均已转义，</ 只出现在结束标签中
+func skipSheetData(br *bufio.Reader) error {
+	var carry []byte // 缓冲区满时截断的片段末尾
+	for {
+		line, err := br.ReadSlice('>')
+		if err == bufio.ErrBufferFull {
+			carry = append(carry[:0], line[max(len(line)-32, 0):]...)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(carry) > 0 {
+			line = append(carry, line...)
+			carry = nil
+		}
+		if !bytes.HasSuffix(line, []byte("sheetData>")) {
+			continue
+		}
+		tag := line[:len(line)-len("sheetData>")]
+		if i := bytes.LastIndex(tag, []byte("</")); i >= 0 && !bytes.ContainsAny(tag[i:], " >") {
+			return nil
+		}
+	}
+}
+
+// splitRange
+// 区域（如 A1:E100）的起止列号
+func splitRange(ref string) (int, int, bool) {
+	from, to, found := strings.Cut(strings.ReplaceAll(ref, "$", ""), ":")
+	if !found {
+		to = from
+	}
+	a, _, err := excelize.CellNameToCoordinates(from)
+	if err != nil {
+		return 0, 0, false
+	}
+	b, _, err := excelize.CellNameToCoordinates(to)
+	if err != nil {
+		return 0, 0, false
+	}
+	return min(a, b), max(a, b), true
+}
+
+func attr(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
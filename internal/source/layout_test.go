@@ -0,0 +1,128 @@
+package source
+
+import (
+	"bufio"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(f *excelize.File) error
+		want  Layout
+	}{
+		{"无版式", func(f *excelize.File) error { return nil }, Layout{}},
+		{"列宽", func(f *excelize.File) error {
+			if err := f.SetColWidth("Sheet1", "A", "B", 20); err != nil {
+				return err
+			}
+			return f.SetColWidth("Sheet1", "D", "D", 8.5)
+		}, Layout{Cols: []ColWidth{{1, 2, 20}, {4, 4, 8.5}}}},
+		{"冻结行首", func(f *excelize.File) error {
+			return f.SetPanes("Sheet1", &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+		}, Layout{FreezeRow: 1}},
+		{"冻结行列", func(f *excelize.File) error {
+			return f.SetPanes("Sheet1", &excelize.Panes{Freeze: true, XSplit: 2, YSplit: 1, TopLeftCell: "C2", ActivePane: "bottomRight"})
+		}, Layout{FreezeCol: 2, FreezeRow: 1}},
+		{"拆分而非冻结", func(f *excelize.File) error {
+			return f.SetPanes("Sheet1", &excelize.Panes{XSplit: 2000, YSplit: 1000, TopLeftCell: "C2", ActivePane: "bottomRight"})
+		}, Layout{}},
+		{"自动筛选", func(f *excelize.File) error {
+			return f.AutoFilter("Sheet1", "B1:C3", nil)
+		}, Layout{FilterMin: 2, FilterMax: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := excelize.NewFile()
+			defer f.Close()
+			rows := [][]any{{"a", "b", "c", "d"}, {1, 2, 3, 4}, {5, 6, 7, 8}}
+			for i, row := range rows {
+				axis, _ := excelize.CoordinatesToCellName(1, i+1)
+				if err := f.SetSheetRow("Sheet1", axis, &row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tt.setup(f); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "book.xlsx")
+			if err := f.SaveAs(path); err != nil {
+				t.Fatal(err)
+			}
+			wb, err := OpenWorkbook(path, option.Source{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer wb.Close()
+			got, err := wb.Layout()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Layout = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipSheetData(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	tests := []struct {
+		name string
+		data string
+		rest string // 跳过后剩余的内容
+		ok   bool
+	}{
+		{"正常", `<row r="1"><c><v>1</v></c></row></sheetData><autoFilter ref="A1"/>`, `<autoFilter ref="A1"/>`, true},
+		{"带前缀", `</x:sheetData><x:autoFilter/>`, `<x:autoFilter/>`, true},
+		{"跨越缓冲区", `<c><v>` + long + `</v></c></sheetData>` + "<tail/>", "<tail/>", true},
+		{"相似标签", `<mySheetData x="1"></mySheetData>`, "", false},
+		{"无结束标签", `<row r="1"/>`, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			br := bufio.NewReaderSize(strings.NewReader(tt.data), 16)
+			err := skipSheetData(br)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok %v", err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			var rest strings.Builder
+			if _, err := br.WriteTo(&rest); err != nil {
+				t.Fatal(err)
+			}
+			if rest.String() != tt.rest {
+				t.Errorf("rest = %q, want %q", rest.String(), tt.rest)
+			}
+		})
+	}
+}
+
+func TestSplitRange(t *testing.T) {
+	tests := []struct {
+		ref      string
+		from, to int
+		ok       bool
+	}{
+		{"A1:E100", 1, 5, true},
+		{"$B$1:$C$9", 2, 3, true},
+		{"D1", 4, 4, true},
+		{"E1:A3", 1, 5, true},
+		{"", 0, 0, false},
+		{"1:3", 0, 0, false},
+	}
+	for _, tt := range tests {
+		from, to, ok := splitRange(tt.ref)
+		if from != tt.from || to != tt.to || ok != tt.ok {
+			t.Errorf("splitRange(%q) = %d, %d, %v; want %d, %d, %v", tt.ref, from, to, ok, tt.from, tt.to, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package source
+
+import (
+	"encoding/xml"
+	"slices"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// CellRange
+// 单元格区域（合并单元格），行列号从1开始
+type CellRange struct {
+	Left, Top, Right, Bottom int
+}
+
+// MergedCells
+// 第一张工作表的合并单元格区域，按首行排序
+func (wb *Workbook) MergedCells() ([]CellRange, error) {
+	var ranges []CellRange
+	err := wb.scanSheet(nil, func(se xml.StartElement) {
+		if se.Name.Local != "mergeCell" {
+			return
+		}
+		from, to, found := strings.Cut(attr(se, "ref"), ":")
+		if !found {
+			return
+		}
+		left, top, err := excelize.CellNameToCoordinates(from)
+		if err != nil {
+			return
+		}
+		right, bottom, err := excelize.CellNameToCoordinates(to)
+		if err != nil {
+			return
+		}
+		ranges = append(ranges, CellRange{min(left, right), min(top, bottom), max(left, right), max(top, bottom)})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(ranges, func(a, b CellRange) int { return a.Top - b.Top })
+	return ranges, nil
+}
+
+// mergedFill
+// 当前行所在的合并单元格区域及其左上角的值
+type mergedFill struct {
+	rng   CellRange
+	value string
+}
+
+// fillMerged
+// 拆分合并单元格：当前行落在区域内的单元格填充左上角的值
+func (r *xlsxRows) fillMerged() {
+	for r.mergedIdx < len(r.merged) && r.merged[r.mergedIdx].Top <= r.num {
+		rng := r.merged[r.mergedIdx]
+		r.mergedIdx++
+		value := ""
+		if rng.Top == r.num && rng.Left <= len(r.row.values) {
+			value = r.row.values[rng.Left-1]
+		}
+		r.filling = append(r.filling, mergedFill{rng, value})
+	}
+	filling := r.filling[:0]
+	for _, m := range r.filling {
+		if m.rng.Bottom < r.num {
+			continue
+		}
+		filling = append(filling, m)
+		if m.value == "" {
+			continue
+		}
+		for len(r.row.values) < m.rng.Right {
+			r.row.values = append(r.row.values, "")
+		}
+		for c := m.rng.Left; c <= m.rng.Right; c++ {
+			r.row.values[c-1] = m.value
+		}
+	}
+	r.filling = filling
+}
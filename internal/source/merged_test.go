@@ -0,0 +1,108 @@
+package source
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeMerged
+// 含合并单元格的 xlsx：A2:A3 纵向、B4:C4 横向、C2:C2 单格（不影响）、D5:D6 左上角为空
+func writeMerged(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	rows := [][]any{{"a", "b", "c", "d"}, {"x", 1, 2}, {nil, 3}, {"y", 4}, {"z", 5, 6}, {"w"}}
+	for i, row := range rows {
+		axis, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", axis, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rng := range [][2]string{{"B4", "C4"}, {"A2", "A3"}, {"D5", "D6"}} {
+		if err := f.MergeCell("Sheet1", rng[0], rng[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "merged.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMergedCells(t *testing.T) {
+	wb, err := OpenWorkbook(writeMerged(t), option.Source{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+	got, err := wb.MergedCells()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CellRange{{1, 2, 1, 3}, {2, 4, 3, 4}, {4, 5, 4, 6}} // 按首行排序
+	if !slices.Equal(got, want) {
+		t.Errorf("MergedCells = %v, want %v", got, want)
+	}
+}
+
+func TestFillMerged(t *testing.T) {
+	path := writeMerged(t)
+	tests := []struct {
+		name string
+		opts option.Source
+		want [][]string
+	}{
+		{"不填充", option.Source{}, [][]string{
+			{"a", "b", "c", "d"}, {"x", "1", "2"}, {"", "3"}, {"y", "4"}, {"z", "5", "6"}, {"w"},
+		}},
+		{"填充", option.Source{FillMerged: true}, [][]string{
+			{"a", "b", "c", "d"}, {"x", "1", "2"}, {"x", "3"}, {"y", "4", "4"}, {"z", "5", "6"}, {"w"},
+		}},
+	}
+	// 同一文件交替按不同方式读取，互不影响
+	for range 2 {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rows, err := Open(path, tt.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer rows.Close()
+				var got [][]string
+				for rows.Next() {
+					row, err := rows.Columns()
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, row)
+				}
+				if !slices.EqualFunc(got, tt.want, slices.Equal) {
+					t.Errorf("rows = %q\nwant %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestIsXls(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"xls", writeCFB(t, "a.xls", "Workbook", xlsFixture(false)), true},
+		{"xlsx", writeMerged(t), false},
+		{"csv", writeFile(t, "a.csv", []byte("a,b\n1,2\n")), false},
+		{"不存在", filepath.Join(t.TempDir(), "none.xls"), false},
+	}
+	for _, tt := range tests {
+		if got := IsXls(tt.path); got != tt.want {
+			t.Errorf("%s：IsXls = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
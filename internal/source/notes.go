@@ -0,0 +1,171 @@
+package source
+
+import (
+	"encoding/xml"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Hyperlink
+// 单元格超链接，Target 为外部地址，Location 为工作簿内位置（如 Sheet2!A1）
+type Hyperlink struct {
+	Col, Row int
+	Target   string
+	Location string
+	Display  string
+	Tooltip  string
+}
+
+// Comment
+// 单元格批注（注释），按文本段保留加粗
+type Comment struct {
+	Col, Row int
+	Author   string
+	Runs     []CommentRun
+}
+
+// CommentRun
+// 批注的一段文本
+type CommentRun struct {
+	Text string
+	Bold bool
+}
+
+// Notes
+// 第一张工作表的超链接及批注，按行号排序
+type Notes struct {
+	Links    []Hyperlink
+	Comments []Comment
+}
+
+// Notes
+// 读取第一张工作表的超链接及批注
+func (wb *Workbook) Notes() (*Notes, error) {
+	rels, err := wb.readRels(wb.Sheet)
+	if err != nil {
+		return nil, err
+	}
+	notes := &Notes{}
+	if notes.Links, err = wb.readLinks(rels); err != nil {
+		return nil, err
+	}
+	for _, rel := range rels {
+		if strings.HasSuffix(rel.typ, relComments) && !rel.external && wb.has(rel.target) {
+			if notes.Comments, err = wb.readComments(rel.target); err != nil {
+				return nil, err
+			}
+		}
+	}
+	slices.SortStableFunc(notes.Links, func(a, b Hyperlink) int { return a.Row - b.Row })
+	slices.SortStableFunc(notes.Comments, func(a, b Comment) int { return a.Row - b.Row })
+	return notes, nil
+}
+
+// readLinks
+// 读取 <hyperlinks>，区域超链接只保留左上角单元格
+func (wb *Workbook) readLinks(rels []relationship) ([]Hyperlink, error) {
+	var links []Hyperlink
+	err := wb.scanSheet(nil, func(se xml.StartElement) {
+		if se.Name.Local != "hyperlink" {
+			return
+		}
+		ref, _, _ := strings.Cut(attr(se, "ref"), ":")
+		col, row, err := excelize.CellNameToCoordinates(ref)
+		if err != nil {
+			return
+		}
+		link := Hyperlink{
+			Col:      col,
+			Row:      row,
+			Location: attr(se, "location"),
+			Display:  attr(se, "display"),
+			Tooltip:  attr(se, "tooltip"),
+		}
+		if id := attr(se, "id"); id != "" { // r:id
+			for _, rel := range rels {
+				if rel.id == id && strings.HasSuffix(rel.typ, relHyperlink) {
+					link.Target = rel.target
+				}
+			}
+		}
+		if link.Target != "" || link.Location != "" {
+			links = append(links, link)
+		}
+	})
+	return links, err
+}
+
+// readComments
+// 读取批注部件 commentsN.xml
+func (wb *Workbook) readComments(part string) ([]Comment, error) {
+	r, err := wb.Open(part)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var (
+		authors  []string
+		comments []Comment
+		cur      *Comment
+		bold     bool
+	)
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return comments, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch se := tok.(type) {
+		case xml.StartElement:
+			switch se.Name.Local {
+			case "author":
+				text, err := readText(decoder)
+				if err != nil {
+					return nil, err
+				}
+				authors = append(authors, text)
+			case "comment":
+				col, row, err := excelize.CellNameToCoordinates(attr(se, "ref"))
+				if err != nil {
+					cur = nil
+					continue
+				}
+				comments = append(comments, Comment{Col: col, Row: row})
+				cur = &comments[len(comments)-1]
+				if id, err := strconv.Atoi(attr(se, "authorId")); err == nil && id >= 0 && id < len(authors) {
+					cur.Author = authors[id]
+				}
+			case "r":
+				bold = false
+			case "b":
+				bold = attr(se, "val") != "0" && attr(se, "val") != "false"
+			case "t":
+				text, err := readText(decoder)
+				if err != nil {
+					return nil, err
+				}
+				if cur != nil {
+					cur.Runs = append(cur.Runs, CommentRun{Text: text, Bold: bold})
+				}
+			case "rPh": // 拼音提示不属于批注文本
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			switch se.Name.Local {
+			case "r":
+				bold = false
+			case "comment":
+				cur = nil
+			}
+		}
+	}
+}
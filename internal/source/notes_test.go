@@ -0,0 +1,57 @@
+package source
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestNotes(t *testing.T) {
+	f := excelize.NewFile()
+	tip, display := "提示", "工单"
+	if err := f.SetCellHyperLink("Sheet1", "B3", "https://t/3", "External", excelize.HyperlinkOpts{Tooltip: &tip, Display: &display}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetCellHyperLink("Sheet1", "A2", "Sheet1!C1", "Location"); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []excelize.Comment{
+		{Cell: "C4", Author: "b", Paragraph: []excelize.RichTextRun{{Text: "b:", Font: &excelize.Font{Bold: true}}, {Text: "后"}}},
+		{Cell: "A1", Author: "a", Paragraph: []excelize.RichTextRun{{Text: "前"}}},
+	} {
+		if err := f.AddComment("Sheet1", c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "book.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	wb, err := OpenWorkbook(path, option.Source{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+	notes, err := wb.Notes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Notes{
+		Links: []Hyperlink{ // 按行号排序
+			{Col: 1, Row: 2, Location: "Sheet1!C1"},
+			{Col: 2, Row: 3, Target: "https://t/3", Display: "工单", Tooltip: "提示"},
+		},
+		Comments: []Comment{
+			{Col: 1, Row: 1, Author: "a", Runs: []CommentRun{{Text: "前"}}},
+			{Col: 3, Row: 4, Author: "b", Runs: []CommentRun{{Text: "b:", Bold: true}, {Text: "后"}}},
+		},
+	}
+	if !reflect.DeepEqual(notes, want) {
+		t.Errorf("Notes = %+v\nwant %+v", notes, want)
+	}
+}
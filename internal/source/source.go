@@ -0,0 +1,87 @@
+package source
+
+import (
+	"path/filepath"
+	"strings"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+)
+
+// Rows
+// 数据文件行读取：按行流式读取，Excel 只读第一张表，首行为行首
+type Rows interface {
+	Next() bool
+	Columns() ([]string, error)
+	Close() error
+}
+
+// FormulaRows
+// 可读取公式的数据文件（xlsx 类）
+type FormulaRows interface {
+	Rows
+	Formulas() []string
+}
+
+// StyledRows
+// 可读取单元格样式的数据文件（xlsx 类）
+type StyledRows interface {
+	Rows
+	Styles() []int
+}
+
+// IsCSV
+// 按后缀判断是否为 CSV 文件
+func IsCSV(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".csv"
+}
+
+// Open
+// 按文件内容识别类型并打开数据文件，已解锁的加密文件直接读取其解密所得的工作簿
+func Open(path string, opts option.Source) (Rows, error) {
+	if _, ok := opts.Unlocked[path]; ok {
+		return openXlsx(path, opts)
+	}
+	kind, err := Detect(path)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case KindCSV:
+		return openCSV(path)
+	case KindXls:
+		return openXls(path)
+	}
+	return openXlsx(path, opts)
+}
+
+// HasCellMeta
+// 能否直接读取单元格样式和类型（xlsx 类），否则只能按数据推断
+func HasCellMeta(path string) bool {
+	kind, err := Detect(path)
+	return err == nil && kind == KindXlsx
+}
+
+// IsXls
+// 按文件内容判断是否为 xls（BIFF8）文件
+func IsXls(path string) bool {
+	kind, err := Detect(path)
+	return err == nil && kind == KindXls
+}
+
+// Count
+// 统计数据行数（不含行首）
+func Count(path string, opts option.Source) (int, error) {
+	rows, err := Open(path, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if count > 0 { // 减去行首
+		count--
+	}
+	return count, nil
+}
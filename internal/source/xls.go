@@ -0,0 +1,533 @@
+package source
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// BIFF8 记录类型
+const (
+	recFormula    = 0x0006
+	recEOF        = 0x000A
+	recDateMode   = 0x0022
+	recFilePass   = 0x002F
+	recContinue   = 0x003C
+	recBoundSheet = 0x0085
+	recMulRK      = 0x00BD
+	recXF         = 0x00E0
+	recSST        = 0x00FC
+	recLabelSST   = 0x00FD
+	recNumber     = 0x0203
+	recLabel      = 0x0204
+	recBoolErr    = 0x0205
+	recString     = 0x0207
+	recRK         = 0x027E
+	recFormat     = 0x041E
+	recBOF        = 0x0809
+)
+
+// 错误值
+var xlsErrors = map[byte]string{
+	0x00: "#NULL!",
+	0x07: "#DIV/0!",
+	0x0F: "#VALUE!",
+	0x17: "#REF!",
+	0x1D: "#NAME?",
+	0x24: "#NUM!",
+	0x2A: "#N/A",
+}
+
+// recordReader
+// 顺序读取 BIFF 记录，支持回退一条记录
+type recordReader struct {
+	r      *bufio.Reader
+	typ    uint16
+	data   []byte
+	unread bool
+}
+
+func (rr *recordReader) next() (uint16, []byte, error) {
+	if rr.unread {
+		rr.unread = false
+		return rr.typ, rr.data, nil
+	}
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(rr.r, head); err != nil {
+		return 0, nil, err
+	}
+	rr.typ = binary.LittleEndian.Uint16(head)
+	rr.data = make([]byte, binary.LittleEndian.Uint16(head[2:]))
+	if _, err := io.ReadFull(rr.r, rr.data); err != nil {
+		return 0, nil, err
+	}
+	return rr.typ, rr.data, nil
+}
+
+func (rr *recordReader) back() {
+	rr.unread = true
+}
+
+// xlsRows
+// 流式读取 Excel 97-2003（BIFF8）工作簿第一张工作表：先解析全局信息（共享字符串、日期格式、1904 纪元），
+// 再定位至工作表逐条读取单元格记录，单元格记录按行有序，读到下一行的记录即输出当前行
+type xlsRows struct {
+	file     *os.File
+	stream   *mscfb.File
+	rr       *recordReader
+	sst      []string
+	xfDate   []bool // 各 XF 是否为日期格式
+	date1904 bool
+
+	line    int      // 已输出行数
+	next    []string // 已读取的下一有数据行
+	nextIdx int      // 下一有数据行的行号（从0开始），-1 表示工作表已读完
+	cur     []string // 当前输出行
+	build   []string // 读取中的行
+	strCell int      // 公式字符串结果待写入的列，-1 表示无
+	ended   bool     // 已读到工作表的 EOF，其后可能为下一工作表或数据流的填充字节
+	err     error
+	done    bool
+}
+
+func openXls(path string) (*xlsRows, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := mscfb.New(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("非 Excel 97-2003 文件：%w", err)
+	}
+	var stream *mscfb.File
+	for _, entry := range doc.File {
+		if entry.Name == "Workbook" {
+			stream = entry
+			break
+		}
+		if entry.Name == "Book" {
+			f.Close()
+			return nil, errors.New("暂不支持 Excel 5.0/95 格式，请另存为 xlsx")
+		}
+	}
+	if stream == nil {
+		f.Close()
+		return nil, errors.New("未找到工作簿数据流")
+	}
+	x := &xlsRows{
+		file:    f,
+		stream:  stream,
+		rr:      &recordReader{r: bufio.NewReaderSize(stream, 1<<20)},
+		strCell: -1,
+	}
+	offset, err := x.readGlobals()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := stream.Seek(int64(offset), io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	x.rr = &recordReader{r: bufio.NewReaderSize(stream, 1<<20)}
+	typ, data, err := x.rr.next()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if typ != recBOF || len(data) < 4 || binary.LittleEndian.Uint16(data[2:]) != 0x0010 {
+		f.Close()
+		return nil, errors.New("工作表数据有误")
+	}
+	x.nextIdx, x.next, x.err = x.readRow()
+	return x, nil
+}
+
+// readGlobals
+// 解析全局信息，返回第一张工作表在数据流中的位置
+func (x *xlsRows) readGlobals() (uint32, error) {
+	typ, data, err := x.rr.next()
+	if err != nil {
+		return 0, err
+	}
+	if typ != recBOF || len(data) < 4 || binary.LittleEndian.Uint16(data) != 0x0600 {
+		return 0, errors.New("仅支持 Excel 97-2003（BIFF8）格式")
+	}
+	var (
+		formats = map[uint16]string{}
+		xfFmts  []uint16
+		offset  uint32
+		found   bool
+	)
+	for {
+		typ, data, err := x.rr.next()
+		if err != nil {
+			return 0, err
+		}
+		switch typ {
+		case recFilePass:
+			return 0, errors.New("暂不支持加密的 Excel 97-2003 文件")
+		case recDateMode:
+			x.date1904 = len(data) >= 2 && binary.LittleEndian.Uint16(data) == 1
+		case recFormat:
+			if len(data) > 2 {
+				s, _ := readXLString(data[2:], 2)
+				formats[binary.LittleEndian.Uint16(data)] = s
+			}
+		case recXF:
+			if len(data) >= 4 {
+				xfFmts = append(xfFmts, binary.LittleEndian.Uint16(data[2:]))
+			}
+		case recBoundSheet:
+			if !found && len(data) >= 6 && data[5] == 0 { // 第一张工作表（跳过图表、宏表）
+				offset, found = binary.LittleEndian.Uint32(data), true
+			}
+		case recSST:
+			segs := [][]byte{data}
+			for {
+				typ, data, err := x.rr.next()
+				if err != nil {
+					return 0, err
+				}
+				if typ != recContinue {
+					x.rr.back()
+					break
+				}
+				segs = append(segs, data)
+			}
+			if x.sst, err = readSST(segs); err != nil {
+				return 0, err
+			}
+		case recEOF:
+			if !found {
+				return 0, errors.New("未找到工作表")
+			}
+			x.xfDate = make([]bool, len(xfFmts))
+			for i, id := range xfFmts {
+				x.xfDate[i] = isDateFormat(int(id), formats[id])
+			}
+			return offset, nil
+		}
+	}
+}
+
+// readRow
+// 读取下一有数据行，返回行号（从0开始）和各列值，工作表读完返回 -1
+func (x *xlsRows) readRow() (int, []string, error) {
+	rowIdx := -1
+	x.build = nil
+	for !x.ended {
+		typ, data, err := x.rr.next()
+		if err == io.EOF || (err == nil && (typ == recEOF || typ == recBOF)) {
+			x.ended = true
+			return rowIdx, x.build, nil
+		}
+		if err != nil {
+			return -1, nil, err
+		}
+		if typ == recString {
+			if x.strCell >= 0 {
+				s, _ := readXLString(data, 2)
+				x.set(x.strCell, s)
+				x.strCell = -1
+			}
+			continue
+		}
+		if !isCellRecord(typ) || len(data) < 6 {
+			continue
+		}
+		row := int(binary.LittleEndian.Uint16(data))
+		if rowIdx < 0 {
+			rowIdx = row
+		} else if row != rowIdx { // 下一行，留待下次读取
+			x.rr.back()
+			return rowIdx, x.build, nil
+		}
+		x.readCell(typ, data)
+	}
+	return -1, nil, nil
+}
+
+func isCellRecord(typ uint16) bool {
+	switch typ {
+	case recLabelSST, recLabel, recNumber, recRK, recMulRK, recBoolErr, recFormula:
+		return true
+	}
+	return false
+}
+
+func (x *xlsRows) set(col int, val string) {
+	for len(x.build) <= col {
+		x.build = append(x.build, "")
+	}
+	x.build[col] = val
+}
+
+func (x *xlsRows) readCell(typ uint16, data []byte) {
+	col := int(binary.LittleEndian.Uint16(data[2:]))
+	xf := int(binary.LittleEndian.Uint16(data[4:]))
+	switch typ {
+	case recLabelSST:
+		if len(data) >= 10 {
+			if i := int(binary.LittleEndian.Uint32(data[6:])); i < len(x.sst) {
+				x.set(col, x.sst[i])
+			}
+		}
+	case recLabel:
+		s, _ := readXLString(data[6:], 2)
+		x.set(col, s)
+	case recNumber:
+		if len(data) >= 14 {
+			x.set(col, x.formatNumber(math.Float64frombits(binary.LittleEndian.Uint64(data[6:])), xf))
+		}
+	case recRK:
+		if len(data) >= 10 {
+			x.set(col, x.formatNumber(decodeRK(binary.LittleEndian.Uint32(data[6:])), xf))
+		}
+	case recMulRK:
+		for i := 4; i+6 <= len(data)-2; i += 6 {
+			xf := int(binary.LittleEndian.Uint16(data[i:]))
+			x.set(col, x.formatNumber(decodeRK(binary.LittleEndian.Uint32(data[i+2:])), xf))
+			col++
+		}
+	case recBoolErr:
+		if len(data) >= 8 {
+			x.set(col, formatBoolErr(data[6], data[7] == 1))
+		}
+	case recFormula:
+		if len(data) < 14 {
+			return
+		}
+		res := data[6:14]
+		if binary.LittleEndian.Uint16(res[6:]) != 0xFFFF {
+			x.set(col, x.formatNumber(math.Float64frombits(binary.LittleEndian.Uint64(res)), xf))
+			return
+		}
+		switch res[0] {
+		case 0: // 字符串结果在其后的 STRING 记录
+			x.set(col, "")
+			x.strCell = col
+		case 1:
+			x.set(col, formatBoolErr(res[2], false))
+		case 2:
+			x.set(col, formatBoolErr(res[2], true))
+		}
+	}
+}
+
+func formatBoolErr(val byte, isErr bool) string {
+	if isErr {
+		return xlsErrors[val]
+	}
+	if val == 1 {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// decodeRK
+// RK 压缩数值：bit0 除以100，bit1 为30位整数，否则为 double 的高30位
+func decodeRK(rk uint32) float64 {
+	var v float64
+	if rk&0x02 != 0 {
+		v = float64(int32(rk) >> 2)
+	} else {
+		v = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+	if rk&0x01 != 0 {
+		v /= 100
+	}
+	return v
+}
+
+// formatNumber
+// 日期格式按 ISO 格式输出，其余按最短精确表示输出
+func (x *xlsRows) formatNumber(v float64, xf int) string {
+	if xf < len(x.xfDate) && x.xfDate[xf] {
+		if s, ok := FormatSerial(v, x.date1904); ok {
+			return s
+		}
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// readXLString
+// 读取 XLUnicodeString，lenSize 为长度字段字节数，返回字符串及所占字节数
+func readXLString(b []byte, lenSize int) (string, int) {
+	if len(b) < lenSize+1 {
+		return "", len(b)
+	}
+	cch := int(b[0])
+	if lenSize == 2 {
+		cch = int(binary.LittleEndian.Uint16(b))
+	}
+	high := b[lenSize]&0x01 != 0
+	b = b[lenSize+1:]
+	if high {
+		n := min(cch*2, len(b)&^1)
+		u := make([]uint16, n/2)
+		for i := range u {
+			u[i] = binary.LittleEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(u)), lenSize + 1 + n
+	}
+	n := min(cch, len(b))
+	r := make([]rune, n)
+	for i := range r {
+		r[i] = rune(b[i])
+	}
+	return string(r), lenSize + 1 + n
+}
+
+// sstReader
+// 共享字符串表可跨 CONTINUE 记录，字符跨记录时新记录首字节为压缩标志
+type sstReader struct {
+	segs [][]byte
+	i    int
+	pos  int
+}
+
+func (s *sstReader) bytes(n int) ([]byte, error) {
+	res := make([]byte, 0, n)
+	for len(res) < n {
+		if s.pos >= len(s.segs[s.i]) {
+			if s.i+1 >= len(s.segs) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			s.i, s.pos = s.i+1, 0
+		}
+		k := min(n-len(res), len(s.segs[s.i])-s.pos)
+		res = append(res, s.segs[s.i][s.pos:s.pos+k]...)
+		s.pos += k
+	}
+	return res, nil
+}
+
+// skip
+// 跳过 n 字节，不分配内存（长度取自文件）
+func (s *sstReader) skip(n int) error {
+	for n > 0 {
+		if s.pos >= len(s.segs[s.i]) {
+			if s.i+1 >= len(s.segs) {
+				return io.ErrUnexpectedEOF
+			}
+			s.i, s.pos = s.i+1, 0
+		}
+		k := min(n, len(s.segs[s.i])-s.pos)
+		s.pos += k
+		n -= k
+	}
+	return nil
+}
+
+func (s *sstReader) chars(cch int, high bool) (string, error) {
+	u := make([]uint16, 0, cch)
+	for len(u) < cch {
+		if s.pos >= len(s.segs[s.i]) {
+			if s.i+1 >= len(s.segs) || len(s.segs[s.i+1]) == 0 {
+				return "", io.ErrUnexpectedEOF
+			}
+			s.i, s.pos = s.i+1, 1
+			high = s.segs[s.i][0]&0x01 != 0
+		}
+		seg := s.segs[s.i]
+		if high {
+			for s.pos+1 < len(seg) && len(u) < cch {
+				u = append(u, binary.LittleEndian.Uint16(seg[s.pos:]))
+				s.pos += 2
+			}
+		} else {
+			for s.pos < len(seg) && len(u) < cch {
+				u = append(u, uint16(seg[s.pos]))
+				s.pos++
+			}
+		}
+	}
+	return string(utf16.Decode(u)), nil
+}
+
+// readSST
+// 解析共享字符串表（XLUnicodeRichExtendedString 数组）
+func readSST(segs [][]byte) ([]string, error) {
+	s := &sstReader{segs: segs}
+	head, err := s.bytes(8)
+	if err != nil {
+		return nil, err
+	}
+	count := int(binary.LittleEndian.Uint32(head[4:]))
+	res := make([]string, 0, min(count, 1<<16)) // 数量取自文件，预分配设上限
+	for range count {
+		b, err := s.bytes(3)
+		if err != nil {
+			return nil, err
+		}
+		cch, flags := int(binary.LittleEndian.Uint16(b)), b[2]
+		runs, ext := 0, 0
+		if flags&0x08 != 0 {
+			b, err := s.bytes(2)
+			if err != nil {
+				return nil, err
+			}
+			runs = int(binary.LittleEndian.Uint16(b))
+		}
+		if flags&0x04 != 0 {
+			b, err := s.bytes(4)
+			if err != nil {
+				return nil, err
+			}
+			ext = int(binary.LittleEndian.Uint32(b))
+		}
+		str, err := s.chars(cch, flags&0x01 != 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.skip(runs*4 + ext); err != nil { // 跳过富文本格式、扩展信息
+			return nil, err
+		}
+		res = append(res, str)
+	}
+	return res, nil
+}
+
+func (x *xlsRows) Next() bool {
+	if x.done {
+		return false
+	}
+	if x.err != nil { // 由 Columns 返回错误
+		x.done = true
+		return true
+	}
+	if x.nextIdx < 0 {
+		x.done = true
+		return false
+	}
+	if x.line < x.nextIdx { // 空行
+		x.line++
+		x.cur = nil
+		return true
+	}
+	x.line++
+	x.cur = x.next
+	x.nextIdx, x.next, x.err = x.readRow()
+	return true
+}
+
+func (x *xlsRows) Columns() ([]string, error) {
+	if x.err != nil {
+		return nil, x.err
+	}
+	return x.cur, nil
+}
+
+func (x *xlsRows) Close() error {
+	return x.file.Close()
+}
@@ -0,0 +1,308 @@
+package source
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+	"unicode/utf16"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+)
+
+// writeCFB
+// 生成仅含一个数据流的复合文档（CFB v3，512 字节扇区）：扇区0为 FAT，扇区1为目录，其后为数据流；
+// 数据流补足 4096 字节，避免存入迷你流
+func writeCFB(t *testing.T, name string, streamName string, stream []byte) string {
+	t.Helper()
+	const (
+		sector     = 512
+		endOfChain = 0xFFFFFFFE
+		freeSect   = 0xFFFFFFFF
+		noStream   = 0xFFFFFFFF
+	)
+	if len(stream) < 4096 {
+		stream = append(stream, make([]byte, 4096-len(stream))...)
+	}
+	size := len(stream)
+	sectors := (size + sector - 1) / sector
+	if sectors > 126 {
+		t.Fatal("数据流过大")
+	}
+	le := binary.LittleEndian
+
+	head := make([]byte, sector)
+	copy(head, magicCFB)
+	le.PutUint16(head[24:], 0x003E)
+	le.PutUint16(head[26:], 0x0003)
+	le.PutUint16(head[28:], 0xFFFE)
+	le.PutUint16(head[30:], 9)
+	le.PutUint16(head[32:], 6)
+	le.PutUint32(head[44:], 1) // FAT 扇区数
+	le.PutUint32(head[48:], 1) // 目录起始扇区
+	le.PutUint32(head[56:], 4096)
+	le.PutUint32(head[60:], endOfChain)
+	le.PutUint32(head[68:], endOfChain)
+	le.PutUint32(head[76:], 0) // DIFAT[0]：FAT 位于扇区0
+	for i := 1; i < 109; i++ {
+		le.PutUint32(head[76+i*4:], freeSect)
+	}
+
+	fat := make([]byte, sector)
+	for i := range sector / 4 {
+		le.PutUint32(fat[i*4:], freeSect)
+	}
+	le.PutUint32(fat[0:], 0xFFFFFFFD) // FATSECT
+	le.PutUint32(fat[4:], endOfChain) // 目录
+	for i := range sectors {
+		next := uint32(i + 3)
+		if i == sectors-1 {
+			next = endOfChain
+		}
+		le.PutUint32(fat[(i+2)*4:], next)
+	}
+
+	dir := make([]byte, sector)
+	entry := func(i int, name string, typ byte, child uint32, start uint32, size int) {
+		e := dir[i*128 : (i+1)*128]
+		u := utf16.Encode([]rune(name))
+		for k, c := range u {
+			le.PutUint16(e[k*2:], c)
+		}
+		le.PutUint16(e[64:], uint16(len(u)*2+2))
+		e[66], e[67] = typ, 1
+		le.PutUint32(e[68:], noStream)
+		le.PutUint32(e[72:], noStream)
+		le.PutUint32(e[76:], child)
+		le.PutUint32(e[116:], start)
+		le.PutUint32(e[120:], uint32(size))
+	}
+	entry(0, "Root Entry", 5, 1, endOfChain, 0)
+	entry(1, streamName, 2, noStream, 2, size)
+	for i := 2; i < 4; i++ {
+		e := dir[i*128 : (i+1)*128]
+		le.PutUint32(e[68:], noStream)
+		le.PutUint32(e[72:], noStream)
+		le.PutUint32(e[76:], noStream)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(head)
+	buf.Write(fat)
+	buf.Write(dir)
+	buf.Write(stream)
+	buf.Write(make([]byte, sectors*sector-size))
+	return writeFile(t, name, buf.Bytes())
+}
+
+// biff
+// 按记录拼接 BIFF8 数据流
+type biff struct {
+	bytes.Buffer
+}
+
+func (b *biff) rec(typ uint16, data ...[]byte) {
+	body := bytes.Join(data, nil)
+	binary.Write(b, binary.LittleEndian, typ)
+	binary.Write(b, binary.LittleEndian, uint16(len(body)))
+	b.Write(body)
+}
+
+func u16(v int) []byte     { return binary.LittleEndian.AppendUint16(nil, uint16(v)) }
+func u32(v uint32) []byte  { return binary.LittleEndian.AppendUint32(nil, v) }
+func f64(v float64) []byte { return binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)) }
+
+// cell
+// 单元格记录的行、列、XF
+func cell(row int, col int, xf int) []byte {
+	return slices.Concat(u16(row), u16(col), u16(xf))
+}
+
+// rkInt
+// 整数 RK，x100 为真则存储值的100倍
+func rkInt(v int32, x100 bool) uint32 {
+	rk := uint32(v)<<2 | 0x02
+	if x100 {
+		rk |= 0x01
+	}
+	return rk
+}
+
+// xlsFixture
+// 全局信息含 1904 纪元标志、XF0（常规）与 XF1（内置日期格式14）及跨 CONTINUE 的共享字符串表：
+// 第2个字符串以单字节字符开始，在 CONTINUE 记录中改为双字节（首字节为标志），第3个字符串含富文本格式
+func xlsFixture(date1904 bool) []byte {
+	var g biff
+	g.rec(recBOF, u16(0x0600), u16(0x0005), make([]byte, 12))
+	if date1904 {
+		g.rec(recDateMode, u16(1))
+	}
+	g.rec(recXF, u16(0), u16(0), make([]byte, 16))
+	g.rec(recXF, u16(0), u16(14), make([]byte, 16))
+	sheetPos := g.Len() + 4 // BOUNDSHEET 位置字段
+	g.rec(recBoundSheet, u32(0), []byte{0, 0, 5, 0}, []byte("Sheet"))
+	g.rec(recSST,
+		u32(3), u32(3),
+		u16(4), []byte{0}, []byte("name"),
+		u16(7), []byte{0}, []byte("abc"))
+	g.rec(recContinue,
+		[]byte{1}, utf16Bytes("d中文e"),
+		u16(4), []byte{0x08}, u16(1), []byte("rich"), make([]byte, 4))
+	g.rec(recEOF)
+
+	var s biff
+	s.rec(recBOF, u16(0x0600), u16(0x0010), make([]byte, 12))
+	// 第1行：共享字符串
+	s.rec(recLabelSST, cell(0, 0, 0), u32(0))
+	s.rec(recLabelSST, cell(0, 1, 0), u32(1))
+	s.rec(recLabelSST, cell(0, 2, 0), u32(2))
+	// 第2行：RK 整数、整数/100、double 高30位；第3列为日期
+	s.rec(recRK, cell(1, 0, 0), u32(rkInt(123, false)))
+	s.rec(recRK, cell(1, 1, 0), u32(rkInt(-123, true)))
+	s.rec(recRK, cell(1, 2, 0), u32(uint32(math.Float64bits(0.5)>>32)))
+	s.rec(recRK, cell(1, 3, 1), u32(rkInt(45000, false)))
+	// 第3行：MULRK 自第3列起
+	s.rec(recMulRK, u16(2), u16(2),
+		u16(0), u32(rkInt(7, false)),
+		u16(0), u32(rkInt(250, true)),
+		u16(1), u32(uint32(math.Float64bits(45000.5)>>32)),
+		u16(4))
+	// 第4行：公式的字符串（其后 STRING 记录）、布尔、错误、数值结果
+	flags := make([]byte, 8) // grbit、chn、cce
+	s.rec(recFormula, cell(3, 0, 0), []byte{0, 0, 0, 0, 0, 0, 0xFF, 0xFF}, flags)
+	s.rec(recString, u16(3), []byte{0}, []byte("str"))
+	s.rec(recFormula, cell(3, 1, 0), []byte{1, 0, 1, 0, 0, 0, 0xFF, 0xFF}, flags)
+	s.rec(recFormula, cell(3, 2, 0), []byte{2, 0, 0x07, 0, 0, 0, 0xFF, 0xFF}, flags)
+	s.rec(recFormula, cell(3, 3, 0), f64(2.5), flags)
+	// 第5行为空，第6行：NUMBER、BOOLERR、LABEL
+	s.rec(recNumber, cell(5, 0, 0), f64(3.25))
+	s.rec(recBoolErr, cell(5, 1, 0), []byte{1, 0})
+	s.rec(recBoolErr, cell(5, 2, 0), []byte{0x2A, 1})
+	s.rec(recLabel, cell(5, 4, 0), u16(2), []byte{0}, []byte("lb"))
+	s.rec(recEOF)
+
+	out := g.Bytes()
+	binary.LittleEndian.PutUint32(out[sheetPos:], uint32(len(out)))
+	return append(out, s.Bytes()...)
+}
+
+func utf16Bytes(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+func TestXlsRows(t *testing.T) {
+	tests := []struct {
+		name     string
+		date1904 bool
+		dates    [2]string // 第2行第4列、第3行第4列
+	}{
+		{"1900", false, [2]string{"2023-03-15", "2023-03-15 12:00:00"}},
+		{"1904", true, [2]string{"2027-03-16", "2027-03-16 12:00:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeCFB(t, "book.xls", "Workbook", xlsFixture(tt.date1904))
+			if kind, err := Detect(path); err != nil || kind != KindXls {
+				t.Fatalf("Detect = %v %v", kind, err)
+			}
+			rows, err := Open(path, option.Source{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var got [][]string
+			for rows.Next() {
+				row, err := rows.Columns()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, row)
+			}
+			want := [][]string{
+				{"name", "abcd中文e", "rich"},
+				{"123", "-1.23", "0.5", tt.dates[0]},
+				{"", "", "7", "2.5", tt.dates[1]},
+				{"str", "TRUE", "#DIV/0!", "2.5"},
+				nil,
+				{"3.25", "TRUE", "#N/A", "", "lb"},
+			}
+			if !slices.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("rows = %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+func TestReadSST(t *testing.T) {
+	tests := []struct {
+		name    string
+		segs    [][]byte
+		want    []string
+		wantErr bool
+	}{
+		{
+			"双字节字符跨记录改为单字节",
+			[][]byte{
+				slices.Concat(u32(2), u32(2), u16(3), []byte{1}, utf16Bytes("中")),
+				slices.Concat([]byte{0}, []byte("ab"), u16(1), []byte{0}, []byte("x")),
+			},
+			[]string{"中ab", "x"},
+			false,
+		},
+		{
+			"扩展信息跨记录",
+			[][]byte{
+				slices.Concat(u32(2), u32(2), u16(1), []byte{0x04}, u32(6), []byte("a"), []byte{1, 2}),
+				slices.Concat([]byte{3, 4, 5, 6}, u16(1), []byte{0}, []byte("b")),
+			},
+			[]string{"a", "b"},
+			false,
+		},
+		{
+			"数量超出实际",
+			[][]byte{slices.Concat(u32(1), u32(math.MaxInt32), u16(1), []byte{0}, []byte("a"))},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readSST(tt.segs)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("期望返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRK(t *testing.T) {
+	tests := []struct {
+		rk   uint32
+		want float64
+	}{
+		{rkInt(123, false), 123},
+		{rkInt(-5, false), -5},
+		{rkInt(123, true), 1.23},
+		{uint32(math.Float64bits(0.5) >> 32), 0.5},
+		{uint32(math.Float64bits(100)>>32) | 0x01, 1},
+	}
+	for _, tt := range tests {
+		if got := decodeRK(tt.rk); got != tt.want {
+			t.Errorf("decodeRK(%#x) = %v, want %v", tt.rk, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,817 @@
+package source
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/xuri/excelize/v2"
+)
+
+// 关系类型后缀
+const (
+	relOfficeDocument = "/officeDocument"
+	relWorksheet      = "/worksheet"
+	relSharedStrings  = "/sharedStrings"
+	relStyles         = "/styles"
+	relHyperlink      = "/hyperlink"
+	relComments       = "/comments"
+)
+
+// NumFmt
+// 单元格样式的数字格式
+type NumFmt struct {
+	ID   int    // 数字格式 ID，小于164为内置格式
+	Code string // 自定义格式代码，内置格式为空
+	Date bool   // 是否为日期时间格式
+}
+
+// Text
+// 是否为文本格式（@），该列的值应按文本处理
+func (n NumFmt) Text() bool {
+	return n.ID == 49 || n.Code == "@"
+}
+
+// ZeroPad
+// 补零格式（如邮编常用的 000000）的位数，其他格式为 0
+func (n NumFmt) ZeroPad() int {
+	if len(n.Code) < 2 || strings.Trim(n.Code, "0") != "" {
+		return 0
+	}
+	return len(n.Code)
+}
+
+// Workbook
+// 以 zip 方式读取的 xlsx 类工作簿，只解析读取第一张工作表所需的部件
+type Workbook struct {
+	Zip      *zip.Reader
+	Sheet    string   // 第一张工作表在包内的路径
+	Date1904 bool     // 1904 日期纪元
+	numFmts  []NumFmt // 各单元格样式（cellXfs）的数字格式
+	sst      string   // 共享字符串部件路径
+	closer   io.Closer
+}
+
+// OpenWorkbook
+// 打开 xlsx 类工作簿（已解锁的加密文件打开其在内存中解密所得的工作簿），解析第一张工作表路径、日期纪元和样式的数字格式
+func OpenWorkbook(file string, opts option.Source) (*Workbook, error) {
+	zr, closer, err := OpenPackage(file, opts)
+	if err != nil {
+		return nil, err
+	}
+	wb := &Workbook{Zip: zr, closer: closer}
+	if err := wb.parse(); err != nil {
+		closer.Close()
+		return nil, err
+	}
+	return wb, nil
+}
+
+func (wb *Workbook) Close() error {
+	return wb.closer.Close()
+}
+
+// NumFmt
+// 样式 ID 对应的数字格式，超出范围视为常规格式
+func (wb *Workbook) NumFmt(style int) NumFmt {
+	if style < 0 || style >= len(wb.numFmts) {
+		return NumFmt{}
+	}
+	return wb.numFmts[style]
+}
+
+// Open
+// 打开包内部件
+func (wb *Workbook) Open(name string) (io.ReadCloser, error) {
+	for _, f := range wb.Zip.File {
+		if f.Name == name {
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("文件不存在：%s", name)
+}
+
+// StylePackage
+// 仅含工作簿、样式、主题等部件的精简包（工作表、共享字符串为空），供 excelize 读取样式定义而不加载数据
+func (wb *Workbook) StylePackage() ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range wb.Zip.File {
+		var stub string
+		switch {
+		case strings.Contains(f.Name, "worksheets/") && strings.HasSuffix(f.Name, ".xml"):
+			stub = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`
+		case strings.HasSuffix(f.Name, "sharedStrings.xml"):
+			stub = `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"/>`
+		case f.Name == "[Content_Types].xml", strings.HasSuffix(f.Name, ".rels"),
+			strings.HasSuffix(f.Name, "workbook.xml"), strings.HasSuffix(f.Name, "styles.xml"), strings.Contains(f.Name, "theme/"):
+		default:
+			continue
+		}
+		w, err := zw.Create(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if stub != "" {
+			if _, err := io.WriteString(w, stub); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(w, r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// has
+// 包内是否存在该部件
+func (wb *Workbook) has(name string) bool {
+	for _, f := range wb.Zip.File {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// relationship
+// 部件关系：Id、类型、包内路径（外部关系为原地址）
+type relationship struct {
+	id, typ, target string
+	external        bool
+}
+
+// readRels
+// 读取部件的关系，目标路径相对该部件所在目录解析，外部关系（如超链接）保留原地址
+func (wb *Workbook) readRels(part string) ([]relationship, error) {
+	dir, base := path.Split(part)
+	name := dir + "_rels/" + base + ".rels"
+	if !wb.has(name) {
+		return nil, nil
+	}
+	r, err := wb.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var res []relationship
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "Relationship" {
+			continue
+		}
+		var rel relationship
+		for _, a := range se.Attr {
+			switch a.Name.Local {
+			case "Id":
+				rel.id = a.Value
+			case "Type":
+				rel.typ = a.Value
+			case "Target":
+				rel.target = a.Value
+			case "TargetMode":
+				rel.external = a.Value == "External"
+			}
+		}
+		if rel.external {
+			res = append(res, rel)
+			continue
+		}
+		if strings.HasPrefix(rel.target, "/") {
+			rel.target = strings.TrimPrefix(rel.target, "/")
+		} else {
+			rel.target = path.Join(dir, rel.target)
+		}
+		res = append(res, rel)
+	}
+	return res, nil
+}
+
+// parse
+// 依次解析 _rels/.rels → workbook.xml（第一张表、日期纪元）→ 工作簿关系 → styles.xml
+func (wb *Workbook) parse() error {
+	book := "xl/workbook.xml"
+	rels, err := wb.readRels("")
+	if err != nil {
+		return err
+	}
+	for _, rel := range rels {
+		if strings.HasSuffix(rel.typ, relOfficeDocument) {
+			book = rel.target
+		}
+	}
+	sheetId, err := wb.parseBook(book)
+	if err != nil {
+		return err
+	}
+	if rels, err = wb.readRels(book); err != nil {
+		return err
+	}
+	styles := ""
+	for _, rel := range rels {
+		switch {
+		case rel.id == sheetId && strings.HasSuffix(rel.typ, relWorksheet):
+			wb.Sheet = rel.target
+		case strings.HasSuffix(rel.typ, relSharedStrings):
+			wb.sst = rel.target
+		case strings.HasSuffix(rel.typ, relStyles):
+			styles = rel.target
+		}
+	}
+	if wb.Sheet == "" {
+		return fmt.Errorf("未找到工作表：%s", sheetId)
+	}
+	if styles != "" {
+		return wb.parseStyles(styles)
+	}
+	return nil
+}
+
+// parseBook
+// 读取第一张工作表的关系 Id 及日期纪元
+func (wb *Workbook) parseBook(book string) (string, error) {
+	r, err := wb.Open(book)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return "", fmt.Errorf("工作簿不含工作表：%s", book)
+		}
+		if err != nil {
+			return "", err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "workbookPr":
+			for _, a := range se.Attr {
+				if a.Name.Local == "date1904" {
+					wb.Date1904 = a.Value == "1" || a.Value == "true"
+				}
+			}
+		case "sheet":
+			for _, a := range se.Attr {
+				if a.Name.Local == "id" && a.Name.Space != "" { // r:id
+					return a.Value, nil
+				}
+			}
+		}
+	}
+}
+
+// parseStyles
+// 读取自定义数字格式及各单元格样式（cellXfs，不含 cellStyleXfs）的数字格式
+func (wb *Workbook) parseStyles(styles string) error {
+	r, err := wb.Open(styles)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	codes := map[int]string{}
+	inCellXfs := false
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch se := tok.(type) {
+		case xml.StartElement:
+			switch se.Name.Local {
+			case "numFmt":
+				var (
+					id   int
+					code string
+				)
+				for _, a := range se.Attr {
+					switch a.Name.Local {
+					case "numFmtId":
+						id, _ = strconv.Atoi(a.Value)
+					case "formatCode":
+						code = a.Value
+					}
+				}
+				codes[id] = code
+			case "cellXfs":
+				inCellXfs = true
+			case "xf":
+				if !inCellXfs {
+					continue
+				}
+				var nf NumFmt
+				for _, a := range se.Attr {
+					if a.Name.Local == "numFmtId" {
+						nf.ID, _ = strconv.Atoi(a.Value)
+					}
+				}
+				nf.Code = codes[nf.ID]
+				nf.Date = isDateFormat(nf.ID, nf.Code)
+				wb.numFmts = append(wb.numFmts, nf)
+			}
+		case xml.EndElement:
+			if se.Name.Local == "cellXfs" {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// readSST
+// 读取全部共享字符串，富文本各段拼接，忽略拼音（rPh）
+func (wb *Workbook) readSST() ([]string, error) {
+	if wb.sst == "" || !wb.has(wb.sst) {
+		return nil, nil
+	}
+	r, err := wb.Open(wb.sst)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var (
+		res  []string
+		text strings.Builder
+	)
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch se := tok.(type) {
+		case xml.StartElement:
+			switch se.Name.Local {
+			case "si":
+				text.Reset()
+			case "t":
+				s, err := readText(decoder)
+				if err != nil {
+					return nil, err
+				}
+				text.WriteString(s)
+			case "rPh":
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			if se.Name.Local == "si" {
+				res = append(res, text.String())
+			}
+		}
+	}
+	return res, nil
+}
+
+// readText
+// 读取元素文本直至元素结束
+func readText(decoder *xml.Decoder) (string, error) {
+	var b strings.Builder
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.EndElement:
+			return b.String(), nil
+		}
+	}
+}
+
+// xlsxRows
+// 流式读取工作表 XML：日期格式的数值按 ISO 格式输出，布尔值为 TRUE/FALSE，
+// 其余数值按文件中的原值输出（不套用千分位等显示格式，避免精度损失）；
+// 与 excelize 一致，缺失的行输出为空行，行尾空单元格省略（含公式的除外）
+type xlsxRows struct {
+	wb      *Workbook
+	sheet   io.ReadCloser
+	decoder *xml.Decoder
+	sst     []string
+	row     xlsxRow
+	pending *xlsxRow // 已读出但行号靠后的行
+	pendNum int      // pending 的行号
+	num     int      // 当前行号
+	shared  map[string]sharedFormula
+	done    bool
+	err     error
+	raw     bool         // 数值按原始值读出
+	numFmt  numFormatter // 数值按数字格式生成显示文本
+
+	merged    []CellRange  // 拆分填充的合并单元格区域
+	mergedIdx int          // 下一个尚未开始的区域
+	filling   []mergedFill // 当前行所在的区域
+}
+
+// xlsxRow
+// 一行中各单元格的值、公式及样式 ID，按列对应
+type xlsxRow struct {
+	values   []string
+	formulas []string
+	styles   []int
+}
+
+// sharedFormula
+// 共享公式的主公式及其所在位置，其余单元格按相对位置偏移
+type sharedFormula struct {
+	formula  string
+	row, col int
+}
+
+func openXlsx(file string, opts option.Source) (*xlsxRows, error) {
+	wb, err := OpenWorkbook(file, opts)
+	if err != nil {
+		return nil, err
+	}
+	sst, err := wb.readSST()
+	if err != nil {
+		wb.Close()
+		return nil, err
+	}
+	var merged []CellRange
+	if opts.FillMerged {
+		if merged, err = wb.MergedCells(); err != nil {
+			wb.Close()
+			return nil, err
+		}
+	}
+	sheet, err := wb.Open(wb.Sheet)
+	if err != nil {
+		wb.Close()
+		return nil, err
+	}
+	return &xlsxRows{wb: wb, sheet: sheet, decoder: xml.NewDecoder(sheet), sst: sst,
+		shared: make(map[string]sharedFormula), merged: merged, raw: opts.RawNumbers}, nil
+}
+
+func (r *xlsxRows) Next() bool {
+	if !r.advance() {
+		return false
+	}
+	if len(r.merged) > 0 && r.err == nil {
+		r.fillMerged()
+	}
+	return true
+}
+
+// advance
+// 读取下一行，缺失的行为空行
+func (r *xlsxRows) advance() bool {
+	if r.err != nil {
+		return false
+	}
+	r.num++
+	if r.pending != nil {
+		if r.pendNum > r.num {
+			r.row = xlsxRow{}
+			return true
+		}
+		r.row, r.pending = *r.pending, nil
+		return true
+	}
+	if r.done {
+		return false
+	}
+	row, num, err := r.readRow()
+	if err != nil {
+		r.err = err
+		return true // 由 Columns 返回错误
+	}
+	if num == 0 { // 已无数据行
+		r.done = true
+		return false
+	}
+	if num > r.num {
+		r.pending, r.pendNum, r.row = &row, num, xlsxRow{}
+		return true
+	}
+	r.row = row
+	return true
+}
+
+func (r *xlsxRows) Columns() ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.row.values, nil
+}
+
+// Formulas
+// 当前行各单元格的公式（不含 =），与 Columns 按列对应，无公式为空；共享公式已展开
+func (r *xlsxRows) Formulas() []string {
+	return r.row.formulas
+}
+
+// Styles
+// 当前行各单元格的样式 ID，按列对应，可长于 Columns（含有样式的空单元格）
+func (r *xlsxRows) Styles() []int {
+	return r.row.styles
+}
+
+func (r *xlsxRows) Close() error {
+	r.sheet.Close()
+	r.numFmt.Close()
+	return r.wb.Close()
+}
+
+// readRow
+// 读取下一个 <row>，返回该行及行号；无更多行时返回行号0
+func (r *xlsxRows) readRow() (xlsxRow, int, error) {
+	for {
+		tok, err := r.decoder.Token()
+		if err == io.EOF {
+			return xlsxRow{}, 0, nil
+		}
+		if err != nil {
+			return xlsxRow{}, 0, err
+		}
+		switch se := tok.(type) {
+		case xml.StartElement:
+			if se.Name.Local != "row" {
+				continue
+			}
+			num := r.num
+			for _, a := range se.Attr {
+				if a.Name.Local == "r" {
+					if n, err := strconv.Atoi(a.Value); err == nil && n > 0 {
+						num = n
+					}
+				}
+			}
+			row, err := r.readCells(num)
+			if err != nil {
+				return xlsxRow{}, 0, err
+			}
+			return row, max(num, 1), nil
+		case xml.EndElement:
+			if se.Name.Local == "sheetData" {
+				return xlsxRow{}, 0, nil
+			}
+		}
+	}
+}
+
+// readCells
+// 读取第 num 行的单元格直至 </row>
+func (r *xlsxRows) readCells(num int) (xlsxRow, error) {
+	var (
+		row xlsxRow
+		col int
+	)
+	for {
+		tok, err := r.decoder.Token()
+		if err != nil {
+			return xlsxRow{}, err
+		}
+		switch se := tok.(type) {
+		case xml.StartElement:
+			if se.Name.Local != "c" {
+				continue
+			}
+			col++
+			var (
+				style int
+				typ   string
+			)
+			for _, a := range se.Attr {
+				switch a.Name.Local {
+				case "r":
+					if c := util.XlsxColIndexFromAxis(a.Value); c > 0 {
+						col = c
+					}
+				case "s":
+					style, _ = strconv.Atoi(a.Value)
+				case "t":
+					typ = a.Value
+				}
+			}
+			val, formula, err := r.readCell(style, typ, num, col)
+			if err != nil {
+				return xlsxRow{}, err
+			}
+			if val != "" || formula != "" {
+				for len(row.values) < col-1 {
+					row.values = append(row.values, "")
+				}
+				row.values = append(row.values, val)
+			}
+			if formula != "" {
+				for len(row.formulas) < col-1 {
+					row.formulas = append(row.formulas, "")
+				}
+				row.formulas = append(row.formulas, formula)
+			}
+			if style != 0 {
+				for len(row.styles) < col-1 {
+					row.styles = append(row.styles, 0)
+				}
+				row.styles = append(row.styles, style)
+			}
+		case xml.EndElement:
+			if se.Name.Local == "row" {
+				if row.values == nil {
+					row.values = []string{}
+				}
+				return row, nil
+			}
+		}
+	}
+}
+
+// readCell
+// 读取第 num 行第 col 列单元格的 <v>、<is>、<f> 直至 </c>，按类型及样式转换为文本
+func (r *xlsxRows) readCell(style int, typ string, num int, col int) (string, string, error) {
+	var v, inline, formula string
+	for {
+		tok, err := r.decoder.Token()
+		if err != nil {
+			return "", "", err
+		}
+		switch se := tok.(type) {
+		case xml.StartElement:
+			switch se.Name.Local {
+			case "v":
+				if v, err = readText(r.decoder); err != nil {
+					return "", "", err
+				}
+			case "t":
+				s, err := readText(r.decoder)
+				if err != nil {
+					return "", "", err
+				}
+				inline += s
+			case "f":
+				if formula, err = r.readFormula(se, num, col); err != nil {
+					return "", "", err
+				}
+			case "rPh":
+				if err := r.decoder.Skip(); err != nil {
+					return "", "", err
+				}
+			}
+		case xml.EndElement:
+			if se.Name.Local == "c" {
+				return r.value(v, inline, style, typ), formula, nil
+			}
+		}
+	}
+}
+
+// readFormula
+// 读取 <f>：共享公式的主单元格记录公式，其余单元格按相对位置展开；模拟运算表不作公式
+func (r *xlsxRows) readFormula(se xml.StartElement, num int, col int) (string, error) {
+	var t, si string
+	for _, a := range se.Attr {
+		switch a.Name.Local {
+		case "t":
+			t = a.Value
+		case "si":
+			si = a.Value
+		}
+	}
+	formula, err := readText(r.decoder)
+	if err != nil {
+		return "", err
+	}
+	switch t {
+	case "shared":
+		if formula != "" {
+			r.shared[si] = sharedFormula{formula: formula, row: num, col: col}
+			return formula, nil
+		}
+		if master, ok := r.shared[si]; ok {
+			return ShiftFormula(master.formula, num-master.row, col-master.col), nil
+		}
+		return "", nil
+	case "dataTable":
+		return "", nil
+	}
+	return formula, nil
+}
+
+func (r *xlsxRows) value(v string, inline string, style int, typ string) string {
+	switch typ {
+	case "s":
+		if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && i >= 0 && i < len(r.sst) {
+			return r.sst[i]
+		}
+		return ""
+	case "inlineStr":
+		return inline
+	case "str", "e":
+		return v
+	case "b":
+		if v == "1" {
+			return "TRUE"
+		}
+		if v == "0" {
+			return "FALSE"
+		}
+		return v
+	case "d":
+		s, _ := parseCellDate(v)
+		return s
+	}
+	if v == "" {
+		return v
+	}
+	numFmt := r.wb.NumFmt(style)
+	if numFmt.Date {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			if s, ok := FormatSerial(f, r.wb.Date1904); ok {
+				return s
+			}
+		}
+	}
+	if !r.raw && numFmt.ID != 0 { // 与 Excel 显示一致，如 12.5%、1,234.50
+		return r.numFmt.Format(v, numFmt)
+	}
+	if n := numFmt.ZeroPad(); n > len(v) && strings.Trim(v, "0123456789") == "" { // 按显示补足前导零
+		return strings.Repeat("0", n-len(v)) + v
+	}
+	return v
+}
+
+// numFormatter
+// 按数字格式生成数值的显示文本：借助内存中的空白工作簿，由 excelize 按与读取整表时相同的规则格式化
+type numFormatter struct {
+	f      *excelize.File
+	styles map[NumFmt]int // 数字格式 - 空白工作簿中的样式 ID
+}
+
+// Format
+// 数值 v 按数字格式显示的文本，格式无法解析时返回原值
+func (n *numFormatter) Format(v string, numFmt NumFmt) string {
+	if n.f == nil {
+		n.f, n.styles = excelize.NewFile(), make(map[NumFmt]int)
+	}
+	id, ok := n.styles[numFmt]
+	if !ok {
+		style := &excelize.Style{NumFmt: numFmt.ID}
+		if numFmt.ID >= 164 {
+			style.CustomNumFmt = &numFmt.Code
+		}
+		var err error
+		if id, err = n.f.NewStyle(style); err != nil {
+			return v
+		}
+		n.styles[numFmt] = id
+	}
+	if err := n.f.SetCellDefault("Sheet1", "A1", v); err != nil {
+		return v
+	}
+	if err := n.f.SetCellStyle("Sheet1", "A1", "A1", id); err != nil {
+		return v
+	}
+	s, err := n.f.GetCellValue("Sheet1", "A1")
+	if err != nil {
+		return v
+	}
+	return s
+}
+
+func (n *numFormatter) Close() {
+	if n.f != nil {
+		n.f.Close()
+	}
+}
@@ -0,0 +1,195 @@
+package source
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+)
+
+// writeXlsx
+// 按部件手工生成最小的 xlsx：样式 0 常规、1 千分位（#,##0.00）、2 百分比（0%）、3 内置日期格式14、4 自定义日期时间格式、
+// 5 自定义百分比（0.0%）
+func writeXlsx(t *testing.T, date1904 bool, sheetData string, sst string) string {
+	t.Helper()
+	const ns = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	const rels = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	const pkgRels = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+	bookPr := ""
+	if date1904 {
+		bookPr = `<workbookPr date1904="1"/>`
+	}
+	parts := [][2]string{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/></Types>`},
+		{"_rels/.rels", `<Relationships ` + pkgRels + `>` +
+			`<Relationship Id="rId1" Type="` + rels + `/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<workbook ` + ns + ` xmlns:r="` + rels + `">` + bookPr +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships ` + pkgRels + `>` +
+			`<Relationship Id="rId1" Type="` + rels + `/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="` + rels + `/sharedStrings" Target="sharedStrings.xml"/>` +
+			`<Relationship Id="rId3" Type="` + rels + `/styles" Target="styles.xml"/></Relationships>`},
+		{"xl/styles.xml", `<styleSheet ` + ns + `><numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy/m/d h:mm"/>` +
+			`<numFmt numFmtId="165" formatCode="0.0%"/></numFmts>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0"/></cellStyleXfs>` +
+			`<cellXfs count="6"><xf numFmtId="0"/><xf numFmtId="4"/><xf numFmtId="9"/><xf numFmtId="14"/><xf numFmtId="164"/>` +
+			`<xf numFmtId="165"/></cellXfs></styleSheet>`},
+		{"xl/sharedStrings.xml", `<sst ` + ns + `>` + sst + `</sst>`},
+		{"xl/worksheets/sheet1.xml", `<worksheet ` + ns + `><sheetData>` + sheetData + `</sheetData></worksheet>`},
+	}
+	path := filepath.Join(t.TempDir(), "book.xlsx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, part := range parts {
+		w, err := zw.Create(part[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(part[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readAll
+// 读取全部行的值及公式
+func readAll(t *testing.T, path string, opts option.Source) ([][]string, [][]string) {
+	t.Helper()
+	rows, err := Open(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var values, formulas [][]string
+	for rows.Next() {
+		row, err := rows.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, row)
+		formulas = append(formulas, rows.(FormulaRows).Formulas())
+	}
+	return values, formulas
+}
+
+func TestXlsxRows(t *testing.T) {
+	sst := `<si><t>name</t></si>` +
+		`<si><r><t>ri</t></r><r><t>ch</t></r><rPh><t>ルビ</t></rPh></si>`
+	sheet := `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>inline</t></is></c><c r="C1" t="s"><v>1</v></c></row>` +
+		`<row r="2"><c r="A2" s="1"><v>1234.5</v></c><c r="B2" s="2"><v>0.125</v></c><c r="C2" s="3"><v>45000</v></c>` +
+		`<c r="D2" s="4"><v>45000.5</v></c><c r="E2" t="d"><v>2026-01-02T03:04:05</v></c><c r="F2" t="b"><v>1</v></c></row>` +
+		// 缺失第3行；共享公式按相对位置展开
+		`<row r="4"><c r="A4"><f t="shared" ref="A4:A5" si="0">B4*2+$C$1</f><v>2</v></c><c r="B4"><v>1</v></c></row>` +
+		`<row r="5"><c r="A5"><f t="shared" si="0"/><v>4</v></c><c r="B5"><v>2</v></c><c r="D5" t="e"><v>#N/A</v></c></row>`
+	tests := []struct {
+		name     string
+		date1904 bool
+		dates    [2]string // C2、D2
+	}{
+		{"1900", false, [2]string{"2023-03-15", "2023-03-15 12:00:00"}},
+		{"1904", true, [2]string{"2027-03-16", "2027-03-16 12:00:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, formulas := readAll(t, writeXlsx(t, tt.date1904, sheet, sst), option.Source{})
+			want := [][]string{
+				{"name", "inline", "rich"},
+				{"1,234.50", "13%", tt.dates[0], tt.dates[1], "2026-01-02 03:04:05", "TRUE"},
+				{},
+				{"2", "1"},
+				{"4", "2", "", "#N/A"},
+			}
+			if !slices.EqualFunc(values, want, slices.Equal) {
+				t.Errorf("values = %q\nwant %q", values, want)
+			}
+			wantFormulas := [][]string{nil, nil, nil, {"B4*2+$C$1"}, {"B5*2+$C$1"}}
+			if !slices.EqualFunc(formulas, wantFormulas, slices.Equal) {
+				t.Errorf("formulas = %q\nwant %q", formulas, wantFormulas)
+			}
+		})
+	}
+}
+
+func TestXlsxNumFmt(t *testing.T) {
+	sheet := `<row r="1"><c r="A1" t="inlineStr"><is><t>rate</t></is></c><c r="B1" t="inlineStr"><is><t>amount</t></is></c>` +
+		`<c r="C1" t="inlineStr"><is><t>n</t></is></c></row>` +
+		`<row r="2"><c r="A2" s="5"><v>0.125</v></c><c r="B2" s="1"><v>1234.5</v></c><c r="C2"><v>7</v></c></row>` +
+		`<row r="3"><c r="A3" s="5"><v>-0.052</v></c><c r="B3" s="1"><v>-1234567</v></c><c r="C3" s="3"><v>45000</v></c></row>`
+	path := writeXlsx(t, false, sheet, "")
+	tests := []struct {
+		name string
+		raw  bool
+		want [][]string
+	}{
+		// 默认按数字格式显示，与 Excel 一致
+		{"显示文本", false, [][]string{{"rate", "amount", "n"}, {"12.5%", "1,234.50", "7"}, {"-5.2%", "-1,234,567.00", "2023-03-15"}}},
+		// 导出 xlsx 沿用样式时读取原始值，日期仍按 ISO 格式
+		{"原始值", true, [][]string{{"rate", "amount", "n"}, {"0.125", "1234.5", "7"}, {"-0.052", "-1234567", "2023-03-15"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := readAll(t, path, option.Source{RawNumbers: tt.raw})
+			if !slices.EqualFunc(values, tt.want, slices.Equal) {
+				t.Errorf("values = %q\nwant %q", values, tt.want)
+			}
+		})
+	}
+}
+
+func TestXlsxStyles(t *testing.T) {
+	sheet := `<row r="1"><c r="A1" t="inlineStr"><is><t>a</t></is></c><c r="B1" t="inlineStr"><is><t>b</t></is></c></row>` +
+		`<row r="2"><c r="A2" s="1"><v>1</v></c><c r="C2" s="2"/></row>` + // 含样式的空单元格、跳过的列
+		`<row r="4"><c r="A4"><v>2</v></c><c r="B4" s="3"><v>45000</v></c></row>`
+	rows, err := Open(writeXlsx(t, false, sheet, ""), option.Source{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got [][]int
+	for rows.Next() {
+		if _, err := rows.Columns(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rows.(StyledRows).Styles())
+	}
+	want := [][]int{nil, {1, 0, 2}, nil, {0, 3}} // 末个含样式的单元格之后省略
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("styles = %v, want %v", got, want)
+	}
+}
+
+func TestNumFmt(t *testing.T) {
+	tests := []struct {
+		fmt     NumFmt
+		text    bool
+		zeroPad int
+	}{
+		{NumFmt{ID: 49}, true, 0},
+		{NumFmt{ID: 164, Code: "@"}, true, 0},
+		{NumFmt{ID: 164, Code: "000000"}, false, 6},
+		{NumFmt{ID: 164, Code: "00"}, false, 2},
+		{NumFmt{ID: 1, Code: "0"}, false, 0},
+		{NumFmt{ID: 164, Code: "0.00"}, false, 0},
+		{NumFmt{ID: 0}, false, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.fmt.Text(); got != tt.text {
+			t.Errorf("%+v Text() = %v, want %v", tt.fmt, got, tt.text)
+		}
+		if got := tt.fmt.ZeroPad(); got != tt.zeroPad {
+			t.Errorf("%+v ZeroPad() = %d, want %d", tt.fmt, got, tt.zeroPad)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package xlsx
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"github.com/fatih/color"
+	"github.com/xuri/excelize/v2"
+)
+
+// 无法保留的公式最多逐条输出的日志数
+const formulaLogLimit = 10
+
+// formulaKeeper
+// 保留公式时按新行号改写引用：引用的数据行须位于当前块（同一数据文件或拆分份内连续写入的行），
+// 块止于被去重的行、续写新表前或数据文件（拆分份）末行；行首（第1行）不变；
+// 引用其他工作表、名称、整列或块外的行则无法保留，按计算结果写入
+type formulaKeeper struct {
+	file    string
+	last    int // 当前数据文件或拆分份的末行（源行号），0 表示未知
+	first   int // 当前块的首行（源行号）
+	end     int // 当前块可连续写入的末行（源行号），0 表示不限
+	delta   int // 当前块的目标行号 - 源行号
+	started bool
+	kept    int
+	dropped int
+}
+
+func newFormulaKeeper(policy option.Formula) *formulaKeeper {
+	if policy != option.FormulaKeep {
+		return nil
+	}
+	return &formulaKeeper{}
+}
+
+// Reset
+// 开始新的数据文件或拆分份
+func (k *formulaKeeper) Reset(file string, last int) {
+	if k == nil {
+		return
+	}
+	k.file, k.last, k.started = file, last, false
+}
+
+// Apply
+// 为源第 srcRow 行、目标第 tarRow 行的数据行写入改写后的公式；去重跳过行或续写新表时开始新的块，
+// end 为当前块可连续写入的末行（下一个被去重的行之前、当前表写满之前），0 表示不限
+func (k *formulaKeeper) Apply(rowNew []any, iter source.Rows, srcRow int, tarRow int, end int) {
+	if k == nil {
+		return
+	}
+	fr, ok := iter.(source.FormulaRows)
+	if !ok {
+		return
+	}
+	if !k.started || tarRow-srcRow != k.delta {
+		k.first, k.delta, k.started = srcRow, tarRow-srcRow, true
+	}
+	k.end = end
+	for c, formula := range fr.Formulas() {
+		if formula == "" || c >= len(rowNew) {
+			continue
+		}
+		cell, ok := rowNew[c].(excelize.Cell)
+		if !ok {
+			continue
+		}
+		res, err := source.RewriteFormula(formula, k.mapRef)
+		if err != nil {
+			k.dropped++
+			if k.dropped <= formulaLogLimit {
+				col, _ := excelize.ColumnNumberToName(c + 1)
+				log.Printf("%s：位置 %s%d，公式 =%s %s，已按计算结果写入", filepath.Base(k.file), col, srcRow, formula, err)
+			}
+			continue
+		}
+		cell.Formula = res
+		rowNew[c] = cell
+		k.kept++
+	}
+}
+
+// mapRef
+// 将引用改写为目标行号
+func (k *formulaKeeper) mapRef(ref *source.Ref) error {
+	switch {
+	case ref.Name != "":
+		return fmt.Errorf("引用了名称 %s", ref.Name)
+	case ref.Sheet != "":
+		return fmt.Errorf("引用了其他工作表 %s", ref.Sheet)
+	case ref.From.Row == 0:
+		return fmt.Errorf("引用了整列 %s", ref)
+	}
+	from, err := k.mapRow(ref.From.Row)
+	if err != nil {
+		return err
+	}
+	if ref.Range {
+		to, err := k.mapRow(ref.To.Row)
+		if err != nil {
+			return err
+		}
+		// 区域同时含行首与数据行，须当前块紧接行首写入，否则将包含其他数据
+		if (ref.From.Row == 1) != (ref.To.Row == 1) && k.first+k.delta != 2 {
+			return fmt.Errorf("引用区域 %s 含行首，合并、拆分后将包含其他数据", ref)
+		}
+		ref.To.Row = to
+	}
+	ref.From.Row = from
+	return nil
+}
+
+func (k *formulaKeeper) mapRow(row int) (int, error) {
+	if row == 1 {
+		return 1, nil
+	}
+	if row < k.first || (k.last > 0 && row > k.last) || (k.end > 0 && row > k.end) {
+		return 0, fmt.Errorf("引用了第%d行，不在连续写入的范围内", row)
+	}
+	return row + k.delta, nil
+}
+
+// Report
+// 输出公式保留情况
+func (k *formulaKeeper) Report() {
+	if k == nil || k.kept+k.dropped == 0 {
+		return
+	}
+	if k.dropped == 0 {
+		fmt.Printf("公式：保留%s\n", color.HiYellowString("%d个", k.kept))
+		return
+	}
+	fmt.Printf("公式：保留%s，%s无法保留，已按计算结果写入\n",
+		color.HiYellowString("%d个", k.kept), color.HiRedString("%d个", k.dropped))
+}
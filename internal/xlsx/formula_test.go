@@ -0,0 +1,134 @@
+package xlsx
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeBook
+// 测试用的 xlsx 数据文件，rows 逐行写入 A 列起，formulas 为单元格公式（不含计算结果）
+func writeBook(t *testing.T, dir string, name string, rows [][]any, formulas map[string]string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		if err := f.SetSheetRow("Sheet1", fmt.Sprintf("A%d", i+1), &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for cell, formula := range formulas {
+		if err := f.SetCellFormula("Sheet1", cell, formula); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, name)
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMergeFormulaKeep(t *testing.T) {
+	dir := t.TempDir()
+	a := writeBook(t, dir, "a.xlsx",
+		[][]any{{"v", "f"}, {1}, {2}, {1}, {3}, {4}},
+		map[string]string{
+			"B2": "A2+A3",   // 块内向后引用
+			"B3": "A3+A4",   // 引用被去重的行
+			"B5": "A5+A2",   // 越过被去重的行向前引用
+			"B6": "A6*$A$1", // 行首不变
+		})
+	b := writeBook(t, dir, "b.xlsx",
+		[][]any{{"v", "f"}, {5}, {6}},
+		map[string]string{
+			"B2": "A2*10", // 跨文件：按新行号改写
+			"B3": "A3+A4", // 越过数据文件末行
+		})
+	tarPath := filepath.Join(dir, "out.xlsx")
+	opts := option.Options{
+		Formula: option.FormulaKeep,
+		Dedup:   option.Dedup{Enabled: true, Keys: []string{"v"}},
+	}
+	if err := MergeXlsx2xlsxV2([]string{a, b}, tarPath, opts, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	out, err := excelize.OpenFile(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	tests := []struct {
+		cell    string
+		value   string // A 列，确认行的对应关系
+		formula string // 无法保留的为空
+	}{
+		{"2", "1", "A2+A3"},
+		{"3", "2", ""},
+		{"4", "3", ""},
+		{"5", "4", "A5*$A$1"},
+		{"6", "5", "A6*10"},
+		{"7", "6", ""},
+	}
+	for _, tt := range tests {
+		value, err := out.GetCellValue("data", "A"+tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formula, err := out.GetCellFormula("data", "B"+tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != tt.value || formula != tt.formula {
+			t.Errorf("第%s行 = %q %q, want %q %q", tt.cell, value, formula, tt.value, tt.formula)
+		}
+	}
+}
+
+// formulaRows
+// 仅提供公式的数据行
+type formulaRows struct {
+	formulas []string
+}
+
+func (r *formulaRows) Next() bool                 { return false }
+func (r *formulaRows) Columns() ([]string, error) { return nil, nil }
+func (r *formulaRows) Close() error               { return nil }
+func (r *formulaRows) Formulas() []string         { return r.formulas }
+
+func TestFormulaKeeper(t *testing.T) {
+	tests := []struct {
+		name    string
+		last    int // 数据文件（拆分份）末行
+		srcRow  int
+		tarRow  int
+		end     int
+		formula string
+		want    string
+	}{
+		{"块内", 0, 5, 10, 0, "A5+A6", "A10+A11"},
+		{"区域含行首", 0, 2, 2, 0, "SUM(A$1:A2)", "SUM(A$1:A2)"},
+		{"区域含行首但块不紧接行首", 0, 5, 10, 0, "SUM(A$1:A5)", ""},
+		{"越过末行", 6, 5, 10, 0, "A7", ""},
+		{"越过续写新表前的行", 0, 5, 10, 6, "A7", ""},
+		{"块首之前", 0, 5, 10, 0, "A4", ""},
+		{"其他工作表", 0, 5, 10, 0, "Sheet2!A5", ""},
+		{"整列", 0, 5, 10, 0, "SUM(A:A)", ""},
+		{"名称", 0, 5, 10, 0, "Rate*A5", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newFormulaKeeper(option.FormulaKeep)
+			k.Reset("a.xlsx", tt.last)
+			row := []any{excelize.Cell{Value: 1.0}}
+			k.Apply(row, &formulaRows{formulas: []string{tt.formula}}, tt.srcRow, tt.tarRow, tt.end)
+			if got := row[0].(excelize.Cell).Formula; got != tt.want {
+				t.Errorf("formula = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package xlsx
+
+import (
+	"log"
+	"path/filepath"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"github.com/xuri/excelize/v2"
+)
+
+// 解析数据格式默认采样的数据行数
+const SampleRows = 1000
+
+// readCellMeta
+// 采样前 sample 个数据行（0 为默认）：xlsx 按样式和类型投票；CSV、xls 无可用样式，按数据推断类型；
+// 已解锁的加密文件读取失败则退回推断
+func readCellMeta(file string, sample int, opts option.Source) (map[int]CellMeta, error) {
+	if sample <= 0 {
+		sample = SampleRows
+	}
+	if !source.HasCellMeta(file) {
+		return inferMeta(file, sample, opts)
+	}
+	meta, err := readXlsxStyleAndType(file, sample, opts)
+	if _, unlocked := opts.Unlocked[file]; err != nil && unlocked {
+		log.Printf("%s：读取加密文件数据格式失败，按数据推断：%v", filepath.Base(file), err)
+		return inferMeta(file, sample, opts)
+	}
+	return meta, err
+}
+
+// ReadCellMeta
+// 解析数据文件各列的数据格式，供其他导出格式（如 JSONL）按列类型转换取值
+func ReadCellMeta(file string, sample int, opts option.Source) (map[int]CellMeta, error) {
+	return readCellMeta(file, sample, opts)
+}
+
+// inferMeta
+// 采样列中非空值全部为数值（不含前导零）则为数值列，全部为 ISO 格式的日期、时间则为日期列，否则为文本列
+func inferMeta(file string, sample int, opts option.Source) (map[int]CellMeta, error) {
+	iter, err := source.Open(file, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	var (
+		cols   int
+		line   int
+		filled = map[int]int{}             // 列中非空值数
+		text   = map[int]int{}             // 列中非数值数
+		dates  = map[int]source.DateKind{} // 日期列的种类，含非日期值则为 DateNone
+	)
+	for line <= sample && iter.Next() {
+		line++
+		row, err := iter.Columns()
+		if err != nil {
+			return nil, err
+		}
+		cols = max(cols, len(row))
+		if line == 1 { // 行首
+			continue
+		}
+		for c, val := range row {
+			if val == "" {
+				continue
+			}
+			kind, seen := dates[c]
+			if !seen || kind != source.DateNone {
+				_, k := source.ParseDate(val)
+				dates[c] = mergeDateKind(kind, k, seen)
+			}
+			filled[c]++
+			if !IsNumber(val) || leadingZero(val) {
+				text[c]++
+			}
+		}
+	}
+	res := make(map[int]CellMeta, cols)
+	for c := range cols {
+		var m CellMeta
+		switch {
+		case filled[c] > 0 && text[c] == 0:
+			m = CellMeta{TypeIdx: excelize.CellTypeNumber, TypeRaw: "n", Votes: filled[c]}
+		case filled[c] > 0 && dates[c] != source.DateNone:
+			m = CellMeta{TypeIdx: excelize.CellTypeNumber, TypeRaw: "n", Date: true,
+				FmtCode: dateFmtCodes[dates[c]], Votes: filled[c]}
+		default: // 一致率为非数值的占比
+			m = CellMeta{TypeIdx: excelize.CellTypeSharedString, TypeRaw: "s", Votes: text[c]}
+		}
+		m.Samples = filled[c]
+		res[c+1] = m
+	}
+	return res, nil
+}
+
+// 推断所得日期列的数字格式
+var dateFmtCodes = map[source.DateKind]string{
+	source.DateOnly:     "yyyy-mm-dd",
+	source.DateWithTime: "yyyy-mm-dd hh:mm:ss",
+	source.TimeOnly:     "hh:mm:ss",
+}
+
+// mergeDateKind
+// 合并列中已有的日期种类与新值的种类：日期与日期时间混合视为日期时间，与时间混合则非日期列
+func mergeDateKind(kind source.DateKind, k source.DateKind, seen bool) source.DateKind {
+	switch {
+	case !seen || kind == k:
+		return k
+	case k == source.DateNone || kind == source.TimeOnly || k == source.TimeOnly:
+		return source.DateNone
+	default:
+		return source.DateWithTime
+	}
+}
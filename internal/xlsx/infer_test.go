@@ -0,0 +1,127 @@
+package xlsx
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/testutil"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestInferMeta(t *testing.T) {
+	src := testutil.WriteFile(t, t.TempDir(), "src.csv", "num,zero,text,date,dt,mixed,empty,big\n"+
+		"1,007,a,2026-01-02,2026-01-02 03:04:05,1,,1e3\n"+
+		"-2.5,001,b,2026-01-03,2026-01-03,x,,12345678901234567890\n"+
+		",,,,,,,\n")
+	meta, err := readCellMeta(src, 0, option.Source{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		col     int
+		typ     excelize.CellType
+		date    bool
+		fmtCode string
+	}{
+		{1, excelize.CellTypeNumber, false, ""},
+		{2, excelize.CellTypeSharedString, false, ""}, // 前导零
+		{3, excelize.CellTypeSharedString, false, ""},
+		{4, excelize.CellTypeNumber, true, "yyyy-mm-dd"},
+		{5, excelize.CellTypeNumber, true, "yyyy-mm-dd hh:mm:ss"}, // 日期与日期时间混合
+		{6, excelize.CellTypeSharedString, false, ""},
+		{7, excelize.CellTypeSharedString, false, ""}, // 全空
+		{8, excelize.CellTypeNumber, false, ""},
+	}
+	for _, tt := range tests {
+		m := meta[tt.col]
+		if m.TypeIdx != tt.typ || m.Date != tt.date || m.FmtCode != tt.fmtCode {
+			t.Errorf("列%d：%+v，期望类型 %d 日期 %v %q", tt.col, m, tt.typ, tt.date, tt.fmtCode)
+		}
+	}
+}
+
+func TestReadXlsxStyleAndType(t *testing.T) {
+	f := excelize.NewFile()
+	num, err := f.NewStyle(&excelize.Style{NumFmt: 4}) // #,##0.00
+	if err != nil {
+		t.Fatal(err)
+	}
+	date, err := f.NewStyle(&excelize.Style{NumFmt: 14})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]any{
+		{"a", "b", "c", "d", "e"},
+		{nil, "x", nil, 1, 1}, // 第2行 A 列空白
+		{1, 2, nil, 2, "y"},   // e 列平票
+		{2, "y", nil, 3, nil},
+		{3, "z", nil, "w", "z"}, // 超出采样
+	}
+	for i, row := range rows {
+		if err := f.SetSheetRow("Sheet1", fmt.Sprintf("A%d", i+1), &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, cell := range []struct {
+		axis  string
+		style int
+	}{{"A3", num}, {"A4", num}, {"A5", num}, {"C2", date}, {"C3", date}} {
+		if err := f.SetCellStyle("Sheet1", cell.axis, cell.axis, cell.style); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := filepath.Join(t.TempDir(), "src.xlsx")
+	if err := f.SaveAs(src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	meta, err := readCellMeta(src, 3, option.Source{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		col     int
+		style   int
+		typ     excelize.CellType
+		date    bool
+		votes   int
+		samples int
+	}{
+		{"首行空白不影响", 1, num, excelize.CellTypeUnset, false, 2, 2},
+		{"多数为文本", 2, 0, excelize.CellTypeSharedString, false, 2, 3},
+		{"整列无数据取空单元格样式", 3, date, excelize.CellTypeUnset, true, 0, 0},
+		{"不计采样以外的行", 4, 0, excelize.CellTypeUnset, false, 3, 3},
+		{"平票取先出现者", 5, 0, excelize.CellTypeUnset, false, 1, 2},
+	}
+	if len(meta) != len(tests) {
+		t.Fatalf("列数 = %d, want %d", len(meta), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := meta[tt.col]
+			if m.StyleId != tt.style || m.TypeIdx != tt.typ || m.Date != tt.date || m.Votes != tt.votes || m.Samples != tt.samples {
+				t.Errorf("%+v，期望样式 %d 类型 %d 日期 %v 得票 %d/%d", m, tt.style, tt.typ, tt.date, tt.votes, tt.samples)
+			}
+		})
+	}
+}
+
+func TestDescribeMeta(t *testing.T) {
+	meta := map[int]CellMeta{
+		1: {StyleId: 2, TypeRaw: "s", Votes: 9, Samples: 10},
+		2: {StyleId: 3, Date: true, Votes: 3, Samples: 3},
+		3: {},
+	}
+	got, err := describeMeta(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "A列 样式 2 类型 s 90%，B列 样式 3 类型 （日期） 100%，C列 样式 0 类型  无数据"
+	if got != want {
+		t.Errorf("describeMeta = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package xlsx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/join"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+)
+
+// JoinXlsx2xlsx
+// 按键列横向拼接左右两表（替代 VLOOKUP），列数据格式分别沿用左右两表
+func JoinXlsx2xlsx(leftPath string, rightPath string, tarPath string, opts option.Options, ctx context.Context) error {
+	opts.Source.RawNumbers = true // 数值读取原始值，沿用数据文件的数字格式写入
+	start := time.Now()
+	fmt.Println("正在解析…")
+
+	// 解析数据格式
+	leftMeta, err := readCellMeta(leftPath, opts.Sample, opts.Source)
+	if err != nil {
+		return err
+	}
+	rightMeta, err := readCellMeta(rightPath, opts.Sample, opts.Source)
+	if err != nil {
+		return err
+	}
+	styles := newStyleTable(false, opts.Source)
+	defer styles.Close()
+	if leftMeta, err = styles.Meta(leftMeta, leftPath); err != nil {
+		return err
+	}
+	if rightMeta, err = styles.Meta(rightMeta, rightPath); err != nil {
+		return err
+	}
+	for i, file := range []string{leftPath, rightPath} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s：%s，%s\n", []string{"左表", "右表"}[i],
+			color.HiYellowString(filepath.Base(file)), util.SizeReadable(info.Size()))
+	}
+
+	joiner, err := join.Open(leftPath, rightPath, opts.Join, opts.Source, ctx)
+	if err != nil {
+		return err
+	}
+	defer joiner.Close()
+
+	// 超出 Excel 最大行数则拒绝拼接，避免写出部分数据后才失败
+	if opts.Overflow == option.OverflowRefuse {
+		fmt.Println("正在统计行数…")
+		rows, err := joiner.Count()
+		if err != nil {
+			return err
+		}
+		if rows+1 > maxRows {
+			return fmt.Errorf("拼接后共%d行（含行首），超出 Excel 最大行数 %d，已拒绝拼接", rows+1, maxRows)
+		}
+	}
+	leftCols := len(joiner.Header) - len(joiner.RightCols)
+	meta := make(map[int]CellMeta, len(joiner.Header))
+	for c := range leftCols {
+		meta[c+1] = leftMeta[c+1]
+	}
+	rightCols := make(map[int]int, len(joiner.RightCols)) // 右表列号 → 导出列号（从1开始）
+	srcCols := make([]int, len(joiner.Header))            // 导出列对应的左表或右表列号（从1开始）
+	for c := range leftCols {
+		srcCols[c] = c + 1
+	}
+	for k, c := range joiner.RightCols {
+		meta[leftCols+k+1] = rightMeta[c+1]
+		rightCols[c+1] = leftCols + k + 1
+		srcCols[leftCols+k] = c + 1
+	}
+	guard := newTextGuard(opts.TextCols)
+	if meta, err = guard.Meta(meta, joiner.Header, styles); err != nil {
+		return err
+	}
+
+	// 沿用左表的列宽、冻结窗格及自动筛选，筛选至左表末列的扩展至右表各列
+	layout, err := readLayout(leftPath, opts.Source)
+	if err != nil {
+		return err
+	}
+	if layout != nil && layout.FilterMax == leftCols {
+		layout.FilterMax = len(joiner.Header)
+	}
+
+	// 使用模板文件，超出 Excel 最大行数时续写至新表或新文件
+	tarFile, err := newSheetWriter(tarPath, opts, styles)
+	if err != nil {
+		return err
+	}
+	tarFile.SetRight(rightPath, rightCols)
+	if err := tarFile.SetLayout(layout); err != nil {
+		tarFile.Close()
+		return err
+	}
+	if err := tarFile.SetHeader(newHeaderRow(joiner.Header, meta), leftPath); err != nil {
+		tarFile.Close()
+		return err
+	}
+
+	fmt.Printf("正在按%s拼接… %s\n", color.HiYellowString(opts.Join.Key), color.HiBlackString("(停止：Ctrl+C)"))
+	dataRows := 0
+	for joiner.Next() {
+		select {
+		case <-ctx.Done():
+			tarFile.Close()
+			return ctx.Err()
+		default:
+		} // 响应 Ctrl+C 打断
+		// 异常值日志记录产生该值的一侧：左表部分取自左表（仅右表有的行，其键值取自右表），右表部分取自右表
+		left, right := joiner.Line(), joiner.RightLine()
+		rowNew, err := newDataRowFrom(joiner.Row(), meta, guard, func(c int) (string, int, int) {
+			if c >= leftCols {
+				return rightPath, right, srcCols[c]
+			}
+			if left == 0 {
+				return rightPath, right, joiner.RightKey + 1
+			}
+			return leftPath, left, srcCols[c]
+		})
+		if err != nil {
+			tarFile.Close()
+			return err
+		}
+		if err := tarFile.AddJoinedRow(rowNew, leftPath, left, right); err != nil {
+			tarFile.Close()
+			return err
+		}
+		dataRows++
+		if dataRows%10000 == 0 {
+			fmt.Printf("已拼接%d行，耗时%s\n", dataRows, util.Cost(start))
+		}
+	}
+	if err := joiner.Err(); err != nil {
+		tarFile.Close()
+		return err
+	}
+	fmt.Println("正在生成…")
+	if err := tarFile.Save(); err != nil {
+		tarFile.Close()
+		return err
+	}
+	joiner.Report()
+	tarFile.notes.Report()
+	tarFile.merges.Report()
+	guard.Report()
+	tarSize := int64(0)
+	for _, path := range tarFile.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		tarSize += info.Size()
+	}
+	fmt.Printf("拼接完成，%s，共%s数据，耗时%s\n",
+		util.SizeReadable(tarSize), color.HiYellowString("%d行", dataRows), util.Cost(start))
+	for _, path := range tarFile.Paths {
+		fmt.Printf("拼接文件：%s%s\n", strings.TrimSuffix(path, filepath.Base(path)),
+			color.HiYellowString(filepath.Base(path)))
+	}
+	return tarFile.protect.report(manifestPath(tarFile.path))
+}
@@ -0,0 +1,114 @@
+package xlsx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/testutil"
+	"github.com/xuri/excelize/v2"
+)
+
+// TestJoinRightNotes
+// 右表的超链接、批注按右表行号及列映射写入拼接结果，键列的无法保留
+func TestJoinRightNotes(t *testing.T) {
+	dir := t.TempDir()
+	left := testutil.WriteFile(t, t.TempDir(), "left.csv", "id,name\n1,a\n2,b\n3,c\n")
+	right := filepath.Join(dir, "right.xlsx")
+	f := excelize.NewFile()
+	rows := [][]any{{"code", "score"}, {2, 80}, {1, 90}}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.SetCellHyperLink("Sheet1", "B2", "https://example.com/", "External"); err != nil {
+		t.Fatal(err)
+	}
+	for _, cell := range []string{"B3", "A3"} {
+		comment := excelize.Comment{Cell: cell, Author: "t", Paragraph: []excelize.RichTextRun{{Text: cell}}}
+		if err := f.AddComment("Sheet1", comment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.SaveAs(right); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tar := filepath.Join(dir, "out.xlsx")
+	opts := option.Options{Join: option.Join{Key: "id", RightKey: "code"}}
+	if err := JoinXlsx2xlsx(left, right, tar, opts, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := readSheets(t, tar)["data"]
+	want := [][]string{{"id", "name", "score"}, {"1", "a", "90"}, {"2", "b", "80"}, {"3", "c"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+	out, err := excelize.OpenFile(tar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	comments, err := out.GetComments("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cells []string
+	for _, c := range comments {
+		cells = append(cells, c.Cell)
+	}
+	if !slices.Equal(cells, []string{"C2"}) { // 右表 B3 → 第2行 C 列，键列 A3 无法保留
+		t.Errorf("comments = %v, want [C2]", cells)
+	}
+	if ok, target, err := out.GetCellHyperLink("data", "C3"); err != nil || !ok || target != "https://example.com/" {
+		t.Errorf("C3 hyperlink = %v %q %v", ok, target, err)
+	}
+}
+
+func TestJoinRefuse(t *testing.T) {
+	defer func(n int) { maxRows = n }(maxRows)
+	maxRows = 4
+	left := testutil.WriteFile(t, t.TempDir(), "left.csv", "id,name\n1,a\n2,b\n3,c\n")
+	right := testutil.WriteFile(t, t.TempDir(), "right.csv", "id,score\n1,90\n1,95\n3,70\n")
+	tests := []struct {
+		name    string
+		typ     option.JoinType
+		wantErr bool
+	}{
+		{"left 一行匹配多行后超出", option.JoinLeft, true}, // 4 行数据
+		{"inner 未超出", option.JoinInner, false},    // 3 行数据
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tarPath := filepath.Join(t.TempDir(), "out.xlsx")
+			opts := option.Options{
+				Overflow: option.OverflowRefuse,
+				Join:     option.Join{Key: "id", Type: tt.typ},
+			}
+			err := JoinXlsx2xlsx(left, right, tarPath, opts, context.Background())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "已拒绝拼接") {
+					t.Errorf("err = %v, want 已拒绝拼接", err)
+				}
+				if _, err := os.Stat(tarPath); !os.IsNotExist(err) {
+					t.Errorf("拒绝拼接时不应生成导出文件：%v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := [][]string{{"id", "name", "score"}, {"1", "a", "90"}, {"1", "a", "95"}, {"3", "c", "70"}}
+			if got := readSheets(t, tarPath)["data"]; !slices.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("rows = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package xlsx
+
+import (
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"github.com/xuri/excelize/v2"
+)
+
+// readLayout
+// 读取数据文件第一张工作表的版式，CSV、xls 无版式
+func readLayout(file string, opts option.Source) (*source.Layout, error) {
+	if !source.HasCellMeta(file) {
+		return nil, nil
+	}
+	wb, err := source.OpenWorkbook(file, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer wb.Close()
+	return wb.Layout()
+}
+
+// setLayout
+// 设置列宽、冻结窗格，须在写入首行前调用
+func setLayout(sw *excelize.StreamWriter, layout *source.Layout) error {
+	if layout == nil {
+		return nil
+	}
+	for _, col := range layout.Cols {
+		if col.Min > excelize.MaxColumns {
+			continue
+		}
+		if err := sw.SetColWidth(col.Min, min(col.Max, excelize.MaxColumns), min(col.Width, excelize.MaxColumnWidth)); err != nil {
+			return err
+		}
+	}
+	if layout.FreezeCol == 0 && layout.FreezeRow == 0 {
+		return nil
+	}
+	cell, err := excelize.CoordinatesToCellName(layout.FreezeCol+1, layout.FreezeRow+1)
+	if err != nil {
+		return err
+	}
+	pane := "bottomRight"
+	if layout.FreezeCol == 0 {
+		pane = "bottomLeft"
+	} else if layout.FreezeRow == 0 {
+		pane = "topRight"
+	}
+	return sw.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		XSplit:      layout.FreezeCol,
+		YSplit:      layout.FreezeRow,
+		TopLeftCell: cell,
+		ActivePane:  pane,
+		Selection:   []excelize.Selection{{SQRef: cell, ActiveCell: cell, Pane: pane}},
+	})
+}
+
+// setFilter
+// 按数据文件的筛选列设置自动筛选，范围调整为行首至末行，须在 Flush 前调用
+func setFilter(f *excelize.File, sheet string, layout *source.Layout, rows int) error {
+	if layout == nil || layout.FilterMin == 0 {
+		return nil
+	}
+	from, err := excelize.CoordinatesToCellName(layout.FilterMin, 1)
+	if err != nil {
+		return err
+	}
+	to, err := excelize.CoordinatesToCellName(layout.FilterMax, max(rows, 1))
+	if err != nil {
+		return err
+	}
+	return f.AutoFilter(sheet, from+":"+to, nil)
+}
@@ -0,0 +1,85 @@
+package xlsx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+// filterRef
+// 工作表自动筛选的范围，无筛选为空
+func filterRef(t *testing.T, f *excelize.File, sheet string) string {
+	t.Helper()
+	for _, dn := range f.GetDefinedName() {
+		if dn.Name == "_xlnm._FilterDatabase" && dn.Scope == sheet {
+			return dn.RefersTo
+		}
+	}
+	return ""
+}
+
+func TestSplitLayout(t *testing.T) {
+	f := excelize.NewFile()
+	rows := [][]any{{"a", "b", "c"}, {1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	for i, row := range rows {
+		axis, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", axis, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.SetColWidth("Sheet1", "B", "B", 30); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetPanes("Sheet1", &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AutoFilter("Sheet1", "A1:B4", nil); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(t.TempDir(), "src.xlsx")
+	if err := f.SaveAs(src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tarDir := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(tarDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SplitXlsx2xlsxByLine(src, tarDir, 2, option.Options{}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		filter string // 按各份的数据行数调整
+	}{
+		{"out-1.xlsx", "'data'!$A$1:$B$3"},
+		{"out-2.xlsx", "'data'!$A$1:$B$2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := excelize.OpenFile(filepath.Join(tarDir, tt.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			if width, err := out.GetColWidth("data", "B"); err != nil || width != 30 {
+				t.Errorf("B列宽 = %v, %v; want 30", width, err)
+			}
+			panes, err := out.GetPanes("data")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !panes.Freeze || panes.XSplit != 0 || panes.YSplit != 1 || panes.TopLeftCell != "A2" {
+				t.Errorf("panes = %+v, want 冻结行首", panes)
+			}
+			if got := filterRef(t, out, "data"); got != tt.filter {
+				t.Errorf("筛选范围 = %q, want %q", got, tt.filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package xlsx
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"github.com/fatih/color"
+	"github.com/xuri/excelize/v2"
+)
+
+// openMerge
+// 已写入首行、尚未写完的合并区域
+type openMerge struct {
+	rng   source.CellRange
+	sw    *excelize.StreamWriter
+	delta int // 目标行号 - 源行号
+	last  int // 已写入的末行（源行号）
+}
+
+// mergeCursor
+// 数据文件的合并区域及写入进度，数据行按行号递增读取
+type mergeCursor struct {
+	ranges []source.CellRange
+	next   int // 下一个尚未开始的区域
+	row    int // 上次写入的行号，行号变小说明重新读取（同一文件重复合并）
+	open   []openMerge
+}
+
+// mergeKeeper
+// 保留合并单元格：区域各行连续写入同一表时按新行号合并，跨行首、跨拆分份、续写新表或中间有行被去重的区域无法保留；
+// 仅含行首的区域在每个导出表中重复
+type mergeKeeper struct {
+	cursors map[string]*mergeCursor
+	opts    option.Source
+	header  []source.CellRange
+	kept    int
+	dropped int
+}
+
+func newMergeKeeper(policy option.Merged, opts option.Source) *mergeKeeper {
+	if policy != option.MergedKeep {
+		return nil
+	}
+	return &mergeKeeper{cursors: make(map[string]*mergeCursor), opts: opts}
+}
+
+func (k *mergeKeeper) cursor(file string) (*mergeCursor, error) {
+	if c, ok := k.cursors[file]; ok {
+		return c, nil
+	}
+	c := &mergeCursor{}
+	if source.HasCellMeta(file) {
+		wb, err := source.OpenWorkbook(file, k.opts)
+		if err != nil {
+			return nil, err
+		}
+		c.ranges, err = wb.MergedCells()
+		wb.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	k.cursors[file] = c
+	return c, nil
+}
+
+// SetHeader
+// 记录行首所在的数据文件，读取仅含行首的合并区域
+func (k *mergeKeeper) SetHeader(file string) error {
+	if k == nil {
+		return nil
+	}
+	c, err := k.cursor(file)
+	if err != nil {
+		return err
+	}
+	k.header = nil
+	for _, rng := range c.ranges {
+		if rng.Top == 1 && rng.Bottom == 1 {
+			k.header = append(k.header, rng)
+		}
+	}
+	return nil
+}
+
+// Header
+// 合并行首的区域
+func (k *mergeKeeper) Header(sw *excelize.StreamWriter) error {
+	if k == nil {
+		return nil
+	}
+	for _, rng := range k.header {
+		if err := k.merge(sw, rng, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply
+// 数据文件第 srcRow 行已写入 sw 的第 tarRow 行：开始以该行为首行的区域，写完末行的区域即合并；
+// srcRow 为 0 表示非数据文件的行（拼接仅右表有的行）
+func (k *mergeKeeper) Apply(sw *excelize.StreamWriter, file string, srcRow int, tarRow int) error {
+	if k == nil || srcRow <= 0 {
+		return nil
+	}
+	c, err := k.cursor(file)
+	if err != nil {
+		return err
+	}
+	if srcRow < c.row {
+		k.dropAll(file, c)
+		c.next = 0
+	}
+	c.row = srcRow
+	open := c.open[:0]
+	for _, m := range c.open {
+		if srcRow > m.last+1 || m.sw != sw || tarRow-srcRow != m.delta {
+			k.drop(file, m.rng, "各行未连续写入同一表")
+			continue
+		}
+		m.last = srcRow
+		if srcRow == m.rng.Bottom {
+			if err := k.merge(sw, m.rng, m.delta); err != nil {
+				return err
+			}
+			continue
+		}
+		open = append(open, m)
+	}
+	c.open = open
+	for c.next < len(c.ranges) && c.ranges[c.next].Top <= srcRow {
+		rng := c.ranges[c.next]
+		c.next++
+		switch {
+		case rng.Top == 1 && rng.Bottom == 1: // 行首，另行处理
+		case rng.Top == 1:
+			k.drop(file, rng, "同时含行首与数据行")
+		case rng.Top < srcRow:
+			k.drop(file, rng, "首行未写入（已去重）")
+		case rng.Bottom == rng.Top:
+			if err := k.merge(sw, rng, tarRow-srcRow); err != nil {
+				return err
+			}
+		default:
+			c.open = append(c.open, openMerge{rng: rng, sw: sw, delta: tarRow - srcRow, last: srcRow})
+		}
+	}
+	return nil
+}
+
+func (k *mergeKeeper) merge(sw *excelize.StreamWriter, rng source.CellRange, delta int) error {
+	from, err := excelize.CoordinatesToCellName(rng.Left, rng.Top+delta)
+	if err != nil {
+		return err
+	}
+	to, err := excelize.CoordinatesToCellName(rng.Right, rng.Bottom+delta)
+	if err != nil {
+		return err
+	}
+	if err := sw.MergeCell(from, to); err != nil {
+		return err
+	}
+	k.kept++
+	return nil
+}
+
+func (k *mergeKeeper) drop(file string, rng source.CellRange, reason string) {
+	k.dropped++
+	if k.dropped <= noteLogLimit {
+		from, _ := excelize.CoordinatesToCellName(rng.Left, rng.Top)
+		to, _ := excelize.CoordinatesToCellName(rng.Right, rng.Bottom)
+		log.Printf("%s：合并单元格 %s:%s %s，已拆分", filepath.Base(file), from, to, reason)
+	}
+}
+
+// dropAll
+// 未写完的区域均无法保留
+func (k *mergeKeeper) dropAll(file string, c *mergeCursor) {
+	for _, m := range c.open {
+		k.drop(file, m.rng, "末行未写入")
+	}
+	c.open = nil
+}
+
+// Report
+// 输出合并单元格保留情况
+func (k *mergeKeeper) Report() {
+	if k == nil {
+		return
+	}
+	for file, c := range k.cursors {
+		k.dropAll(file, c)
+	}
+	if k.kept+k.dropped == 0 {
+		return
+	}
+	if k.dropped == 0 {
+		fmt.Printf("合并单元格：保留%s\n", color.HiYellowString("%d个", k.kept))
+		return
+	}
+	fmt.Printf("合并单元格：保留%s，%s无法保留，已拆分\n",
+		color.HiYellowString("%d个", k.kept), color.HiRedString("%d个", k.dropped))
+}
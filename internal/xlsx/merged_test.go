@@ -0,0 +1,76 @@
+package xlsx
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestMergeMergedPolicy(t *testing.T) {
+	dir := t.TempDir()
+	f := excelize.NewFile()
+	for i, row := range [][]any{{"a", "b"}, {"k", 1}, {nil, 2}, {"m", 3}} {
+		axis, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", axis, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.MergeCell("Sheet1", "A2", "A3"); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "src.xlsx")
+	if err := f.SaveAs(src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dedup := option.Dedup{Enabled: true, Keys: []string{"a"}}
+	tests := []struct {
+		name   string
+		opts   option.Options
+		rows   [][]string
+		merged []string
+	}{
+		{"none", option.Options{Dedup: dedup},
+			[][]string{{"a", "b"}, {"k", "1"}, {"", "2"}, {"m", "3"}}, nil},
+		{"fill 按填充后的值去重", option.Options{Dedup: dedup, Merged: option.MergedFill, Source: option.Source{FillMerged: true}},
+			[][]string{{"a", "b"}, {"k", "1"}, {"m", "3"}}, nil},
+		{"keep", option.Options{Merged: option.MergedKeep},
+			[][]string{{"a", "b"}, {"k", "1"}, {"", "2"}, {"m", "3"}}, []string{"A2:A3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tarPath := filepath.Join(t.TempDir(), "out.xlsx")
+			if err := MergeXlsx2xlsxV2([]string{src}, tarPath, tt.opts, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			out, err := excelize.OpenFile(tarPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			rows, err := out.GetRows("data")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.EqualFunc(rows, tt.rows, slices.Equal) {
+				t.Errorf("rows = %q, want %q", rows, tt.rows)
+			}
+			cells, err := out.GetMergeCells("data")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var merged []string
+			for _, c := range cells {
+				merged = append(merged, c.GetStartAxis()+":"+c.GetEndAxis())
+			}
+			if !slices.Equal(merged, tt.merged) {
+				t.Errorf("merged = %q, want %q", merged, tt.merged)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"github.com/fatih/color"
+	"github.com/xuri/excelize/v2"
+)
+
+// 无法保留的超链接最多逐条输出的日志数
+const noteLogLimit = 10
+
+// noteCursor
+// 数据文件的超链接、批注及下一待写入的位置，数据行按行号递增读取
+type noteCursor struct {
+	notes   *source.Notes
+	link    int
+	comment int
+	row     int // 上次写入的行号，行号变小说明重新读取（同一文件重复合并）
+}
+
+// noteCopier
+// 按新行号写入数据文件的超链接及批注，各数据文件分别记录写入位置（有序合并交错写入）；
+// 行首的超链接、批注取自首个写入行首的数据文件，续写新表、拆分的每份文件均重复；
+// 拼接时右表各行按键值查找、不按行号递增，其超链接、批注按列映射写入
+type noteCopier struct {
+	cursors  map[string]*noteCursor
+	opts     option.Source
+	header   *source.Notes
+	hfile    string // 行首所在的数据文件
+	rfile    string // 拼接的右表
+	rcols    map[int]int
+	links    int
+	comments int
+	dropped  int
+}
+
+func newNoteCopier(opts option.Source) *noteCopier {
+	return &noteCopier{cursors: make(map[string]*noteCursor), opts: opts}
+}
+
+// cursor
+// 首次使用时读取数据文件的超链接、批注，CSV、xls 无
+func (n *noteCopier) cursor(file string) (*noteCursor, error) {
+	if c, ok := n.cursors[file]; ok {
+		return c, nil
+	}
+	c := &noteCursor{notes: &source.Notes{}}
+	if source.HasCellMeta(file) {
+		wb, err := source.OpenWorkbook(file, n.opts)
+		if err != nil {
+			return nil, err
+		}
+		c.notes, err = wb.Notes()
+		wb.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	n.cursors[file] = c
+	return c, nil
+}
+
+// SetHeader
+// 记录行首所在的数据文件，读取其行首的超链接、批注
+func (n *noteCopier) SetHeader(file string) error {
+	c, err := n.cursor(file)
+	if err != nil {
+		return err
+	}
+	n.header, n.hfile = &source.Notes{}, file
+	for _, link := range c.notes.Links {
+		if link.Row == 1 {
+			n.header.Links = append(n.header.Links, link)
+		}
+	}
+	for _, cmt := range c.notes.Comments {
+		if cmt.Row == 1 {
+			n.header.Comments = append(n.header.Comments, cmt)
+		}
+	}
+	return nil
+}
+
+// SetRight
+// 拼接时记录右表及其列映射（右表列号 → 导出列号，从1开始），未映射的列（键列）的超链接、批注无法保留
+func (n *noteCopier) SetRight(file string, cols map[int]int) {
+	n.rfile, n.rcols = file, cols
+}
+
+// Header
+// 写入行首的超链接、批注
+func (n *noteCopier) Header(f *excelize.File, sheet string) error {
+	if n.header != nil {
+		if err := n.write(f, sheet, n.hfile, n.header.Links, n.header.Comments, 1, nil); err != nil {
+			return err
+		}
+	}
+	if n.rfile == "" {
+		return nil
+	}
+	return n.ApplyRight(f, sheet, 1, 1)
+}
+
+// Apply
+// 写入数据文件第 srcRow 行的超链接、批注至导出表第 tarRow 行；同一行可重复写入（拼接一对多）
+func (n *noteCopier) Apply(f *excelize.File, sheet string, file string, srcRow int, tarRow int) error {
+	c, err := n.cursor(file)
+	if err != nil {
+		return err
+	}
+	if srcRow < c.row {
+		c.link, c.comment = 0, 0
+	}
+	c.row = srcRow
+	links, comments := c.notes.Links, c.notes.Comments
+	for c.link < len(links) && links[c.link].Row < srcRow {
+		c.link++
+	}
+	for c.comment < len(comments) && comments[c.comment].Row < srcRow {
+		c.comment++
+	}
+	i, j := c.link, c.comment
+	for i < len(links) && links[i].Row == srcRow {
+		i++
+	}
+	for j < len(comments) && comments[j].Row == srcRow {
+		j++
+	}
+	if i == c.link && j == c.comment {
+		return nil
+	}
+	return n.write(f, sheet, file, links[c.link:i], comments[c.comment:j], tarRow, nil)
+}
+
+// ApplyRight
+// 写入右表第 srcRow 行的超链接、批注至导出表第 tarRow 行，列按映射改写
+func (n *noteCopier) ApplyRight(f *excelize.File, sheet string, srcRow int, tarRow int) error {
+	c, err := n.cursor(n.rfile)
+	if err != nil {
+		return err
+	}
+	links, comments := c.notes.Links, c.notes.Comments
+	i := sort.Search(len(links), func(k int) bool { return links[k].Row >= srcRow })
+	li := i
+	for li < len(links) && links[li].Row == srcRow {
+		li++
+	}
+	j := sort.Search(len(comments), func(k int) bool { return comments[k].Row >= srcRow })
+	cj := j
+	for cj < len(comments) && comments[cj].Row == srcRow {
+		cj++
+	}
+	if i == li && j == cj {
+		return nil
+	}
+	return n.write(f, sheet, n.rfile, links[i:li], comments[j:cj], tarRow, n.rcols)
+}
+
+// write
+// cols 非 nil 时按其改写列号，不在其中的列无法保留
+func (n *noteCopier) write(f *excelize.File, sheet string, file string, links []source.Hyperlink, comments []source.Comment, row int, cols map[int]int) error {
+	for _, link := range links {
+		col := link.Col
+		if cols != nil {
+			var ok bool
+			if col, ok = cols[link.Col]; !ok {
+				n.drop(file, link.Col, link.Row, "超链接", "所在列未拼接")
+				continue
+			}
+		}
+		cell, err := excelize.CoordinatesToCellName(col, row)
+		if err != nil {
+			return err
+		}
+		if link.Target == "" {
+			n.drop(file, link.Col, link.Row, "超链接", fmt.Sprintf("指向工作簿内位置 %s", link.Location))
+			continue
+		}
+		var opts excelize.HyperlinkOpts
+		if link.Display != "" {
+			opts.Display = &link.Display
+		}
+		if link.Tooltip != "" {
+			opts.Tooltip = &link.Tooltip
+		}
+		if err := f.SetCellHyperLink(sheet, cell, link.Target, "External", opts); err != nil {
+			if errors.Is(err, excelize.ErrTotalSheetHyperlinks) {
+				n.drop(file, link.Col, link.Row, "超链接", "超出单表超链接数上限")
+				continue
+			}
+			return err
+		}
+		n.links++
+	}
+	for _, cmt := range comments {
+		col := cmt.Col
+		if cols != nil {
+			var ok bool
+			if col, ok = cols[cmt.Col]; !ok {
+				n.drop(file, cmt.Col, cmt.Row, "批注", "所在列未拼接")
+				continue
+			}
+		}
+		cell, err := excelize.CoordinatesToCellName(col, row)
+		if err != nil {
+			return err
+		}
+		runs := make([]excelize.RichTextRun, len(cmt.Runs))
+		for k, run := range cmt.Runs {
+			runs[k].Text = run.Text
+			if run.Bold {
+				runs[k].Font = &excelize.Font{Bold: true, Size: 9}
+			}
+		}
+		if err := f.AddComment(sheet, excelize.Comment{Cell: cell, Author: cmt.Author, Paragraph: runs}); err != nil {
+			return err
+		}
+		n.comments++
+	}
+	return nil
+}
+
+func (n *noteCopier) drop(file string, col int, row int, kind string, reason string) {
+	n.dropped++
+	if n.dropped <= noteLogLimit {
+		cell, _ := excelize.CoordinatesToCellName(col, row)
+		log.Printf("%s：位置 %s，%s%s，已忽略", filepath.Base(file), cell, kind, reason)
+	}
+}
+
+// Report
+// 输出超链接、批注保留情况
+func (n *noteCopier) Report() {
+	if n.links+n.comments+n.dropped == 0 {
+		return
+	}
+	if n.dropped == 0 {
+		fmt.Printf("超链接、批注：保留%s超链接，%s批注\n",
+			color.HiYellowString("%d个", n.links), color.HiYellowString("%d个", n.comments))
+		return
+	}
+	fmt.Printf("超链接、批注：保留%s超链接，%s批注，%s无法保留\n", color.HiYellowString("%d个", n.links),
+		color.HiYellowString("%d个", n.comments), color.HiRedString("%d个", n.dropped))
+}
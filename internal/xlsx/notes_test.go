@@ -0,0 +1,156 @@
+package xlsx
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeNotesBook
+// 测试用的含超链接、批注的 xlsx 数据文件，links 为单元格 -> 外部地址（# 开头为工作簿内位置）
+func writeNotesBook(t *testing.T, dir string, name string, rows [][]any, links map[string]string, comments map[string]string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		axis, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", axis, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for cell, link := range links {
+		linkType := "External"
+		if link[0] == '#' {
+			link, linkType = link[1:], "Location"
+		}
+		if err := f.SetCellHyperLink("Sheet1", cell, link, linkType); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for cell, text := range comments {
+		err := f.AddComment("Sheet1", excelize.Comment{Cell: cell, Author: "rev", Paragraph: []excelize.RichTextRun{
+			{Text: "rev:", Font: &excelize.Font{Bold: true}}, {Text: text},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, name)
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// notesOf
+// 导出表的超链接（单元格 -> 地址）及批注（单元格 -> 文本）
+func notesOf(t *testing.T, path string) (map[string]string, map[string]string) {
+	t.Helper()
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	links := make(map[string]string)
+	for row := 1; row <= 10; row++ {
+		for col := 1; col <= 3; col++ {
+			cell, _ := excelize.CoordinatesToCellName(col, row)
+			ok, target, err := f.GetCellHyperLink("data", cell)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				links[cell] = target
+			}
+		}
+	}
+	comments := make(map[string]string)
+	cmts, err := f.GetComments("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cmts {
+		var text string
+		for _, run := range c.Paragraph {
+			text += run.Text
+		}
+		comments[c.Cell] = text
+	}
+	return links, comments
+}
+
+func TestSplitNotes(t *testing.T) {
+	src := writeNotesBook(t, t.TempDir(), "src.xlsx",
+		[][]any{{"k", "v"}, {1, "a"}, {2, "b"}, {3, "c"}},
+		map[string]string{"A2": "https://t/1", "A3": "#Sheet1!A1"}, // 工作簿内位置无法保留
+		map[string]string{"B1": "列说明", "B4": "待核对"})
+	tarDir := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(tarDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SplitXlsx2xlsxByLine(src, tarDir, 2, option.Options{}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		links    map[string]string
+		comments map[string]string // 行首批注每份重复
+	}{
+		{"out-1.xlsx", map[string]string{"A2": "https://t/1"}, map[string]string{"B1": "rev:列说明"}},
+		{"out-2.xlsx", map[string]string{}, map[string]string{"B1": "rev:列说明", "B2": "rev:待核对"}},
+	}
+	for _, tt := range tests {
+		links, comments := notesOf(t, filepath.Join(tarDir, tt.name))
+		if !maps.Equal(links, tt.links) {
+			t.Errorf("%s：links = %v, want %v", tt.name, links, tt.links)
+		}
+		if !maps.Equal(comments, tt.comments) {
+			t.Errorf("%s：comments = %v, want %v", tt.name, comments, tt.comments)
+		}
+	}
+}
+
+func TestMergeNotes(t *testing.T) {
+	dir := t.TempDir()
+	a := writeNotesBook(t, dir, "a.xlsx",
+		[][]any{{"k", "v"}, {1, "a"}, {2, "b"}, {3, "c"}},
+		map[string]string{"A4": "https://t/3"},
+		map[string]string{"B1": "列说明"})
+	b := writeNotesBook(t, dir, "b.xlsx",
+		[][]any{{"k", "v"}, {2, "x"}, {4, "d"}},
+		map[string]string{"A2": "https://t/2", "A3": "https://t/4"}, // 第2行去重时丢弃
+		map[string]string{"B1": "另一说明", "B3": "新增"})                 // 行首取自首个数据文件
+	tests := []struct {
+		name     string
+		dedup    option.Dedup
+		links    map[string]string
+		comments map[string]string
+	}{
+		{"不去重", option.Dedup{},
+			map[string]string{"A4": "https://t/3", "A5": "https://t/2", "A6": "https://t/4"},
+			map[string]string{"B1": "rev:列说明", "B6": "rev:新增"}},
+		{"去重", option.Dedup{Enabled: true, Keys: []string{"k"}},
+			map[string]string{"A4": "https://t/3", "A5": "https://t/4"},
+			map[string]string{"B1": "rev:列说明", "B5": "rev:新增"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tarPath := filepath.Join(t.TempDir(), "out.xlsx")
+			if err := MergeXlsx2xlsxV2([]string{a, b}, tarPath, option.Options{Dedup: tt.dedup}, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			links, comments := notesOf(t, tarPath)
+			if !maps.Equal(links, tt.links) {
+				t.Errorf("links = %v, want %v", links, tt.links)
+			}
+			if !maps.Equal(comments, tt.comments) {
+				t.Errorf("comments = %v, want %v", comments, tt.comments)
+			}
+		})
+	}
+}
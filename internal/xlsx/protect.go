@@ -0,0 +1,111 @@
+package xlsx
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/fatih/color"
+	"github.com/xuri/excelize/v2"
+)
+
+// 随机密码字符，去除易混淆的 0O1lI
+const passwordChars = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+
+// 随机密码长度
+const passwordLen = 12
+
+// protector
+// 为导出文件分配密码并加密保存，随机密码记入清单
+type protector struct {
+	opts      option.Protect
+	passwords [][2]string // 文件、密码
+}
+
+func newProtector(opts option.Protect) *protector {
+	return &protector{opts: opts}
+}
+
+// randomPassword
+// 生成随机密码
+func randomPassword() (string, error) {
+	var b strings.Builder
+	n := big.NewInt(int64(len(passwordChars)))
+	for range passwordLen {
+		i, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(passwordChars[i.Int64()])
+	}
+	return b.String(), nil
+}
+
+// saveAs
+// 保存文件，启用加密时使用分配的密码
+// 注意：加密须在内存中完成，导出文件越大内存占用越高
+func (p *protector) saveAs(f *excelize.File, path string) error {
+	if p == nil || !p.opts.Enabled() {
+		return f.SaveAs(path)
+	}
+	password := p.opts.Password
+	if p.opts.Random {
+		var err error
+		if password, err = randomPassword(); err != nil {
+			return err
+		}
+	}
+	if err := f.SaveAs(path, excelize.Options{Password: password}); err != nil {
+		return err
+	}
+	p.passwords = append(p.passwords, [2]string{filepath.Base(path), password})
+	return nil
+}
+
+// manifestPath
+// 密码清单与导出文件（夹）同级存放：xxx-passwords.csv
+func manifestPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "-passwords.csv"
+}
+
+// report
+// 输出加密情况，随机密码写入清单（CSV，UTF-8 BOM）；清单含明文密码，仅当前用户可读写
+func (p *protector) report(path string) error {
+	if p == nil || !p.opts.Enabled() || len(p.passwords) == 0 {
+		return nil
+	}
+	if !p.opts.Random {
+		fmt.Printf("已加密：%s文件使用同一密码\n", color.HiYellowString("%d个", len(p.passwords)))
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteString("\xEF\xBB\xBF"); err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"文件", "密码"}); err != nil {
+		return err
+	}
+	for _, item := range p.passwords {
+		if err := w.Write(item[:]); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	fmt.Printf("已加密：%s文件各用独立密码\n", color.HiYellowString("%d个", len(p.passwords)))
+	fmt.Printf("密码清单：%s%s %s\n", strings.TrimSuffix(path, filepath.Base(path)),
+		color.HiYellowString(filepath.Base(path)), color.HiRedString("(请与数据文件分开发送)"))
+	return nil
+}
@@ -0,0 +1,146 @@
+package xlsx
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/testutil"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestRandomPassword(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 20 {
+		p, err := randomPassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p) != passwordLen {
+			t.Errorf("len(%q) = %d, want %d", p, len(p), passwordLen)
+		}
+		if i := strings.IndexFunc(p, func(r rune) bool { return !strings.ContainsRune(passwordChars, r) }); i >= 0 {
+			t.Errorf("%q 含易混淆字符 %q", p, p[i])
+		}
+		if seen[p] {
+			t.Errorf("随机密码重复：%q", p)
+		}
+		seen[p] = true
+	}
+}
+
+func TestManifestPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join("out", "a-merge.xlsx"), filepath.Join("out", "a-merge-passwords.csv")},
+		{filepath.Join("out", "a.b.xlsx"), filepath.Join("out", "a.b-passwords.csv")},
+	}
+	for _, tt := range tests {
+		if got := manifestPath(tt.path); got != tt.want {
+			t.Errorf("manifestPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// readManifest
+// 读取密码清单，文件名 -> 密码
+func readManifest(t *testing.T, path string) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, ok := strings.CutPrefix(string(data), "\xEF\xBB\xBF")
+	if !ok {
+		t.Error("密码清单缺少 UTF-8 BOM")
+	}
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 || !slices.Equal(records[0], []string{"文件", "密码"}) {
+		t.Fatalf("密码清单行首 = %q", records)
+	}
+	res := make(map[string]string)
+	for _, rec := range records[1:] {
+		res[rec[0]] = rec[1]
+	}
+	return res
+}
+
+func TestSplitProtect(t *testing.T) {
+	src := testutil.WriteFile(t, t.TempDir(), "src.csv", "h\n1\n2\n3\n")
+	want := map[string][][]string{
+		"out-1.xlsx": {{"h"}, {"1"}, {"2"}},
+		"out-2.xlsx": {{"h"}, {"3"}},
+	}
+	tests := []struct {
+		name     string
+		protect  option.Protect
+		manifest bool
+	}{
+		{"不加密", option.Protect{}, false},
+		{"同一密码", option.Protect{Password: "secret"}, false},
+		{"随机密码", option.Protect{Random: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tarDir := filepath.Join(t.TempDir(), "out")
+			if err := os.MkdirAll(tarDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			err := SplitXlsx2xlsxByLine(src, tarDir, 2, option.Options{Protect: tt.protect}, context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			manifest := tarDir + "-passwords.csv"
+			_, err = os.Stat(manifest)
+			if tt.manifest != (err == nil) {
+				t.Fatalf("密码清单存在 = %v, want %v", err == nil, tt.manifest)
+			}
+			if info, err := os.Stat(manifest); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+				t.Errorf("密码清单权限 = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+			}
+			var passwords map[string]string
+			if tt.manifest {
+				passwords = readManifest(t, manifest)
+				if len(passwords) != len(want) || passwords["out-1.xlsx"] == passwords["out-2.xlsx"] {
+					t.Errorf("每份拆分文件应使用独立密码：%q", passwords)
+				}
+			}
+			for name, rows := range want {
+				path := filepath.Join(tarDir, name)
+				password := tt.protect.Password
+				if tt.manifest {
+					password = passwords[name]
+				}
+				if password != "" {
+					if f, err := excelize.OpenFile(path); err == nil {
+						f.Close()
+						t.Errorf("%s：未加密", name)
+					}
+				}
+				f, err := excelize.OpenFile(path, excelize.Options{Password: password})
+				if err != nil {
+					t.Fatalf("%s：%v", name, err)
+				}
+				got, err := f.GetRows("data")
+				f.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.EqualFunc(got, rows, slices.Equal) {
+					t.Errorf("%s：rows = %q, want %q", name, got, rows)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package xlsx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/dedup"
+	"gitee.com/nguaduot/split-xlsx-go/internal/kmerge"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+)
+
+// MergeXlsx2xlsxSorted
+// 有序合并：各数据文件已按键列排序，并发流式读取并按键列交错写入，合并结果整体有序
+func MergeXlsx2xlsxSorted(srcPaths []string, tarPath string, opts option.Options, ctx context.Context) error {
+	opts.Source.RawNumbers = true // 数值读取原始值，沿用数据文件的数字格式写入
+	start := time.Now()
+	fmt.Println("正在解析…")
+
+	// 超出 Excel 最大行数则拒绝合并，避免生成无法打开的文件；去重时按去除重复行后的行数判断
+	if opts.Overflow == option.OverflowRefuse && !opts.Dedup.Enabled {
+		if err := refuseOverflow(srcPaths, opts.Source, nil); err != nil {
+			return err
+		}
+	}
+
+	srcSizes := make([]int64, len(srcPaths))
+	for i, file := range srcPaths {
+		f, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		srcSizes[i] = f.Size()
+	}
+
+	// 解析数据格式
+	styles := newStyleTable(opts.CellStyle, opts.Source)
+	defer styles.Close()
+	meta, metaFile, err := readMergeMeta(srcPaths, srcSizes, opts.Sample, opts.Source, styles)
+	if err != nil {
+		return err
+	}
+
+	// 去重需预先扫描全部数据文件
+	var filter *dedup.Filter
+	if opts.Dedup.Enabled {
+		f, err := dedup.Scan(srcPaths, opts.Dedup, opts.Source, ctx)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		filter = f
+		if opts.Overflow == option.OverflowRefuse {
+			if err := refuseOverflow(srcPaths, opts.Source, filter); err != nil {
+				return err
+			}
+		}
+	}
+
+	merger, err := kmerge.Open(srcPaths, opts.Sort, opts.Source, ctx)
+	if err != nil {
+		return err
+	}
+	defer merger.Close()
+
+	// 沿用数据文件的列宽、冻结窗格及自动筛选
+	layout, err := readLayout(metaFile, opts.Source)
+	if err != nil {
+		return err
+	}
+
+	// 使用模板文件，超出 Excel 最大行数时续写至新表或新文件
+	tarFile, err := newSheetWriter(tarPath, opts, styles)
+	if err != nil {
+		return err
+	}
+	if meta, err = styles.Meta(meta, metaFile); err != nil {
+		tarFile.Close()
+		return err
+	}
+	guard := newTextGuard(opts.TextCols)
+	if meta, err = guard.Meta(meta, merger.Header, styles); err != nil {
+		tarFile.Close()
+		return err
+	}
+	if err := tarFile.SetLayout(layout); err != nil {
+		tarFile.Close()
+		return err
+	}
+	header, err := styles.ApplyStyles(newHeaderRow(merger.Header, meta), merger.HeaderStyles, srcPaths[0])
+	if err != nil {
+		tarFile.Close()
+		return err
+	}
+	if err := tarFile.SetHeader(header, srcPaths[0]); err != nil {
+		tarFile.Close()
+		return err
+	}
+
+	fmt.Printf("正在按%s有序合并… %s\n", color.HiYellowString(opts.Sort.Key), color.HiBlackString("(停止：Ctrl+C)"))
+	dataRows := 0
+	for merger.Next() {
+		select {
+		case <-ctx.Done():
+			tarFile.Close()
+			return ctx.Err()
+		default:
+		} // 响应 Ctrl+C 打断
+		i, line, row := merger.Row()
+		if filter != nil { // 去除重复行
+			drop, err := filter.Drop(i)
+			if err != nil {
+				tarFile.Close()
+				return err
+			}
+			if drop {
+				continue
+			}
+		}
+		rowNew, err := newDataRow(row, meta, guard, srcPaths[i], line)
+		if err != nil {
+			tarFile.Close()
+			return err
+		}
+		if rowNew, err = styles.ApplyStyles(rowNew, merger.Styles(), srcPaths[i]); err != nil {
+			tarFile.Close()
+			return err
+		}
+		if err := tarFile.AddRow(rowNew, srcPaths[i], line); err != nil {
+			tarFile.Close()
+			return err
+		}
+		dataRows++
+		if dataRows%10000 == 0 {
+			fmt.Printf("已合并%d行，耗时%s\n", dataRows, util.Cost(start))
+		}
+	}
+	if err := merger.Err(); err != nil {
+		tarFile.Close()
+		return err
+	}
+	for i, rows := range merger.FileRows() {
+		fmt.Printf("数据文件%d：读取完成，共%s\n", i+1, color.HiYellowString("%d行", rows))
+	}
+	merger.Report()
+	return saveMerged(tarFile, srcPaths, filter, guard, dataRows, start)
+}
@@ -0,0 +1,202 @@
+package xlsx
+
+import (
+	"bytes"
+	"log"
+	"path/filepath"
+	"reflect"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"github.com/xuri/excelize/v2"
+)
+
+// styleKey
+// 数据文件中的样式 ID
+type styleKey struct {
+	file string
+	id   int
+}
+
+// styleTable
+// 复制数据文件的样式定义（数字格式、字体、填充、边框等）至导出文件：各数据文件的样式 ID 先映射为全局 ID，
+// 写入导出文件时再注册为导出文件的样式 ID；数据行中的 StyleID 均为全局 ID，0 为模板的默认样式
+type styleTable struct {
+	cells   bool // 逐单元格保留样式，否则各列统一使用列样式
+	opts    option.Source
+	sources map[string]*excelize.File // 数据文件的样式定义（仅含样式部件）
+	defs    []*excelize.Style         // 全局 ID 对应的样式定义
+	keys    map[styleKey]int
+	dst     *excelize.File // 当前导出文件
+	ids     map[int]int    // 全局 ID 与当前导出文件样式 ID 的映射
+}
+
+func newStyleTable(cells bool, opts option.Source) *styleTable {
+	return &styleTable{
+		cells:   cells,
+		opts:    opts,
+		sources: make(map[string]*excelize.File),
+		defs:    []*excelize.Style{nil},
+		keys:    make(map[styleKey]int),
+	}
+}
+
+// Import
+// 数据文件样式 ID 对应的全局 ID，数据文件的默认样式（0）同样导入，以沿用其默认字体
+func (t *styleTable) Import(file string, id int) (int, error) {
+	key := styleKey{file, id}
+	if gid, ok := t.keys[key]; ok {
+		return gid, nil
+	}
+	src, ok := t.sources[file]
+	if !ok {
+		wb, err := source.OpenWorkbook(file, t.opts)
+		if err != nil {
+			return 0, err
+		}
+		pkg, err := wb.StylePackage()
+		wb.Close()
+		if err != nil {
+			return 0, err
+		}
+		if src, err = excelize.OpenReader(bytes.NewReader(pkg)); err != nil {
+			return 0, err
+		}
+		t.sources[file] = src
+	}
+	style, err := src.GetStyle(id)
+	if err != nil {
+		log.Printf("%s：样式 %d 读取失败 %v，按默认样式写入", filepath.Base(file), id, err)
+		t.keys[key] = 0
+		return 0, nil
+	}
+	gid := t.Add(style)
+	t.keys[key] = gid
+	return gid, nil
+}
+
+// Add
+// 新增样式定义，返回全局 ID
+func (t *styleTable) Add(style *excelize.Style) int {
+	t.defs = append(t.defs, style)
+	return len(t.defs) - 1
+}
+
+// Equal
+// 两个数据文件的样式定义是否相同
+func (t *styleTable) Equal(fileA string, idA int, fileB string, idB int) (bool, error) {
+	a, err := t.Import(fileA, idA)
+	if err != nil {
+		return false, err
+	}
+	b, err := t.Import(fileB, idB)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(t.defs[a], t.defs[b]), nil
+}
+
+// Meta
+// 将数据格式的样式 ID 转为全局 ID：xlsx 文件导入其样式，推断的日期列按数字格式新增样式
+func (t *styleTable) Meta(meta map[int]CellMeta, file string) (map[int]CellMeta, error) {
+	res := make(map[int]CellMeta, len(meta))
+	for k, m := range meta {
+		if source.HasCellMeta(file) {
+			id, err := t.Import(file, m.StyleId)
+			if err != nil {
+				return nil, err
+			}
+			if m.Date && m.TypeRaw == "d" { // ISO 日期单元格的样式可能不含日期格式
+				id = t.Add(withNumFmt(t.defs[id], dateStyle(m)))
+			}
+			m.StyleId = id
+		} else if m.Date {
+			m.StyleId = t.Add(dateStyle(m))
+		}
+		res[k] = m
+	}
+	return res, nil
+}
+
+// Apply
+// 逐单元格保留样式时按当前行各单元格的样式改写 StyleID，含样式的空单元格补入行尾；非 xlsx 类数据文件沿用列样式
+func (t *styleTable) Apply(rowNew []any, iter source.Rows, file string) ([]any, error) {
+	if !t.cells {
+		return rowNew, nil
+	}
+	sr, ok := iter.(source.StyledRows)
+	if !ok {
+		return rowNew, nil
+	}
+	return t.ApplyStyles(rowNew, sr.Styles(), file)
+}
+
+// ApplyStyles
+// 同 Apply，样式 ID 由调用方给出（有序合并时由归并协程随行读取），nil 表示数据文件不含样式
+func (t *styleTable) ApplyStyles(rowNew []any, styles []int, file string) ([]any, error) {
+	if !t.cells || styles == nil {
+		return rowNew, nil
+	}
+	for c := range max(len(rowNew), len(styles)) {
+		style := 0
+		if c < len(styles) {
+			style = styles[c]
+		}
+		id, err := t.Import(file, style)
+		if err != nil {
+			return nil, err
+		}
+		if c >= len(rowNew) {
+			rowNew = append(rowNew, excelize.Cell{StyleID: id})
+			continue
+		}
+		if cell, ok := rowNew[c].(excelize.Cell); ok {
+			cell.StyleID = id
+			rowNew[c] = cell
+		}
+	}
+	return rowNew, nil
+}
+
+// Resolve
+// 返回 StyleID 替换为导出文件样式 ID 的行副本，样式按需注册
+func (t *styleTable) Resolve(dst *excelize.File, row []any) ([]any, error) {
+	if dst != t.dst {
+		t.dst, t.ids = dst, make(map[int]int)
+	}
+	res := make([]any, len(row))
+	for c, v := range row {
+		cell, ok := v.(excelize.Cell)
+		if ok && cell.StyleID != 0 {
+			id, ok := t.ids[cell.StyleID]
+			if !ok {
+				var err error
+				if id, err = dst.NewStyle(t.defs[cell.StyleID]); err != nil {
+					return nil, err
+				}
+				t.ids[cell.StyleID] = id
+			}
+			cell.StyleID = id
+			v = cell
+		}
+		res[c] = v
+	}
+	return res, nil
+}
+
+func (t *styleTable) Close() {
+	for _, f := range t.sources {
+		f.Close()
+	}
+}
+
+// withNumFmt
+// 样式副本，数字格式替换为 numFmt 的数字格式
+func withNumFmt(style *excelize.Style, numFmt *excelize.Style) *excelize.Style {
+	res := &excelize.Style{}
+	if style != nil {
+		*res = *style
+	}
+	res.NumFmt, res.CustomNumFmt = numFmt.NumFmt, numFmt.CustomNumFmt
+	return res
+}
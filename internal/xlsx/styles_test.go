@@ -0,0 +1,154 @@
+package xlsx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+// styleSummary
+// 用于比较的样式要点：数字格式、粗体、字体颜色、填充色
+type styleSummary struct {
+	numFmt int
+	bold   bool
+	color  string
+	fill   string
+}
+
+func summarize(t *testing.T, f *excelize.File, sheet string, axis string) styleSummary {
+	t.Helper()
+	id, err := f.GetCellStyle(sheet, axis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	style, err := f.GetStyle(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res styleSummary
+	res.numFmt = style.NumFmt
+	if style.Font != nil {
+		res.bold, res.color = style.Font.Bold, style.Font.Color
+	}
+	if len(style.Fill.Color) > 0 {
+		res.fill = style.Fill.Color[0]
+	}
+	return res
+}
+
+func TestSplitStyles(t *testing.T) {
+	f := excelize.NewFile()
+	styles := []*excelize.Style{
+		{NumFmt: 4, Font: &excelize.Font{Bold: true}},
+		{NumFmt: 10, Font: &excelize.Font{Color: "FF0000"}},
+		{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFFF00"}}},
+	}
+	rows := [][]any{{"v"}, {1}, {-2}, {3}}
+	for i, row := range rows {
+		axis, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", axis, &row); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			continue
+		}
+		id, err := f.NewStyle(styles[i-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.SetCellStyle("Sheet1", axis, axis, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := filepath.Join(t.TempDir(), "src.xlsx")
+	if err := f.SaveAs(src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	bold := styleSummary{numFmt: 4, bold: true}
+	tests := []struct {
+		name      string
+		cellStyle bool
+		want      []styleSummary // A2:A4
+	}{
+		{"列样式", false, []styleSummary{bold, bold, bold}}, // 平票取先出现者
+		{"逐单元格样式", true, []styleSummary{bold, {numFmt: 10, color: "FF0000"}, {fill: "FFFF00"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tarDir := filepath.Join(t.TempDir(), "out")
+			if err := os.MkdirAll(tarDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := SplitXlsx2xlsxByLine(src, tarDir, 10, option.Options{CellStyle: tt.cellStyle}, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			out, err := excelize.OpenFile(filepath.Join(tarDir, "out-1.xlsx"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			for i, want := range tt.want {
+				axis, _ := excelize.CoordinatesToCellName(1, i+2)
+				if got := summarize(t, out, "data", axis); got != want {
+					t.Errorf("%s = %+v, want %+v", axis, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStyleTableImport(t *testing.T) {
+	var paths []string
+	for _, name := range []string{"a.xlsx", "b.xlsx"} {
+		f := excelize.NewFile()
+		// 两个文件的样式 ID 错位：a 中为 1、2，b 中为 2、1
+		defs := []*excelize.Style{{NumFmt: 4}, {Font: &excelize.Font{Italic: true}}}
+		if name == "b.xlsx" {
+			defs[0], defs[1] = defs[1], defs[0]
+		}
+		for _, def := range defs {
+			if _, err := f.NewStyle(def); err != nil {
+				t.Fatal(err)
+			}
+		}
+		path := filepath.Join(t.TempDir(), name)
+		if err := f.SaveAs(path); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		paths = append(paths, path)
+	}
+	table := newStyleTable(true, option.Source{})
+	defer table.Close()
+	tests := []struct {
+		idA, idB int
+		want     bool
+	}{
+		{0, 0, true},
+		{1, 2, true},
+		{2, 1, true},
+		{1, 1, false},
+	}
+	for _, tt := range tests {
+		got, err := table.Equal(paths[0], tt.idA, paths[1], tt.idB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Equal(a %d, b %d) = %v, want %v", tt.idA, tt.idB, got, tt.want)
+		}
+	}
+	first, err := table.Import(paths[0], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := table.Import(paths[0], 1); again != first {
+		t.Errorf("重复导入的全局 ID = %d, want %d", again, first)
+	}
+}
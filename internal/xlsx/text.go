@@ -0,0 +1,149 @@
+package xlsx
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
+	"github.com/fatih/color"
+	"github.com/xuri/excelize/v2"
+)
+
+// Excel 数值的最大有效数字位数，超出部分显示、保存时均被截为 0
+const maxDigits = 15
+
+// 内置数字格式：文本（@）
+const numFmtText = 49
+
+// lossyNumber
+// 整数超出 15 位有效数字（身份证号、订单号、银行账号等），按数值写入会丢失精度；
+// 小数不在此列，公式计算所得的小数常以 17 位有效数字保存，本就是浮点数
+func lossyNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if s == "" || strings.Trim(s, "0123456789") != "" {
+		return false
+	}
+	s = strings.Trim(s, "0") // 首尾的 0 不计入有效数字（末尾的 0 可由指数表示）
+	return len(s) > maxDigits
+}
+
+// leadingZero
+// 含前导零的整数（邮编 010020、编码 0001 等），按数值写入会丢失前导零
+func leadingZero(s string) bool {
+	return len(s) > 1 && s[0] == '0' && strings.Trim(s, "0123456789") == ""
+}
+
+// IsNumber
+// 可解析为数值，排除 NaN、Inf、十六进制、下划线分隔等 ParseFloat 接受但不应视为数值的写法
+func IsNumber(s string) bool {
+	if strings.ContainsAny(s, "iInNxX_") {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// KeepText
+// 数值列中的值是否应按文本导出：含前导零，或超出 15 位有效数字
+func KeepText(s string) bool {
+	return leadingZero(s) || lossyNumber(s)
+}
+
+// textGuard
+// 数值列按文本写入：指定的列始终按文本写入，其余数值列中会丢失精度或前导零的值逐个按文本写入，结束时列出涉及的列
+type textGuard struct {
+	keys   []string       // 指定按文本写入的列，列名（A、B…）或行首文字
+	header []string       // 行首，用于列出涉及的列
+	lossy  map[int]int    // 列号 - 按文本写入的值数
+	first  map[int]string // 列号 - 首个按文本写入的位置
+}
+
+func newTextGuard(keys []string) *textGuard {
+	return &textGuard{keys: keys, lossy: make(map[int]int), first: make(map[int]string)}
+}
+
+// Meta
+// 按行首定位指定的列，改为文本列并使用文本数字格式（须在样式转为全局 ID 后调用）
+func (g *textGuard) Meta(meta map[int]CellMeta, header []string, styles *styleTable) (map[int]CellMeta, error) {
+	g.header = header
+	if len(g.keys) == 0 {
+		return meta, nil
+	}
+	res := make(map[int]CellMeta, len(meta))
+	for k, m := range meta {
+		res[k] = m
+	}
+	var cols []string
+	for _, key := range g.keys {
+		idx, err := util.ColumnIndexFromKey(header, key)
+		if err != nil {
+			return nil, err
+		}
+		m := res[idx+1]
+		m.TypeIdx, m.TypeRaw, m.Date = excelize.CellTypeSharedString, "s", false
+		m.StyleId = styles.Add(withNumFmt(styles.defs[m.StyleId], &excelize.Style{NumFmt: numFmtText}))
+		res[idx+1] = m
+		cols = append(cols, g.colName(idx+1))
+	}
+	fmt.Printf("按文本写入：%s\n", color.HiYellowString(strings.Join(cols, "、")))
+	return res, nil
+}
+
+// Keep
+// 第 c 列（从0开始）的数值是否按文本写入，是则计入涉及的列
+func (g *textGuard) Keep(c int, value string, src cellSource) bool {
+	if g == nil {
+		return false
+	}
+	reason := ""
+	switch {
+	case leadingZero(value):
+		reason = "含前导零"
+	case lossyNumber(value):
+		reason = fmt.Sprintf("超出 %d 位有效数字", maxDigits)
+	default:
+		return false
+	}
+	col := c + 1
+	if g.lossy[col] == 0 {
+		file, line, srcCol := src(c)
+		name, _ := excelize.ColumnNumberToName(srcCol)
+		g.first[col] = fmt.Sprintf("%s 位置 %s%d", filepath.Base(file), name, line)
+		log.Printf("%s：位置 %s%d，数值 %s %s，按文本写入", filepath.Base(file), name, line, value, reason)
+	}
+	g.lossy[col]++
+	return true
+}
+
+// Report
+// 列出存在含前导零、超出 15 位有效数字而按文本写入的值的列
+func (g *textGuard) Report() {
+	if g == nil || len(g.lossy) == 0 {
+		return
+	}
+	cols := make([]int, 0, len(g.lossy))
+	for col := range g.lossy {
+		cols = append(cols, col)
+	}
+	slices.Sort(cols)
+	items := make([]string, len(cols))
+	for i, col := range cols {
+		items[i] = fmt.Sprintf("%s %d个（首个：%s）", g.colName(col), g.lossy[col], g.first[col])
+	}
+	fmt.Printf("%s以下列的数值含前导零或超出 %d 位有效数字，已按文本写入，可通过 -text-cols 指定整列按文本写入：%s\n",
+		color.HiRedString("注意："), maxDigits, strings.Join(items, "，"))
+}
+
+// colName
+// 列名附行首文字，如 C列（身份证号）
+func (g *textGuard) colName(col int) string {
+	name, _ := excelize.ColumnNumberToName(col)
+	if col <= len(g.header) && strings.TrimSpace(g.header[col-1]) != "" {
+		return fmt.Sprintf("%s列（%s）", name, strings.TrimSpace(g.header[col-1]))
+	}
+	return name + "列"
+}
@@ -0,0 +1,299 @@
+package xlsx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/testutil"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestLossyNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"123456789012345", false},     // 15 位
+		{"1234567890123456", true},     // 16 位
+		{"110101199003071234", true},   // 身份证号
+		{"-1234567890123456", true},    // 负数
+		{"1000000000000000000", false}, // 末尾的 0 可由指数表示
+		{"0001234567890123", false},    // 前导零不计入
+		{"1234567890.123456", false},   // 小数
+		{"1.2345678901234567E+20", false},
+		{"", false},
+		{"-", false},
+		{"12345678901234567a", false},
+	}
+	for _, tt := range tests {
+		if got := lossyNumber(tt.in); got != tt.want {
+			t.Errorf("lossyNumber(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTextGuardKeep(t *testing.T) {
+	g := newTextGuard(nil)
+	g.header = []string{"id", "", "amount"}
+	src := func(line int) cellSource {
+		return func(c int) (string, int, int) { return "a.xlsx", line, c + 1 }
+	}
+	values := []struct {
+		c     int
+		value string
+		line  int
+		want  bool
+	}{
+		{0, "110101199003071234", 2, true},
+		{0, "12", 3, false},
+		{0, "110101199003075678", 4, true},
+		{1, "12345678901234567", 5, true},
+		{2, "1234.5", 6, false},
+	}
+	for _, v := range values {
+		if got := g.Keep(v.c, v.value, src(v.line)); got != v.want {
+			t.Errorf("Keep(%d, %q) = %v, want %v", v.c, v.value, got, v.want)
+		}
+	}
+	tests := []struct {
+		col   int
+		count int
+		first string
+		name  string
+	}{
+		{1, 2, "a.xlsx 位置 A2", "A列（id）"},
+		{2, 1, "a.xlsx 位置 B5", "B列"},
+		{3, 0, "", "C列（amount）"},
+	}
+	for _, tt := range tests {
+		if g.lossy[tt.col] != tt.count || g.first[tt.col] != tt.first || g.colName(tt.col) != tt.name {
+			t.Errorf("列%d = %d %q %q, want %d %q %q",
+				tt.col, g.lossy[tt.col], g.first[tt.col], g.colName(tt.col), tt.count, tt.first, tt.name)
+		}
+	}
+	var none *textGuard
+	if none.Keep(0, "110101199003071234", src(2)) {
+		t.Error("nil guard 不应按文本写入")
+	}
+	none.Report()
+}
+
+func TestTextGuardMeta(t *testing.T) {
+	header := []string{"id", "phone", "amount"}
+	meta := map[int]CellMeta{
+		1: {TypeIdx: excelize.CellTypeUnset},
+		2: {TypeIdx: excelize.CellTypeNumber, Date: true},
+		3: {TypeIdx: excelize.CellTypeUnset},
+	}
+	tests := []struct {
+		name string
+		keys []string
+		text []bool // 各列是否改为文本列
+		err  bool
+	}{
+		{"未指定", nil, []bool{false, false, false}, false},
+		{"按列名与行首", []string{"A", "phone"}, []bool{true, true, false}, false},
+		{"不存在的列", []string{"名称"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			styles := newStyleTable(false, option.Source{})
+			got, err := newTextGuard(tt.keys).Meta(meta, header, styles)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want err %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			for i, text := range tt.text {
+				m := got[i+1]
+				if isText := m.TypeIdx == excelize.CellTypeSharedString && !m.Date; isText != text {
+					t.Errorf("列%d：%+v，期望文本列 %v", i+1, m, text)
+				}
+				if text && (m.StyleId == 0 || styles.defs[m.StyleId].NumFmt != numFmtText) {
+					t.Errorf("列%d：样式 %d 不是文本数字格式", i+1, m.StyleId)
+				}
+			}
+			if meta[1].TypeIdx != excelize.CellTypeUnset {
+				t.Error("不应修改传入的数据格式")
+			}
+		})
+	}
+}
+
+func TestSplitLongNumbers(t *testing.T) {
+	src := testutil.WriteFile(t, t.TempDir(), "src.csv", "id,amount,code\n"+
+		"110101199003071234,1.5,7\n"+
+		"12,2,8\n")
+	tests := []struct {
+		name     string
+		textCols []string
+		want     map[string]excelize.CellType // 单元格 - 写入类型
+	}{
+		{"自动", nil, map[string]excelize.CellType{
+			"A2": excelize.CellTypeInlineString, "A3": excelize.CellTypeUnset, "B2": excelize.CellTypeUnset, "C2": excelize.CellTypeUnset,
+		}},
+		{"指定整列", []string{"code"}, map[string]excelize.CellType{
+			"A2": excelize.CellTypeInlineString, "C2": excelize.CellTypeInlineString, "C3": excelize.CellTypeInlineString,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tarDir := filepath.Join(t.TempDir(), "out")
+			if err := os.MkdirAll(tarDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := SplitXlsx2xlsxByLine(src, tarDir, 10, option.Options{TextCols: tt.textCols}, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			out, err := excelize.OpenFile(filepath.Join(tarDir, "out-1.xlsx"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			if value, _ := out.GetCellValue("data", "A2"); value != "110101199003071234" {
+				t.Errorf("A2 = %q，精度丢失", value)
+			}
+			for axis, want := range tt.want {
+				got, err := out.GetCellType("data", axis)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("%s 类型 = %v, want %v", axis, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeepText(t *testing.T) {
+	tests := []struct {
+		in      string
+		leading bool
+		want    bool
+	}{
+		{"010020", true, true},
+		{"0001", true, true},
+		{"0", false, false},
+		{"0.5", false, false}, // 小数不是前导零
+		{"-01", false, false},
+		{"10020", false, false},
+		{"1234567890123456", false, true},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		if got := leadingZero(tt.in); got != tt.leading {
+			t.Errorf("leadingZero(%q) = %v, want %v", tt.in, got, tt.leading)
+		}
+		if got := KeepText(tt.in); got != tt.want {
+			t.Errorf("KeepText(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitLeadingZeros(t *testing.T) {
+	f := excelize.NewFile()
+	zipFmt := "000000"
+	zip, err := f.NewStyle(&excelize.Style{CustomNumFmt: &zipFmt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := f.NewStyle(&excelize.Style{NumFmt: numFmtText})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := []struct {
+		axis  string
+		value any
+		style int
+	}{
+		{"A1", "zip", 0}, {"B1", "code", 0}, {"C1", "n", 0},
+		{"A2", 10020, zip}, {"B2", "0012", text}, {"C2", 7, 0},
+		{"A3", 200001, zip}, {"B3", 345, text}, {"C3", "08", 0}, // 数值列中的前导零
+	}
+	for _, c := range cells {
+		if err := f.SetCellValue("Sheet1", c.axis, c.value); err != nil {
+			t.Fatal(err)
+		}
+		if c.style != 0 {
+			if err := f.SetCellStyle("Sheet1", c.axis, c.axis, c.style); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	src := filepath.Join(t.TempDir(), "src.xlsx")
+	if err := f.SaveAs(src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tarDir := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(tarDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SplitXlsx2xlsxByLine(src, tarDir, 10, option.Options{}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	out, err := excelize.OpenFile(filepath.Join(tarDir, "out-1.xlsx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	tests := []struct {
+		axis  string
+		value string
+		typ   excelize.CellType
+	}{
+		{"A2", "010020", excelize.CellTypeInlineString}, // 补零格式按显示补足
+		{"A3", "200001", excelize.CellTypeInlineString},
+		{"B2", "0012", excelize.CellTypeInlineString}, // 文本格式
+		{"B3", "345", excelize.CellTypeInlineString},
+		{"C2", "7", excelize.CellTypeUnset},
+		{"C3", "08", excelize.CellTypeInlineString},
+	}
+	for _, tt := range tests {
+		value, err := out.GetCellValue("data", tt.axis, excelize.Options{RawCellValue: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		typ, err := out.GetCellType("data", tt.axis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != tt.value || typ != tt.typ {
+			t.Errorf("%s = %q %v, want %q %v", tt.axis, value, typ, tt.value, tt.typ)
+		}
+	}
+}
+
+func TestIsNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"1", true},
+		{"-2.5", true},
+		{"1e3", true},
+		{"1E+3", true},
+		{".5", true},
+		{"", false},
+		{"-", false},
+		{"NaN", false},
+		{"Inf", false},
+		{"-infinity", false},
+		{"0x10", false},
+		{"0x1p-2", false},
+		{"1_000", false},
+		{"1,000", false},
+		{"12abc", false},
+	}
+	for _, tt := range tests {
+		if got := IsNumber(tt.in); got != tt.want {
+			t.Errorf("IsNumber(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
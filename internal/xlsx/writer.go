@@ -0,0 +1,287 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
+	"github.com/fatih/color"
+	"github.com/xuri/excelize/v2"
+)
+
+// 每表最大行数（含行首），即 Excel 最大行数；测试时调小
+var maxRows = excelize.TotalRows
+
+// sheetWriter
+// 基于模板文件流式写入，超出 Excel 最大行数时按 overflow 续写至新表或新文件，并重复行首
+type sheetWriter struct {
+	path     string
+	overflow option.Overflow
+	header   []any
+	file     *excelize.File
+	sw       *excelize.StreamWriter
+	protect  *protector
+	styles   *styleTable // 行中为全局样式 ID，写入时注册至当前文件
+	layout   *source.Layout
+	notes    *noteCopier
+	merges   *mergeKeeper
+	sheet    string   // 当前表名
+	recalc   bool     // 当前文件已设置打开即重算
+	sheets   int      // 当前文件已建表数
+	rows     int      // 当前表已写入行数（含行首）
+	limit    int      // 每表最大行数，即 Excel 最大行数
+	Paths    []string // 已生成的文件
+}
+
+func newSheetWriter(path string, opts option.Options, styles *styleTable) (*sheetWriter, error) {
+	w := &sheetWriter{
+		path:     path,
+		overflow: opts.Overflow,
+		protect:  newProtector(opts.Protect),
+		styles:   styles,
+		notes:    newNoteCopier(opts.Source),
+		merges:   newMergeKeeper(opts.Merged, opts.Source),
+		limit:    maxRows,
+	}
+	if err := w.openFile(path); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// openFile
+// 使用模板文件（来自 Excel 2016+ 创建的空文件）
+func (w *sheetWriter) openFile(path string) error {
+	f, err := excelize.OpenReader(bytes.NewReader(templateXlsx))
+	if err != nil {
+		return err
+	}
+	sw, err := f.NewStreamWriter("data") // 流式写入（不爆内存，注意始终从首行开始）
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := setLayout(sw, w.layout); err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.sw, w.sheet, w.recalc = f, sw, "data", false
+	w.sheets, w.rows = 1, 0
+	w.Paths = append(w.Paths, path)
+	return nil
+}
+
+func (w *sheetWriter) saveFile() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if err := w.protect.saveAs(w.file, w.Paths[len(w.Paths)-1]); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
+
+// rollover
+// 当前表已满，续写至新表或新文件
+func (w *sheetWriter) rollover() error {
+	if w.overflow == option.OverflowFile {
+		if err := w.saveFile(); err != nil {
+			return err
+		}
+		ext := filepath.Ext(w.path)
+		path := fmt.Sprintf("%s-%d%s", strings.TrimSuffix(w.path, ext), len(w.Paths)+1, ext)
+		if err := w.openFile(path); err != nil {
+			return err
+		}
+		fmt.Printf("已超出 Excel 最大行数，续写至新文件：%s\n", color.HiYellowString(filepath.Base(path)))
+	} else {
+		if err := w.flush(); err != nil {
+			return err
+		}
+		w.sheets++
+		sheet := fmt.Sprintf("data_%d", w.sheets)
+		if _, err := w.file.NewSheet(sheet); err != nil {
+			return err
+		}
+		sw, err := w.file.NewStreamWriter(sheet)
+		if err != nil {
+			return err
+		}
+		if err := setLayout(sw, w.layout); err != nil {
+			return err
+		}
+		w.sw, w.sheet, w.rows = sw, sheet, 0
+		fmt.Printf("已超出 Excel 最大行数，续写至新表：%s\n", color.HiYellowString(sheet))
+	}
+	if w.header != nil {
+		return w.writeHeader()
+	}
+	return nil
+}
+
+// flush
+// 结束当前表的写入，自动筛选范围随写入行数调整
+func (w *sheetWriter) flush() error {
+	if err := setFilter(w.file, w.sheet, w.layout, w.rows); err != nil {
+		return err
+	}
+	return w.sw.Flush()
+}
+
+// SetLayout
+// 沿用数据文件的列宽、冻结窗格及自动筛选，续写的新表、新文件同样设置；须在写入行首前调用
+func (w *sheetWriter) SetLayout(layout *source.Layout) error {
+	w.layout = layout
+	return setLayout(w.sw, layout)
+}
+
+// SetHeader
+// 写入行首，续表、续文件时自动重复；file 为行首所在的数据文件，其行首的超链接、批注及合并单元格一并写入
+func (w *sheetWriter) SetHeader(row []any, file string) error {
+	if err := w.notes.SetHeader(file); err != nil {
+		return err
+	}
+	if err := w.merges.SetHeader(file); err != nil {
+		return err
+	}
+	w.header = row
+	return w.writeHeader()
+}
+
+func (w *sheetWriter) writeHeader() error {
+	w.rows++
+	if err := w.setRow(w.header); err != nil {
+		return err
+	}
+	if err := w.merges.Header(w.sw); err != nil {
+		return err
+	}
+	return w.notes.Header(w.file, w.sheet)
+}
+
+// NextRow
+// 下一数据行将写入的行号，当前表已满则为续写新表（文件）的首个数据行
+func (w *sheetWriter) NextRow() int {
+	if w.rows >= w.limit {
+		return 2
+	}
+	return w.rows + 1
+}
+
+// RowsLeft
+// 下一数据行所在的表还可写入的数据行数（含该行）
+func (w *sheetWriter) RowsLeft() int {
+	if w.rows >= w.limit {
+		return w.limit - 1
+	}
+	return w.limit - w.rows
+}
+
+// AddRow
+// 写入数据行，数据文件第 line 行（含行首）的超链接、批注及合并单元格按新行号一并写入
+func (w *sheetWriter) AddRow(row []any, file string, line int) error {
+	if err := w.addRow(row); err != nil {
+		return err
+	}
+	if err := w.merges.Apply(w.sw, file, line, w.rows); err != nil {
+		return err
+	}
+	return w.notes.Apply(w.file, w.sheet, file, line, w.rows)
+}
+
+// SetRight
+// 拼接时设置右表及其列映射（右表列号 → 导出列号，从1开始），须在写入行首前调用
+func (w *sheetWriter) SetRight(file string, cols map[int]int) {
+	w.notes.SetRight(file, cols)
+}
+
+// AddJoinedRow
+// 写入拼接行，左表第 leftLine 行、右表第 rightLine 行（无该侧为0）的超链接、批注按新行号一并写入；
+// 右表各行按键值查找而非连续写入，仅保留左表的合并单元格
+func (w *sheetWriter) AddJoinedRow(row []any, leftFile string, leftLine int, rightLine int) error {
+	if err := w.addRow(row); err != nil {
+		return err
+	}
+	if leftLine > 0 {
+		if err := w.merges.Apply(w.sw, leftFile, leftLine, w.rows); err != nil {
+			return err
+		}
+		if err := w.notes.Apply(w.file, w.sheet, leftFile, leftLine, w.rows); err != nil {
+			return err
+		}
+	}
+	if rightLine > 0 {
+		return w.notes.ApplyRight(w.file, w.sheet, rightLine, w.rows)
+	}
+	return nil
+}
+
+func (w *sheetWriter) addRow(row []any) error {
+	if w.rows >= w.limit {
+		if w.overflow == option.OverflowRefuse {
+			return fmt.Errorf("超出 Excel 最大行数：%d", w.limit)
+		}
+		if err := w.rollover(); err != nil {
+			return err
+		}
+	}
+	if !w.recalc && hasFormula(row) {
+		if err := setRecalc(w.file); err != nil {
+			return err
+		}
+		w.recalc = true
+	}
+	w.rows++
+	return w.setRow(row)
+}
+
+func (w *sheetWriter) setRow(row []any) error {
+	row, err := w.styles.Resolve(w.file, row)
+	if err != nil {
+		return err
+	}
+	return w.sw.SetRow(fmt.Sprintf("A%d", w.rows), row)
+}
+
+func (w *sheetWriter) Save() error {
+	return w.saveFile()
+}
+
+func (w *sheetWriter) Close() {
+	w.file.Close()
+}
+
+// dateStyle
+// 日期列沿用源文件的数字格式，无则按日期时间显示
+func dateStyle(m CellMeta) *excelize.Style {
+	if m.FmtCode != "" {
+		code := m.FmtCode
+		return &excelize.Style{CustomNumFmt: &code}
+	}
+	if m.NumFmt != 0 {
+		return &excelize.Style{NumFmt: m.NumFmt}
+	}
+	code := "yyyy-mm-dd hh:mm:ss"
+	return &excelize.Style{CustomNumFmt: &code}
+}
+
+// hasFormula
+// 数据行是否含公式（错误值以常量公式写入）
+func hasFormula(row []any) bool {
+	for _, v := range row {
+		if cell, ok := v.(excelize.Cell); ok && cell.Formula != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// setRecalc
+// 设置打开即重算，流式写入的公式不含计算链，否则须手动重算才能显示错误值
+func setRecalc(f *excelize.File) error {
+	recalc := true
+	return f.SetCalcProps(&excelize.CalcPropsOptions{FullCalcOnLoad: &recalc})
+}
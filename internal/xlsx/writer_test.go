@@ -0,0 +1,159 @@
+package xlsx
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/testutil"
+	"github.com/xuri/excelize/v2"
+)
+
+// readSheets
+// 读取导出文件各表的全部行
+func readSheets(t *testing.T, path string) map[string][][]string {
+	t.Helper()
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	res := make(map[string][][]string)
+	for _, sheet := range f.GetSheetList() {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res[sheet] = rows
+	}
+	return res
+}
+
+func TestSheetWriterOverflow(t *testing.T) {
+	src := testutil.WriteFile(t, t.TempDir(), "src.csv", "h\n1\n2\n3\n4\n5\n")
+	tests := []struct {
+		name     string
+		overflow option.Overflow
+		wantErr  bool
+		files    []string              // 生成的文件名
+		sheets   map[string][][]string // 首个文件各表的行
+	}{
+		{
+			name:     "sheet",
+			overflow: option.OverflowSheet,
+			files:    []string{"out.xlsx"},
+			sheets: map[string][][]string{
+				"data":   {{"h"}, {"1"}, {"2"}},
+				"data_2": {{"h"}, {"3"}, {"4"}},
+				"data_3": {{"h"}, {"5"}},
+			},
+		},
+		{
+			name:     "file",
+			overflow: option.OverflowFile,
+			files:    []string{"out.xlsx", "out-2.xlsx", "out-3.xlsx"},
+			sheets: map[string][][]string{
+				"data": {{"h"}, {"1"}, {"2"}},
+			},
+		},
+		{
+			name:     "refuse",
+			overflow: option.OverflowRefuse,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.xlsx")
+			w, err := newSheetWriter(path, option.Options{Overflow: tt.overflow}, newStyleTable(false, option.Source{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.limit = 3
+			if err = w.SetHeader([]any{"h"}, src); err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= 5 && err == nil; i++ {
+				err = w.AddRow([]any{i}, src, i+1)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error beyond the row limit")
+				}
+				w.Close()
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = w.Save(); err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, p := range w.Paths {
+				files = append(files, filepath.Base(p))
+			}
+			if !slices.Equal(files, tt.files) {
+				t.Errorf("files = %v, want %v", files, tt.files)
+			}
+			got := readSheets(t, path)
+			for sheet, rows := range tt.sheets {
+				if !slices.EqualFunc(got[sheet], rows, slices.Equal) {
+					t.Errorf("sheet %s = %v, want %v", sheet, got[sheet], rows)
+				}
+			}
+			if len(got) != len(tt.sheets) {
+				t.Errorf("sheets = %v, want %d sheets", got, len(tt.sheets))
+			}
+		})
+	}
+}
+
+func TestMergeRefuseAfterDedup(t *testing.T) {
+	defer func(n int) { maxRows = n }(maxRows)
+	maxRows = 4 // 去重前 6 行（含行首），去重后 4 行
+	a := testutil.WriteFile(t, t.TempDir(), "a.csv", "id\n1\n1\n2\n")
+	b := testutil.WriteFile(t, t.TempDir(), "b.csv", "id\n2\n3\n")
+	tests := []struct {
+		name    string
+		sorted  bool
+		dedup   bool
+		wantErr bool
+	}{
+		{"不去重超出", false, false, true},
+		{"去重后未超出", false, true, false},
+		{"有序合并不去重超出", true, false, true},
+		{"有序合并去重后未超出", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tarPath := filepath.Join(t.TempDir(), "out.xlsx")
+			opts := option.Options{
+				Overflow: option.OverflowRefuse,
+				Dedup:    option.Dedup{Enabled: tt.dedup, Keys: []string{"id"}},
+			}
+			merge := MergeXlsx2xlsxV2
+			if tt.sorted {
+				opts.Sort = option.Sort{Enabled: true, Key: "id"}
+				merge = MergeXlsx2xlsxSorted
+			}
+			err := merge([]string{a, b}, tarPath, opts, context.Background())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "已拒绝合并") {
+					t.Errorf("err = %v, want 已拒绝合并", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := [][]string{{"id"}, {"1"}, {"2"}, {"3"}}
+			if got := readSheets(t, tarPath)["data"]; !slices.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("rows = %q, want %q", got, want)
+			}
+		})
+	}
+}
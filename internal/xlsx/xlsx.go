@@ -1,7 +1,6 @@
 package xlsx
 
 import (
-	"archive/zip"
 	"bytes"
 	"context"
 	_ "embed"
@@ -12,12 +11,16 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"gitee.com/nguaduot/split-xlsx-go/internal/dedup"
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"gitee.com/nguaduot/split-xlsx-go/internal/source"
 	"gitee.com/nguaduot/split-xlsx-go/pkg/util"
 	"github.com/fatih/color"
 	"github.com/xuri/excelize/v2"
@@ -26,10 +29,51 @@ import (
 //go:embed template.xlsx
 var templateXlsx []byte
 
+// Excel 错误值，可作常量公式写入
+var cellErrors = []string{"#NULL!", "#DIV/0!", "#VALUE!", "#REF!", "#NAME?", "#NUM!", "#N/A"}
+
 type CellMeta struct {
 	StyleId int
 	TypeIdx excelize.CellType
 	TypeRaw string
+	Date    bool   // 日期、时间列
+	NumFmt  int    // 日期列的内置数字格式 ID
+	FmtCode string // 日期列的自定义数字格式代码，优先于 NumFmt
+	Votes   int    // 采样中与此格式一致的非空单元格数
+	Samples int    // 采样的非空单元格数
+}
+
+// typeName
+// 日志中的类型名，日期列附注
+func (m CellMeta) typeName() string {
+	if m.Date {
+		return m.TypeRaw + "（日期）"
+	}
+	return m.TypeRaw
+}
+
+// confidence
+// 日志中的采样一致率
+func (m CellMeta) confidence() string {
+	if m.Samples == 0 {
+		return "无数据"
+	}
+	return fmt.Sprintf("%d%%", m.Votes*100/m.Samples)
+}
+
+// describeMeta
+// 数据格式日志：各列样式、类型及采样一致率
+func describeMeta(meta map[int]CellMeta) (string, error) {
+	var msg strings.Builder
+	for j := range len(meta) {
+		col, err := excelize.ColumnNumberToName(j + 1)
+		if err != nil {
+			return "", err
+		}
+		m := meta[j+1]
+		fmt.Fprintf(&msg, "%s列 样式 %d 类型 %s %s，", col, m.StyleId, m.typeName(), m.confidence())
+	}
+	return strings.TrimSuffix(msg.String(), "，"), nil
 }
 
 func cellTypeRaw2Idx(t string) excelize.CellType {
@@ -75,9 +119,10 @@ func cellTypeIdx2Raw(t excelize.CellType) string {
 }
 
 // readXlsxStyleAndType
+// 采样前 sample 个数据行，按非空单元格的样式和类型投票决定各列数据格式，整列无数据则取空单元格的样式
 // 关于 excelize file.GetCellStyle() file.GetCellType()
 // 均需加载完整样式数据，大表内存爆炸
-func readXlsxStyleAndType(file string) (map[int]CellMeta, error) {
+func readXlsxStyleAndType(file string, sample int, opts option.Source) (map[int]CellMeta, error) {
 	// f, err := excelize.OpenFile(file, excelize.Options{
 	// 	UnzipSizeLimit:    8 << 30, // 8GB
 	// 	UnzipXMLSizeLimit: 4 << 30, // 4GB
@@ -96,29 +141,30 @@ func readXlsxStyleAndType(file string) (map[int]CellMeta, error) {
 	// f.Close()
 	// cols := len(row) // 列数
 
-	r, err := zip.OpenReader(file)
+	wb, err := source.OpenWorkbook(file, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer r.Close()
-	var sheetReader io.ReadCloser
-	sheetPath := "xl/worksheets/sheet1.xml"
-	for _, f := range r.File {
-		if f.Name == sheetPath {
-			sheetReader, err = f.Open()
-			if err != nil {
-				return nil, err
-			}
-			break
-		}
-	}
-	if sheetReader == nil {
-		return nil, fmt.Errorf("文件不存在：%s", sheetPath)
+	defer wb.Close()
+	sheetReader, err := wb.Open(wb.Sheet) // 第一张表（不一定是 sheet1.xml）
+	if err != nil {
+		return nil, err
 	}
 	defer sheetReader.Close()
-	decoder := xml.NewDecoder(sheetReader)
-	res := make(map[int]CellMeta)
-	inTargetRow := false
+	type vote struct {
+		style   int
+		typeRaw string
+	}
+	var (
+		decoder = xml.NewDecoder(sheetReader)
+		counts  = map[int]map[vote]int{} // 各列非空单元格的得票
+		order   = map[int][]vote{}       // 各列得票项的出现顺序，平票取先出现者
+		blank   = map[int]vote{}         // 各列首个空单元格
+		maxCol  int
+		row     int
+		col     int
+	)
+scan:
 	for {
 		tok, err := decoder.Token()
 		if err == io.EOF {
@@ -131,61 +177,108 @@ func readXlsxStyleAndType(file string) (map[int]CellMeta, error) {
 		case xml.StartElement:
 			// <row r="2">
 			if se.Name.Local == "row" {
+				row, col = row+1, 0
 				for _, a := range se.Attr {
 					if a.Name.Local == "r" {
-						r, _ := strconv.Atoi(a.Value)
-						if r == 2 {
-							inTargetRow = true
+						if r, err := strconv.Atoi(a.Value); err == nil {
+							row = r
 						}
 					}
 				}
+				if row > sample+1 { // 采样结束
+					break scan
+				}
+				continue
 			}
-			// <c r="B2" s="5" t="s">
-			if inTargetRow && se.Name.Local == "c" {
-				var (
-					axis    string
-					styleId string
-					typeRaw string
-				)
-				for _, a := range se.Attr {
-					switch a.Name.Local {
-					case "r":
-						axis = a.Value
-					case "s":
-						styleId = a.Value
-					case "t":
-						typeRaw = a.Value
-					}
+			if se.Name.Local != "c" {
+				continue
+			}
+			// <c r="B2" s="5" t="s"><v>0</v></c>
+			col++
+			if row < 2 { // 行首
+				if err := decoder.Skip(); err != nil {
+					return nil, err
 				}
-				if axis != "" {
-					col := util.XlsxColIndexFromAxis(axis)
-					styleIdFix := 0
-					if styleId != "" {
-						styleIdFix, err = strconv.Atoi(styleId)
-						if err != nil {
-							return nil, err
-						}
+				continue
+			}
+			var v vote
+			for _, a := range se.Attr {
+				switch a.Name.Local {
+				case "r":
+					if c := util.XlsxColIndexFromAxis(a.Value); c > 0 {
+						col = c
 					}
-					res[col] = CellMeta{
-						StyleId: styleIdFix,
-						TypeIdx: cellTypeRaw2Idx(typeRaw),
-						TypeRaw: typeRaw,
+				case "s":
+					if v.style, err = strconv.Atoi(a.Value); err != nil {
+						return nil, err
 					}
+				case "t":
+					v.typeRaw = a.Value
 				}
 			}
+			if v.typeRaw == "n" { // 与缺省类型一致
+				v.typeRaw = ""
+			}
+			var cell struct {
+				V  string    `xml:"v"`
+				IS *struct{} `xml:"is"`
+			}
+			if err := decoder.DecodeElement(&cell, &se); err != nil {
+				return nil, err
+			}
+			maxCol = max(maxCol, col)
+			if cell.V == "" && cell.IS == nil {
+				if _, ok := blank[col]; !ok {
+					blank[col] = vote{style: v.style}
+				}
+				continue
+			}
+			if counts[col] == nil {
+				counts[col] = map[vote]int{}
+			}
+			if counts[col][v] == 0 {
+				order[col] = append(order[col], v)
+			}
+			counts[col][v]++
 		case xml.EndElement:
-			// </row>，目标行结束，立刻退出
-			if inTargetRow && se.Name.Local == "row" {
-				return res, nil
+			if se.Name.Local == "sheetData" {
+				break scan
 			}
 		}
 	}
+	res := make(map[int]CellMeta, maxCol)
+	for c := 1; c <= maxCol; c++ {
+		best, votes, samples := blank[c], 0, 0
+		for _, v := range order[c] {
+			n := counts[c][v]
+			samples += n
+			if n > votes {
+				best, votes = v, n
+			}
+		}
+		numFmt := wb.NumFmt(best.style)
+		m := CellMeta{
+			StyleId: best.style,
+			TypeIdx: cellTypeRaw2Idx(best.typeRaw),
+			TypeRaw: best.typeRaw,
+			Date:    best.typeRaw == "d" || (numFmt.Date && best.typeRaw == ""),
+			NumFmt:  numFmt.ID,
+			FmtCode: numFmt.Code,
+			Votes:   votes,
+			Samples: samples,
+		}
+		if (numFmt.Text() || numFmt.ZeroPad() > 0) && (best.typeRaw == "" || best.typeRaw == "s") {
+			// 文本格式、补零格式（邮编、编码等）的列按文本写入，数值单元格读取时已补足前导零
+			m.TypeIdx, m.TypeRaw, m.Date = excelize.CellTypeSharedString, "s", false
+		}
+		res[c] = m
+	}
 	return res, nil
 }
 
 // getRows()
 // 不要读取 dimension 信息来获取行数，通过程序生成的表格文件可能并不包含该信息
-func getRows(file string) (int, error) {
+func getRows(file string, opts option.Source) (int, error) {
 	// f, err := excelize.OpenFile(file)
 	// if err != nil {
 	// 	return 0, err
@@ -202,29 +295,29 @@ func getRows(file string) (int, error) {
 	// }
 	// return count, nil
 
-	f, err := excelize.OpenFile(file, excelize.Options{
-		UnzipSizeLimit:    8 << 30, // 8GB
-		UnzipXMLSizeLimit: 4 << 30, // 4GB
-	})
-	if err != nil {
-		return 0, err
-	}
-	defer f.Close()
-	rows, err := f.Rows(f.GetSheetName(0))
-	if err != nil {
-		return 0, err
-	}
-	count := 0
-	for rows.Next() {
-		count++
+	return source.Count(file, opts)
+}
+
+// refuseOverflow
+// 合并后的行数（含行首）超出 Excel 最大行数则返回错误；filter 非空时按去除重复行后的行数计算
+func refuseOverflow(srcPaths []string, opts option.Source, filter *dedup.Filter) error {
+	var srcRows int
+	if filter != nil {
+		srcRows = filter.Remaining()
+	} else {
+		n, err := CalcRows(srcPaths, opts)
+		if err != nil {
+			return err
+		}
+		srcRows = n
 	}
-	if count > 0 { // 减去行首
-		count--
+	if srcRows+1 > maxRows {
+		return fmt.Errorf("合并后共%d行（含行首），超出 Excel 最大行数 %d，已拒绝合并", srcRows+1, maxRows)
 	}
-	return count, nil
+	return nil
 }
 
-func CalcRows(files []string) (int, error) {
+func CalcRows(files []string, opts option.Source) (int, error) {
 	// totalRows := 0
 	// for i, file := range files {
 	// 	start := time.Now()
@@ -250,7 +343,7 @@ func CalcRows(files []string) (int, error) {
 		go func(f string) {
 			defer wg.Done()
 			start := time.Now()
-			rows, err := getRows(file)
+			rows, err := getRows(file, opts)
 			if err != nil {
 				errMu.Lock()
 				errors = append(errors, err)
@@ -271,6 +364,186 @@ func CalcRows(files []string) (int, error) {
 	return totalRows, nil
 }
 
+// readMergeMeta
+// 解析各数据文件的数据格式，列数、样式或类型不一致则无法合并；
+// CSV、xls 数据格式为推断所得，仅检查列数，混合合并时以 xlsx 文件为准；同时返回数据格式所属的数据文件。
+// 样式按定义而非 ID 比较，逐单元格保留样式时各文件的样式分别导入，只检查类型
+func readMergeMeta(srcPaths []string, srcSizes []int64, sample int, srcOpts option.Source, styles *styleTable) (map[int]CellMeta, string, error) {
+	var (
+		meta     map[int]CellMeta
+		metaFile string
+	)
+	for i, file := range srcPaths {
+		m, err := readCellMeta(file, sample, srcOpts)
+		if err != nil {
+			return nil, "", err
+		}
+		msg, err := describeMeta(m)
+		if err != nil {
+			return nil, "", err
+		}
+		log.Printf("%s：数据格式 %s", filepath.Base(file), msg)
+		fmt.Printf("数据文件%d：%s，%s，%d列\n",
+			i+1, color.HiYellowString(filepath.Base(file)), util.SizeReadable(srcSizes[i]), len(m))
+		if i == 0 {
+			meta, metaFile = m, file
+			continue
+		}
+		if len(m) != len(meta) {
+			return nil, "", fmt.Errorf("列数不一致：%s（%d列），%s（%d）列",
+				filepath.Base(srcPaths[0]), len(meta), filepath.Base(file), len(m))
+		}
+		if !source.HasCellMeta(file) {
+			continue
+		}
+		if !source.HasCellMeta(metaFile) {
+			meta, metaFile = m, file
+			continue
+		}
+		for k, v := range m {
+			same := true
+			if !styles.cells {
+				if same, err = styles.Equal(metaFile, meta[k].StyleId, file, v.StyleId); err != nil {
+					return nil, "", err
+				}
+			}
+			if !same || v.TypeIdx != meta[k].TypeIdx {
+				col, err := excelize.ColumnNumberToName(k)
+				if err != nil {
+					return nil, "", err
+				}
+				return nil, "", fmt.Errorf("%s列数据格式不一致：%s（样式 %d 类型 %s），%s（样式 %d 类型 %s）",
+					col, filepath.Base(metaFile), meta[k].StyleId, meta[k].TypeRaw,
+					filepath.Base(file), v.StyleId, v.TypeRaw)
+			}
+		}
+	}
+	return meta, metaFile, nil
+}
+
+// newHeaderRow
+// 行首不检查 CellType
+func newHeaderRow(row []string, meta map[int]CellMeta) []any {
+	rowNew := make([]any, len(row))
+	for c := range row {
+		rowNew[c] = excelize.Cell{
+			StyleID: meta[c+1].StyleId,
+			Value:   row[c],
+		}
+	}
+	return rowNew
+}
+
+// newDataRow
+// 按列数据格式转换数据行，数值列无法解析则按原值写入并记录日志，会丢失精度的数值经 guard 按文本写入
+func newDataRow(row []string, meta map[int]CellMeta, guard *textGuard, file string, line int) ([]any, error) {
+	return newDataRowFrom(row, meta, guard, func(c int) (string, int, int) { return file, line, c + 1 })
+}
+
+// cellSource
+// 第 c 列（从0开始）值的来源：数据文件、行号及列号（从1开始），用于异常值日志
+type cellSource func(c int) (file string, line int, col int)
+
+// newDataRowFrom
+// 同 newDataRow，各列的值可来自不同数据文件（拼接）
+func newDataRowFrom(row []string, meta map[int]CellMeta, guard *textGuard, src cellSource) ([]any, error) {
+	rowNew := make([]any, len(row))
+	for c := range row {
+		cell := excelize.Cell{
+			StyleID: meta[c+1].StyleId,
+		}
+		if row[c] == "" {
+			cell.Value = nil
+		} else if !isTextType(meta[c+1].TypeIdx) && slices.Contains(cellErrors, row[c]) {
+			// 流式写入不支持错误值类型，以常量公式写入并缓存原值，打开时重算为错误值
+			cell.Formula, cell.Value = row[c], row[c]
+		} else if meta[c+1].Date {
+			value, ok := dateValue(row[c])
+			if !ok {
+				file, line, col := src(c)
+				name, err := excelize.ColumnNumberToName(col)
+				if err != nil {
+					return nil, err
+				}
+				log.Printf("%s：位置 %s%d，数据类型 日期，异常数据类型值 %s",
+					filepath.Base(file), name, line, row[c])
+			}
+			cell.Value = value
+		} else if meta[c+1].TypeIdx == excelize.CellTypeNumber ||
+			meta[c+1].TypeIdx == excelize.CellTypeUnset {
+			valFix, err := strconv.ParseFloat(row[c], 64)
+			if err == nil && guard.Keep(c, row[c], src) {
+				cell.Value = row[c]
+			} else if err == nil {
+				cell.Value = valFix
+			} else {
+				cell.Value = row[c]
+				file, line, col := src(c)
+				name, err := excelize.ColumnNumberToName(col)
+				if err != nil {
+					return nil, err
+				}
+				log.Printf("%s：位置 %s%d，数据类型 %s，异常数据类型值 %s",
+					filepath.Base(file), name, line, meta[c+1].TypeRaw, row[c])
+			}
+		} else if meta[c+1].TypeIdx == excelize.CellTypeBool {
+			value, ok := boolValue(row[c])
+			if !ok {
+				file, line, col := src(c)
+				name, err := excelize.ColumnNumberToName(col)
+				if err != nil {
+					return nil, err
+				}
+				log.Printf("%s：位置 %s%d，数据类型 %s，异常数据类型值 %s",
+					filepath.Base(file), name, line, meta[c+1].TypeRaw, row[c])
+			}
+			cell.Value = value
+		} else if meta[c+1].TypeIdx == excelize.CellTypeError { // 同列其他值多为公式计算的数值
+			if valFix, err := strconv.ParseFloat(row[c], 64); err == nil && !guard.Keep(c, row[c], src) {
+				cell.Value = valFix
+			} else {
+				cell.Value = row[c]
+			}
+		} else { // excelize.CellTypeInlineString, excelize.CellTypeSharedString, excelize.CellTypeFormula
+			cell.Value = row[c]
+		}
+		rowNew[c] = cell
+	}
+	return rowNew, nil
+}
+
+// isTextType
+// 文本列（含公式文本结果）中形如错误值的内容按文本写入
+func isTextType(t excelize.CellType) bool {
+	return t == excelize.CellTypeSharedString || t == excelize.CellTypeInlineString || t == excelize.CellTypeFormula
+}
+
+// boolValue
+// TRUE/FALSE（不区分大小写）或 1/0 写为布尔值，无法解析则按原值写入
+func boolValue(s string) (any, bool) {
+	switch strings.ToUpper(s) {
+	case "TRUE", "1":
+		return true, true
+	case "FALSE", "0":
+		return false, true
+	}
+	return s, false
+}
+
+// dateValue
+// ISO 格式的日期、日期时间写为 time.Time，时间写为一天中的比例，无法解析则按原值写入
+func dateValue(s string) (any, bool) {
+	t, kind := source.ParseDate(s)
+	switch kind {
+	case source.DateNone:
+		return s, false
+	case source.TimeOnly:
+		return float64(t.Hour()*3600+t.Minute()*60+t.Second()) / 86400, true
+	default:
+		return t, true
+	}
+}
+
 // MergeXlsx2xlsxV1
 // 适用于小文件
 func MergeXlsx2xlsxV1(srcPaths []string, tarPath string, ctx context.Context) error {
@@ -434,10 +707,18 @@ func MergeXlsx2xlsxV1(srcPaths []string, tarPath string, ctx context.Context) er
 
 // MergeXlsx2xlsxV2
 // 适用于大文件
-func MergeXlsx2xlsxV2(srcPaths []string, tarPath string, ctx context.Context) error {
+func MergeXlsx2xlsxV2(srcPaths []string, tarPath string, opts option.Options, ctx context.Context) error {
+	opts.Source.RawNumbers = true // 数值读取原始值，沿用数据文件的数字格式写入
 	start := time.Now()
 	fmt.Println("正在解析…")
 
+	// 超出 Excel 最大行数则拒绝合并，避免生成无法打开的文件；去重时按去除重复行后的行数判断
+	if opts.Overflow == option.OverflowRefuse && !opts.Dedup.Enabled {
+		if err := refuseOverflow(srcPaths, opts.Source, nil); err != nil {
+			return err
+		}
+	}
+
 	// 获取文件大小，用于估算进度
 	srcSizes := make([]int64, len(srcPaths))
 	for i, file := range srcPaths {
@@ -449,52 +730,11 @@ func MergeXlsx2xlsxV2(srcPaths []string, tarPath string, ctx context.Context) er
 	}
 
 	// 解析数据格式
-	var meta map[int]CellMeta
-	for i, file := range srcPaths {
-		m, err := readXlsxStyleAndType(file)
-		if err != nil {
-			return err
-		}
-		var msg strings.Builder
-		for j := range len(m) {
-			col, err := excelize.ColumnNumberToName(j + 1)
-			if err != nil {
-				return err
-			}
-			fmt.Fprintf(&msg, "%s列 样式 %d 类型 %s，", col, m[j].StyleId, m[j].TypeRaw)
-		}
-		log.Printf("%s：数据格式 %s", filepath.Base(file), strings.TrimSuffix(msg.String(), "，"))
-		fmt.Printf("数据文件%d：%s，%s，%d列\n",
-			i+1, color.HiYellowString(filepath.Base(file)), util.SizeReadable(srcSizes[i]), len(m))
-		if i == 0 {
-			meta = m
-			continue
-		}
-		if len(m) != len(meta) {
-			return fmt.Errorf("列数不一致：%s（%d列），%s（%d）列",
-				filepath.Base(srcPaths[0]), len(meta), filepath.Base(file), len(m))
-		}
-		for k, v := range m {
-			if v.StyleId != meta[k].StyleId || v.TypeIdx != meta[k].TypeIdx {
-				col, err := excelize.ColumnNumberToName(k)
-				if err != nil {
-					return err
-				}
-				return fmt.Errorf("%s列数据格式不一致：%s（样式 %d 类型 %s），%s（样式 %d 类型 %s）",
-					col, filepath.Base(srcPaths[0]), meta[k].StyleId, meta[k].TypeRaw,
-					filepath.Base(file), v.StyleId, v.TypeRaw)
-			}
-		}
-	}
-	for k, v := range meta { // 日志输出不支持的 CellTyle
-		if v.TypeIdx != excelize.CellTypeNumber && v.TypeIdx != excelize.CellTypeUnset &&
-			v.TypeIdx != excelize.CellTypeInlineString && v.TypeIdx != excelize.CellTypeSharedString {
-			col, err := excelize.ColumnNumberToName(k)
-			if err != nil {
-				return err
-			}
-			log.Printf("%s：%s列数据类型 %s 暂不支持", filepath.Base(srcPaths[0]), col, v.TypeRaw)
-		}
+	styles := newStyleTable(opts.CellStyle, opts.Source)
+	defer styles.Close()
+	meta, metaFile, err := readMergeMeta(srcPaths, srcSizes, opts.Sample, opts.Source, styles)
+	if err != nil {
+		return err
 	}
 
 	// // 新建文件，使用 excelize 默认模板（字体为 Calibri）
@@ -507,18 +747,45 @@ func MergeXlsx2xlsxV2(srcPaths []string, tarPath string, ctx context.Context) er
 	// 	return err
 	// }
 
-	// 使用模板文件（来自 Excel 2016+ 创建的空文件）
-	tarFile, err := excelize.OpenReader(bytes.NewReader(templateXlsx))
+	// 去重需预先扫描全部数据文件
+	var filter *dedup.Filter
+	if opts.Dedup.Enabled {
+		f, err := dedup.Scan(srcPaths, opts.Dedup, opts.Source, ctx)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		filter = f
+		if opts.Overflow == option.OverflowRefuse {
+			if err := refuseOverflow(srcPaths, opts.Source, filter); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 沿用数据文件的列宽、冻结窗格及自动筛选
+	layout, err := readLayout(metaFile, opts.Source)
 	if err != nil {
 		return err
 	}
-	tarSheet := "data"
-	sw, err := tarFile.NewStreamWriter(tarSheet) // 流式写入（不爆内存，注意始终从首行开始）
+
+	// 使用模板文件，超出 Excel 最大行数时续写至新表或新文件
+	tarFile, err := newSheetWriter(tarPath, opts, styles)
 	if err != nil {
 		return err
 	}
+	if meta, err = styles.Meta(meta, metaFile); err != nil {
+		tarFile.Close()
+		return err
+	}
+	if err := tarFile.SetLayout(layout); err != nil {
+		tarFile.Close()
+		return err
+	}
 
 	fmt.Printf("正在合并… %s\n", color.HiBlackString("(停止：Ctrl+C)"))
+	keeper := newFormulaKeeper(opts.Formula)
+	guard := newTextGuard(opts.TextCols)
 	wroteHeader := false
 	totalRows := 0
 	for i, file := range srcPaths {
@@ -528,27 +795,27 @@ func MergeXlsx2xlsxV2(srcPaths []string, tarPath string, ctx context.Context) er
 			return ctx.Err()
 		default:
 		} // 响应 Ctrl+C 打断
-		f, err := excelize.OpenFile(file, excelize.Options{
-			UnzipSizeLimit:    8 << 30, // 8GB
-			UnzipXMLSizeLimit: 4 << 30, // 4GB
-		})
+		iter, err := source.Open(file, opts.Source) // 流式读取（不会一次性加载整表）
 		if err != nil {
 			tarFile.Close()
 			return err
 		}
-		// defer f.Close() // 循环中不使用该方法
-		sheet := f.GetSheetName(0) // 只读第一张表
-		iter, err := f.Rows(sheet) // 流式读取（不会一次性加载整表）
-		if err != nil {
-			f.Close()
-			tarFile.Close()
-			return err
+		// defer iter.Close() // 循环中不使用该方法
+		last := 0 // 引用不能越过数据文件末行
+		if opts.Formula == option.FormulaKeep {
+			if last, err = source.Count(file, opts.Source); err != nil {
+				iter.Close()
+				tarFile.Close()
+				return err
+			}
+			last++
 		}
+		keeper.Reset(file, last)
 		fileRows := 0
 		for iter.Next() {
 			select {
 			case <-ctx.Done():
-				f.Close()
+				iter.Close()
 				tarFile.Close()
 				return ctx.Err()
 			default:
@@ -557,54 +824,62 @@ func MergeXlsx2xlsxV2(srcPaths []string, tarPath string, ctx context.Context) er
 			totalRows++
 			row, err := iter.Columns()
 			if err != nil {
-				f.Close()
+				iter.Close()
 				tarFile.Close()
 				return err
 			}
-			rowNew := make([]any, len(row))
+			if fileRows > 1 && filter != nil { // 去除重复行
+				drop, err := filter.Drop(i)
+				if err != nil {
+					iter.Close()
+					tarFile.Close()
+					return err
+				}
+				if drop {
+					continue
+				}
+			}
 			if fileRows == 1 { // 控制只写一次行首
 				if wroteHeader {
 					continue
 				}
 				wroteHeader = true
-				for c := range row { // 行首不检查 CellType
-					rowNew[c] = excelize.Cell{
-						StyleID: meta[c+1].StyleId,
-						Value:   row[c],
-					}
+				if meta, err = guard.Meta(meta, row, styles); err != nil {
+					iter.Close()
+					tarFile.Close()
+					return err
 				}
-			} else {
-				for c := range row {
-					cell := excelize.Cell{
-						StyleID: meta[c+1].StyleId,
-					}
-					if row[c] == "" {
-						cell.Value = nil
-					} else if meta[c+1].TypeIdx == excelize.CellTypeNumber ||
-						meta[c+1].TypeIdx == excelize.CellTypeUnset {
-						valFix, err := strconv.ParseFloat(row[c], 64)
-						if err == nil {
-							cell.Value = valFix
-						} else {
-							cell.Value = row[c]
-							col, err := excelize.ColumnNumberToName(c + 1)
-							if err != nil {
-								f.Close()
-								tarFile.Close()
-								return err
-							}
-							log.Printf("%s：位置 %s%d，数据类型 %s，异常数据类型值 %s",
-								filepath.Base(file), col, fileRows, meta[c+1].TypeRaw, row[c])
-						}
-					} else { // excelize.CellTypeInlineString, excelize.CellTypeSharedString
-						cell.Value = row[c]
-					}
-					rowNew[c] = cell
+				rowNew, err := styles.Apply(newHeaderRow(row, meta), iter, file)
+				if err != nil {
+					iter.Close()
+					tarFile.Close()
+					return err
+				}
+				if err := tarFile.SetHeader(rowNew, file); err != nil {
+					iter.Close()
+					tarFile.Close()
+					return err
 				}
+				continue
 			}
-			axis := fmt.Sprintf("A%d", totalRows-i)
-			if err := sw.SetRow(axis, rowNew); err != nil {
-				f.Close()
+			rowNew, err := newDataRow(row, meta, guard, file, fileRows)
+			if err != nil {
+				iter.Close()
+				tarFile.Close()
+				return err
+			}
+			end := fileRows + tarFile.RowsLeft() - 1
+			if next, ok := filter.NextDrop(i); ok {
+				end = min(end, next-1)
+			}
+			keeper.Apply(rowNew, iter, fileRows, tarFile.NextRow(), end)
+			if rowNew, err = styles.Apply(rowNew, iter, file); err != nil {
+				iter.Close()
+				tarFile.Close()
+				return err
+			}
+			if err := tarFile.AddRow(rowNew, file, fileRows); err != nil {
+				iter.Close()
 				tarFile.Close()
 				return err
 			}
@@ -616,7 +891,7 @@ func MergeXlsx2xlsxV2(srcPaths []string, tarPath string, ctx context.Context) er
 				}
 			}
 		}
-		f.Close()
+		iter.Close()
 		sizeDone, sizeTodo := int64(0), int64(0)
 		for j := range srcSizes {
 			if j <= i {
@@ -632,52 +907,61 @@ func MergeXlsx2xlsxV2(srcPaths []string, tarPath string, ctx context.Context) er
 			fmt.Printf("数据文件%d：读取完成，共%s\n", i+1, color.HiYellowString("%d行", fileRows-1))
 		}
 	}
+	dataRows := totalRows - len(srcPaths)
+	if filter != nil {
+		dataRows -= filter.Total()
+	}
+	keeper.Report()
+	return saveMerged(tarFile, srcPaths, filter, guard, dataRows, start)
+}
+
+// saveMerged
+// 保存合并文件并输出汇总
+func saveMerged(tarFile *sheetWriter, srcPaths []string, filter *dedup.Filter, guard *textGuard, dataRows int, start time.Time) error {
 	fmt.Println("正在生成…")
-	sw.Flush()
-	if err := tarFile.SaveAs(tarPath); err != nil {
+	if err := tarFile.Save(); err != nil {
 		tarFile.Close()
 		return err
 	}
-	tarFile.Close()
-	info, err := os.Stat(tarPath)
-	if err != nil {
-		return err
+	if filter != nil {
+		filter.Report(srcPaths)
+	}
+	tarFile.notes.Report()
+	tarFile.merges.Report()
+	guard.Report()
+	tarSize := int64(0)
+	for _, path := range tarFile.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		tarSize += info.Size()
 	}
 	fmt.Printf("合并完成，%s，共%s数据，耗时%s\n",
-		util.SizeReadable(info.Size()), color.HiYellowString("%d行", totalRows-len(srcPaths)), util.Cost(start))
-	fmt.Printf("合并文件：%s%s\n", strings.TrimSuffix(tarPath, filepath.Base(tarPath)),
-		color.HiYellowString(filepath.Base(tarPath)))
-	return nil
+		util.SizeReadable(tarSize), color.HiYellowString("%d行", dataRows), util.Cost(start))
+	for _, path := range tarFile.Paths {
+		fmt.Printf("合并文件：%s%s\n", strings.TrimSuffix(path, filepath.Base(path)),
+			color.HiYellowString(filepath.Base(path)))
+	}
+	return tarFile.protect.report(manifestPath(tarFile.path))
 }
 
-func SplitXlsx2xlsxByLine(srcPath string, tarDir string, lineCount int, ctx context.Context) error {
+func SplitXlsx2xlsxByLine(srcPath string, tarDir string, lineCount int, opts option.Options, ctx context.Context) error {
+	opts.Source.RawNumbers = true // 数值读取原始值，沿用数据文件的数字格式写入
 	start := time.Now()
+	protect := newProtector(opts.Protect)
 	fmt.Println("正在解析…")
 
 	// 解析数据格式
-	meta, err := readXlsxStyleAndType(srcPath)
+	meta, err := readCellMeta(srcPath, opts.Sample, opts.Source)
 	if err != nil {
 		return err
 	}
-	var msg strings.Builder
-	for j := range len(meta) {
-		col, err := excelize.ColumnNumberToName(j + 1)
-		if err != nil {
-			return err
-		}
-		fmt.Fprintf(&msg, "%s列 样式 %d 类型 %s，", col, meta[j].StyleId, meta[j].TypeRaw)
-	}
-	log.Printf("%s：数据格式 %s", filepath.Base(srcPath), strings.TrimSuffix(msg.String(), "，"))
-	for k, v := range meta { // 日志输出不支持的 CellTyle
-		if v.TypeIdx != excelize.CellTypeNumber && v.TypeIdx != excelize.CellTypeUnset &&
-			v.TypeIdx != excelize.CellTypeInlineString && v.TypeIdx != excelize.CellTypeSharedString {
-			col, err := excelize.ColumnNumberToName(k)
-			if err != nil {
-				return err
-			}
-			log.Printf("%s：%s列数据类型 %s 暂不支持", filepath.Base(srcPath), col, v.TypeRaw)
-		}
+	msg, err := describeMeta(meta)
+	if err != nil {
+		return err
 	}
+	log.Printf("%s：数据格式 %s", filepath.Base(srcPath), msg)
 	info, err := os.Stat(srcPath)
 	if err != nil {
 		return err
@@ -686,19 +970,45 @@ func SplitXlsx2xlsxByLine(srcPath string, tarDir string, lineCount int, ctx cont
 		color.HiYellowString(filepath.Base(srcPath)), util.SizeReadable(info.Size()), len(meta))
 
 	fmt.Printf("正在按每%s拆分… %s\n", color.HiYellowString("%d行", lineCount), color.HiBlackString("(停止：Ctrl+C)"))
-	srcFile, err := excelize.OpenFile(srcPath, excelize.Options{
-		UnzipSizeLimit:    8 << 30, // 8GB
-		UnzipXMLSizeLimit: 4 << 30, // 4GB
-	})
+	iter, err := source.Open(srcPath, opts.Source)
 	if err != nil {
 		return err
 	}
-	srcSheet := srcFile.GetSheetName(0)
-	iter, err := srcFile.Rows(srcSheet)
 	iter.Next()
 	rowHeader, err := iter.Columns()
 	if err != nil {
-		srcFile.Close()
+		iter.Close()
+		return err
+	}
+	styles := newStyleTable(opts.CellStyle, opts.Source)
+	defer styles.Close()
+	if meta, err = styles.Meta(meta, srcPath); err != nil {
+		iter.Close()
+		return err
+	}
+	guard := newTextGuard(opts.TextCols)
+	if meta, err = guard.Meta(meta, rowHeader, styles); err != nil {
+		iter.Close()
+		return err
+	}
+	rowHeaderNew, err := styles.Apply(newHeaderRow(rowHeader, meta), iter, srcPath)
+	if err != nil {
+		iter.Close()
+		return err
+	}
+	layout, err := readLayout(srcPath, opts.Source) // 沿用数据文件的列宽、冻结窗格及自动筛选
+	if err != nil {
+		iter.Close()
+		return err
+	}
+	notes := newNoteCopier(opts.Source)
+	if err := notes.SetHeader(srcPath); err != nil {
+		iter.Close()
+		return err
+	}
+	merges := newMergeKeeper(opts.Merged, opts.Source)
+	if err := merges.SetHeader(srcPath); err != nil {
+		iter.Close()
 		return err
 	}
 	var (
@@ -708,13 +1018,21 @@ func SplitXlsx2xlsxByLine(srcPath string, tarDir string, lineCount int, ctx cont
 		tarPathIdx int
 		totalRows  int
 		fileRows   int
+		recalc     bool // 当前文件已设置打开即重算
 	)
+	keeper := newFormulaKeeper(opts.Formula)
 	for iter.Next() {
 		if totalRows%lineCount == 0 {
 			if tarPathIdx > 0 {
+				if err := setFilter(tarFile, "data", layout, fileRows+1); err != nil {
+					tarFile.Close()
+					iter.Close()
+					return err
+				}
 				sw.Flush()
-				if err := tarFile.SaveAs(tarPath); err != nil {
+				if err := protect.saveAs(tarFile, tarPath); err != nil {
 					tarFile.Close()
+					iter.Close()
 					return err
 				}
 				tarFile.Close()
@@ -725,33 +1043,48 @@ func SplitXlsx2xlsxByLine(srcPath string, tarDir string, lineCount int, ctx cont
 			// 使用模板文件（来自 Excel 2016+ 创建的空文件）
 			tarFile, err = excelize.OpenReader(bytes.NewReader(templateXlsx))
 			if err != nil {
-				srcFile.Close()
+				iter.Close()
 				return err
 			}
 			sw, err = tarFile.NewStreamWriter("data") // 流式写入（不爆内存，注意始终从首行开始）
 			if err != nil {
 				tarFile.Close()
-				srcFile.Close()
+				iter.Close()
 				return err
 			}
-			rowNew := make([]any, len(rowHeader))
-			for c := range rowHeader { // 行首不检查 CellType
-				rowNew[c] = excelize.Cell{
-					StyleID: meta[c+1].StyleId,
-					Value:   rowHeader[c],
-				}
+			if err := setLayout(sw, layout); err != nil {
+				tarFile.Close()
+				iter.Close()
+				return err
+			}
+			rowNew, err := styles.Resolve(tarFile, rowHeaderNew)
+			if err != nil {
+				tarFile.Close()
+				iter.Close()
+				return err
 			}
 			if err := sw.SetRow("A1", rowNew); err != nil {
 				tarFile.Close()
-				srcFile.Close()
+				iter.Close()
 				return err
 			}
-			fileRows = 0
+			if err := merges.Header(sw); err != nil {
+				tarFile.Close()
+				iter.Close()
+				return err
+			}
+			if err := notes.Header(tarFile, "data"); err != nil {
+				tarFile.Close()
+				iter.Close()
+				return err
+			}
+			fileRows, recalc = 0, false
+			keeper.Reset(srcPath, totalRows+1+lineCount)
 		}
 		select {
 		case <-ctx.Done():
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return ctx.Err()
 		default:
 		} // 响应 Ctrl+C 打断
@@ -760,41 +1093,48 @@ func SplitXlsx2xlsxByLine(srcPath string, tarDir string, lineCount int, ctx cont
 		row, err := iter.Columns()
 		if err != nil {
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return err
 		}
-		rowNew := make([]any, len(row))
-		for c := range row {
-			cell := excelize.Cell{
-				StyleID: meta[c+1].StyleId,
-			}
-			if row[c] == "" {
-				cell.Value = nil
-			} else if meta[c+1].TypeIdx == excelize.CellTypeNumber ||
-				meta[c+1].TypeIdx == excelize.CellTypeUnset {
-				valFix, err := strconv.ParseFloat(row[c], 64)
-				if err == nil {
-					cell.Value = valFix
-				} else {
-					cell.Value = row[c]
-					col, err := excelize.ColumnNumberToName(c + 1)
-					if err != nil {
-						tarFile.Close()
-						srcFile.Close()
-						return err
-					}
-					log.Printf("%s：位置 %s%d，数据类型 %s，异常数据类型值 %s",
-						filepath.Base(srcPath), col, fileRows, meta[c+1].TypeRaw, row[c])
-				}
-			} else { // excelize.CellTypeInlineString, excelize.CellTypeSharedString
-				cell.Value = row[c]
+		rowNew, err := newDataRow(row, meta, guard, srcPath, totalRows+1)
+		if err != nil {
+			tarFile.Close()
+			iter.Close()
+			return err
+		}
+		keeper.Apply(rowNew, iter, totalRows+1, fileRows+1, 0)
+		if rowNew, err = styles.Apply(rowNew, iter, srcPath); err != nil {
+			tarFile.Close()
+			iter.Close()
+			return err
+		}
+		if !recalc && hasFormula(rowNew) {
+			if err := setRecalc(tarFile); err != nil {
+				tarFile.Close()
+				iter.Close()
+				return err
 			}
-			rowNew[c] = cell
+			recalc = true
+		}
+		if rowNew, err = styles.Resolve(tarFile, rowNew); err != nil {
+			tarFile.Close()
+			iter.Close()
+			return err
 		}
 		axis := fmt.Sprintf("A%d", fileRows+1)
 		if err := sw.SetRow(axis, rowNew); err != nil {
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
+			return err
+		}
+		if err := merges.Apply(sw, srcPath, totalRows+1, fileRows+1); err != nil {
+			tarFile.Close()
+			iter.Close()
+			return err
+		}
+		if err := notes.Apply(tarFile, "data", srcPath, totalRows+1, fileRows+1); err != nil {
+			tarFile.Close()
+			iter.Close()
 			return err
 		}
 		if totalRows%10000 == 0 {
@@ -806,52 +1146,48 @@ func SplitXlsx2xlsxByLine(srcPath string, tarDir string, lineCount int, ctx cont
 		}
 	}
 	if tarPathIdx > 0 {
+		if err := setFilter(tarFile, "data", layout, fileRows+1); err != nil {
+			tarFile.Close()
+			return err
+		}
 		sw.Flush()
-		if err := tarFile.SaveAs(tarPath); err != nil {
+		if err := protect.saveAs(tarFile, tarPath); err != nil {
 			tarFile.Close()
 			return err
 		}
 		tarFile.Close()
 		fmt.Printf("数据文件%d：写入完成，共%d行\n", tarPathIdx, fileRows)
 	}
-	srcFile.Close()
+	iter.Close()
 	fmt.Printf("拆分完成，共%s，分为%s文件，耗时%s\n",
 		color.HiYellowString("%d行", totalRows), color.HiYellowString("%d个", tarPathIdx), util.Cost(start))
 	fmt.Printf("拆分文件夹：%s%s\n", strings.TrimSuffix(tarDir, filepath.Base(tarDir)),
 		color.HiYellowString(filepath.Base(tarDir)))
-	return nil
+	keeper.Report()
+	notes.Report()
+	merges.Report()
+	guard.Report()
+	return protect.report(tarDir + "-passwords.csv")
 }
 
-func SplitXlsx2xlsxByFile(srcPath string, tarDir string, fileCount int, ctx context.Context) error {
+func SplitXlsx2xlsxByFile(srcPath string, tarDir string, fileCount int, opts option.Options, ctx context.Context) error {
+	opts.Source.RawNumbers = true // 数值读取原始值，沿用数据文件的数字格式写入
 	start := time.Now()
+	protect := newProtector(opts.Protect)
 	fmt.Println("正在解析…")
 
 	// 解析数据格式
-	meta, err := readXlsxStyleAndType(srcPath)
+	meta, err := readCellMeta(srcPath, opts.Sample, opts.Source)
 	if err != nil {
 		return err
 	}
-	var msg strings.Builder
-	for j := range len(meta) {
-		col, err := excelize.ColumnNumberToName(j + 1)
-		if err != nil {
-			return err
-		}
-		fmt.Fprintf(&msg, "%s列 样式 %d 类型 %s，", col, meta[j].StyleId, meta[j].TypeRaw)
-	}
-	log.Printf("%s：数据格式 %s", filepath.Base(srcPath), strings.TrimSuffix(msg.String(), "，"))
-	for k, v := range meta { // 日志输出不支持的 CellTyle
-		if v.TypeIdx != excelize.CellTypeNumber && v.TypeIdx != excelize.CellTypeUnset &&
-			v.TypeIdx != excelize.CellTypeInlineString && v.TypeIdx != excelize.CellTypeSharedString {
-			col, err := excelize.ColumnNumberToName(k)
-			if err != nil {
-				return err
-			}
-			log.Printf("%s：%s列数据类型 %s 暂不支持", filepath.Base(srcPath), col, v.TypeRaw)
-		}
+	msg, err := describeMeta(meta)
+	if err != nil {
+		return err
 	}
+	log.Printf("%s：数据格式 %s", filepath.Base(srcPath), msg)
 
-	srcRows, err := getRows(srcPath)
+	srcRows, err := getRows(srcPath, opts.Source)
 	if err != nil {
 		return err
 	}
@@ -867,19 +1203,45 @@ func SplitXlsx2xlsxByFile(srcPath string, tarDir string, fileCount int, ctx cont
 		color.HiYellowString(filepath.Base(srcPath)), util.SizeReadable(info.Size()), len(meta), srcRows)
 
 	fmt.Printf("正在拆分为%s文件… %s\n", color.HiYellowString("%d个", fileCount), color.HiBlackString("(停止：Ctrl+C)"))
-	srcFile, err := excelize.OpenFile(srcPath, excelize.Options{
-		UnzipSizeLimit:    8 << 30, // 8GB
-		UnzipXMLSizeLimit: 4 << 30, // 4GB
-	})
+	iter, err := source.Open(srcPath, opts.Source)
 	if err != nil {
 		return err
 	}
-	srcSheet := srcFile.GetSheetName(0)
-	iter, err := srcFile.Rows(srcSheet)
 	iter.Next()
 	rowHeader, err := iter.Columns()
 	if err != nil {
-		srcFile.Close()
+		iter.Close()
+		return err
+	}
+	styles := newStyleTable(opts.CellStyle, opts.Source)
+	defer styles.Close()
+	if meta, err = styles.Meta(meta, srcPath); err != nil {
+		iter.Close()
+		return err
+	}
+	guard := newTextGuard(opts.TextCols)
+	if meta, err = guard.Meta(meta, rowHeader, styles); err != nil {
+		iter.Close()
+		return err
+	}
+	rowHeaderNew, err := styles.Apply(newHeaderRow(rowHeader, meta), iter, srcPath)
+	if err != nil {
+		iter.Close()
+		return err
+	}
+	layout, err := readLayout(srcPath, opts.Source) // 沿用数据文件的列宽、冻结窗格及自动筛选
+	if err != nil {
+		iter.Close()
+		return err
+	}
+	notes := newNoteCopier(opts.Source)
+	if err := notes.SetHeader(srcPath); err != nil {
+		iter.Close()
+		return err
+	}
+	merges := newMergeKeeper(opts.Merged, opts.Source)
+	if err := merges.SetHeader(srcPath); err != nil {
+		iter.Close()
 		return err
 	}
 	var (
@@ -889,14 +1251,22 @@ func SplitXlsx2xlsxByFile(srcPath string, tarDir string, fileCount int, ctx cont
 		tarPathIdx int
 		totalRows  int
 		fileRows   int
+		recalc     bool // 当前文件已设置打开即重算
 		startFile  time.Time
 	)
+	keeper := newFormulaKeeper(opts.Formula)
 	for iter.Next() {
 		if totalRows%lineCount == 0 {
 			if tarPathIdx > 0 {
+				if err := setFilter(tarFile, "data", layout, fileRows+1); err != nil {
+					tarFile.Close()
+					iter.Close()
+					return err
+				}
 				sw.Flush()
-				if err := tarFile.SaveAs(tarPath); err != nil {
+				if err := protect.saveAs(tarFile, tarPath); err != nil {
 					tarFile.Close()
+					iter.Close()
 					return err
 				}
 				tarFile.Close()
@@ -909,33 +1279,48 @@ func SplitXlsx2xlsxByFile(srcPath string, tarDir string, fileCount int, ctx cont
 			// 使用模板文件（来自 Excel 2016+ 创建的空文件）
 			tarFile, err = excelize.OpenReader(bytes.NewReader(templateXlsx))
 			if err != nil {
-				srcFile.Close()
+				iter.Close()
 				return err
 			}
 			sw, err = tarFile.NewStreamWriter("data") // 流式写入（不爆内存，注意始终从首行开始）
 			if err != nil {
 				tarFile.Close()
-				srcFile.Close()
+				iter.Close()
 				return err
 			}
-			rowNew := make([]any, len(rowHeader))
-			for c := range rowHeader { // 行首不检查 CellType
-				rowNew[c] = excelize.Cell{
-					StyleID: meta[c+1].StyleId,
-					Value:   rowHeader[c],
-				}
+			if err := setLayout(sw, layout); err != nil {
+				tarFile.Close()
+				iter.Close()
+				return err
+			}
+			rowNew, err := styles.Resolve(tarFile, rowHeaderNew)
+			if err != nil {
+				tarFile.Close()
+				iter.Close()
+				return err
 			}
 			if err := sw.SetRow("A1", rowNew); err != nil {
 				tarFile.Close()
-				srcFile.Close()
+				iter.Close()
+				return err
+			}
+			if err := merges.Header(sw); err != nil {
+				tarFile.Close()
+				iter.Close()
+				return err
+			}
+			if err := notes.Header(tarFile, "data"); err != nil {
+				tarFile.Close()
+				iter.Close()
 				return err
 			}
-			fileRows = 0
+			fileRows, recalc = 0, false
+			keeper.Reset(srcPath, totalRows+1+lineCount)
 		}
 		select {
 		case <-ctx.Done():
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return ctx.Err()
 		default:
 		} // 响应 Ctrl+C 打断
@@ -944,41 +1329,48 @@ func SplitXlsx2xlsxByFile(srcPath string, tarDir string, fileCount int, ctx cont
 		row, err := iter.Columns()
 		if err != nil {
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
 			return err
 		}
-		rowNew := make([]any, len(row))
-		for c := range row {
-			cell := excelize.Cell{
-				StyleID: meta[c+1].StyleId,
-			}
-			if row[c] == "" {
-				cell.Value = nil
-			} else if meta[c+1].TypeIdx == excelize.CellTypeNumber ||
-				meta[c+1].TypeIdx == excelize.CellTypeUnset {
-				valFix, err := strconv.ParseFloat(row[c], 64)
-				if err == nil {
-					cell.Value = valFix
-				} else {
-					cell.Value = row[c]
-					col, err := excelize.ColumnNumberToName(c + 1)
-					if err != nil {
-						tarFile.Close()
-						srcFile.Close()
-						return err
-					}
-					log.Printf("%s：位置 %s%d，数据类型 %s，异常数据类型值 %s",
-						filepath.Base(srcPath), col, fileRows, meta[c+1].TypeRaw, row[c])
-				}
-			} else { // excelize.CellTypeInlineString, excelize.CellTypeSharedString
-				cell.Value = row[c]
+		rowNew, err := newDataRow(row, meta, guard, srcPath, totalRows+1)
+		if err != nil {
+			tarFile.Close()
+			iter.Close()
+			return err
+		}
+		keeper.Apply(rowNew, iter, totalRows+1, fileRows+1, 0)
+		if rowNew, err = styles.Apply(rowNew, iter, srcPath); err != nil {
+			tarFile.Close()
+			iter.Close()
+			return err
+		}
+		if !recalc && hasFormula(rowNew) {
+			if err := setRecalc(tarFile); err != nil {
+				tarFile.Close()
+				iter.Close()
+				return err
 			}
-			rowNew[c] = cell
+			recalc = true
+		}
+		if rowNew, err = styles.Resolve(tarFile, rowNew); err != nil {
+			tarFile.Close()
+			iter.Close()
+			return err
 		}
 		axis := fmt.Sprintf("A%d", fileRows+1)
 		if err := sw.SetRow(axis, rowNew); err != nil {
 			tarFile.Close()
-			srcFile.Close()
+			iter.Close()
+			return err
+		}
+		if err := merges.Apply(sw, srcPath, totalRows+1, fileRows+1); err != nil {
+			tarFile.Close()
+			iter.Close()
+			return err
+		}
+		if err := notes.Apply(tarFile, "data", srcPath, totalRows+1, fileRows+1); err != nil {
+			tarFile.Close()
+			iter.Close()
 			return err
 		}
 		if totalRows%10000 == 0 {
@@ -990,18 +1382,26 @@ func SplitXlsx2xlsxByFile(srcPath string, tarDir string, fileCount int, ctx cont
 		}
 	}
 	if tarPathIdx > 0 {
+		if err := setFilter(tarFile, "data", layout, fileRows+1); err != nil {
+			tarFile.Close()
+			return err
+		}
 		sw.Flush()
-		if err := tarFile.SaveAs(tarPath); err != nil {
+		if err := protect.saveAs(tarFile, tarPath); err != nil {
 			tarFile.Close()
 			return err
 		}
 		tarFile.Close()
 		fmt.Printf("数据文件%d：写入完成，共%d行\n", tarPathIdx, fileRows)
 	}
-	srcFile.Close()
+	iter.Close()
 	fmt.Printf("拆分完成，共%s，分为%s文件，耗时%s\n",
 		color.HiYellowString("%d行", totalRows), color.HiYellowString("%d个", tarPathIdx), util.Cost(start))
 	fmt.Printf("拆分文件夹：%s%s\n", strings.TrimSuffix(tarDir, filepath.Base(tarDir)),
 		color.HiYellowString(filepath.Base(tarDir)))
-	return nil
+	keeper.Report()
+	notes.Report()
+	merges.Report()
+	guard.Report()
+	return protect.report(tarDir + "-passwords.csv")
 }
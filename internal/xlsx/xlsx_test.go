@@ -0,0 +1,146 @@
+package xlsx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gitee.com/nguaduot/split-xlsx-go/internal/option"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestNewDataRow(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     excelize.CellType
+		date    bool
+		value   string
+		want    any
+		formula string
+	}{
+		{"数值", excelize.CellTypeNumber, false, "1.5", 1.5, ""},
+		{"未设类型的数值", excelize.CellTypeUnset, false, "-2", -2.0, ""},
+		{"数值列的文本", excelize.CellTypeNumber, false, "abc", "abc", ""},
+		{"空值", excelize.CellTypeNumber, false, "", nil, ""},
+		{"布尔", excelize.CellTypeBool, false, "TRUE", true, ""},
+		{"布尔 0", excelize.CellTypeBool, false, "0", false, ""},
+		{"布尔列的文本", excelize.CellTypeBool, false, "yes", "yes", ""},
+		{"数值列的错误值", excelize.CellTypeNumber, false, "#N/A", "#N/A", "#N/A"},
+		{"错误值列的错误值", excelize.CellTypeError, false, "#DIV/0!", "#DIV/0!", "#DIV/0!"},
+		{"错误值列的数值", excelize.CellTypeError, false, "3", 3.0, ""},
+		{"文本列的错误值按文本", excelize.CellTypeSharedString, false, "#N/A", "#N/A", ""},
+		{"公式文本结果", excelize.CellTypeFormula, false, "#REF!", "#REF!", ""},
+		{"公式文本结果的数字", excelize.CellTypeFormula, false, "12", "12", ""},
+		{"日期", excelize.CellTypeNumber, true, "2026-01-02", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), ""},
+		{"时间", excelize.CellTypeNumber, true, "12:00:00", 0.5, ""},
+		{"日期列的文本", excelize.CellTypeNumber, true, "n/a", "n/a", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := map[int]CellMeta{1: {StyleId: 3, TypeIdx: tt.typ, Date: tt.date}}
+			row, err := newDataRow([]string{tt.value}, meta, nil, "a.xlsx", 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cell := row[0].(excelize.Cell)
+			if cell.StyleID != 3 {
+				t.Errorf("StyleID = %d, want 3", cell.StyleID)
+			}
+			if !reflect.DeepEqual(cell.Value, tt.want) {
+				t.Errorf("Value = %#v, want %#v", cell.Value, tt.want)
+			}
+			if cell.Formula != tt.formula {
+				t.Errorf("Formula = %q, want %q", cell.Formula, tt.formula)
+			}
+			if got := hasFormula(row); got != (tt.formula != "") {
+				t.Errorf("hasFormula = %v", got)
+			}
+		})
+	}
+}
+
+func TestBoolValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want any
+		ok   bool
+	}{
+		{"TRUE", true, true},
+		{"true", true, true},
+		{"1", true, true},
+		{"FALSE", false, true},
+		{"0", false, true},
+		{"是", "是", false},
+	}
+	for _, tt := range tests {
+		got, ok := boolValue(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("boolValue(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSplitCellTypes(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src.xlsx")
+	f := excelize.NewFile()
+	for _, cell := range []struct {
+		axis  string
+		value any
+	}{
+		{"A1", "flag"}, {"B1", "num"}, {"C1", "err"},
+		{"A2", true}, {"B2", 1.5}, {"C2", 2},
+		{"A3", false}, {"B3", 2}, {"C3", "#N/A"},
+	} {
+		if err := f.SetCellValue("Sheet1", cell.axis, cell.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.SaveAs(src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tarDir := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(tarDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SplitXlsx2xlsxByLine(src, tarDir, 10, option.Options{}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	out, err := excelize.OpenFile(filepath.Join(tarDir, "out-1.xlsx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	tests := []struct {
+		axis    string
+		typ     excelize.CellType
+		value   string
+		formula string
+	}{
+		{"A2", excelize.CellTypeBool, "TRUE", ""},
+		{"A3", excelize.CellTypeBool, "FALSE", ""},
+		{"B2", excelize.CellTypeUnset, "1.5", ""},
+		{"C3", excelize.CellTypeFormula, "#N/A", "#N/A"}, // 常量公式，打开时重算为错误值
+	}
+	for _, tt := range tests {
+		typ, err := out.GetCellType("data", tt.axis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := out.GetCellValue("data", tt.axis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formula, err := out.GetCellFormula("data", tt.axis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != tt.typ || value != tt.value || formula != tt.formula {
+			t.Errorf("%s = %v %q %q, want %v %q %q", tt.axis, typ, value, formula, tt.typ, tt.value, tt.formula)
+		}
+	}
+}
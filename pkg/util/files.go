@@ -0,0 +1,205 @@
+package util
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// HasGlobMeta
+// 路径是否含通配符
+func HasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// matchAny
+// 文件名是否匹配任一模式，不区分大小写
+func matchAny(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOutputOf
+// 是否为导出至 tarPath 时生成的文件：导出文件本身、续写的 xxx-2.xlsx 等及密码清单 xxx-passwords.csv（不区分大小写）
+func IsOutputOf(file string, tarPath string) bool {
+	if SamePath(file, tarPath) {
+		return true
+	}
+	if !SamePath(filepath.Dir(file), filepath.Dir(tarPath)) {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(tarPath))
+	stem := strings.ToLower(strings.TrimSuffix(filepath.Base(tarPath), filepath.Ext(tarPath)))
+	rest, ok := strings.CutPrefix(strings.ToLower(filepath.Base(file)), stem+"-")
+	if !ok {
+		return false
+	}
+	if rest == "passwords.csv" {
+		return true
+	}
+	n, ok := strings.CutSuffix(rest, ext)
+	return ok && n != "" && strings.Trim(n, "0123456789") == ""
+}
+
+// SamePath
+// 两路径是否指向同一文件（按绝对路径比较，不区分大小写）
+func SamePath(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return strings.EqualFold(absA, absB)
+}
+
+// ExpandDataFiles
+// 展开目录或通配符（如 exports/2026-10-*.xlsx）为数据文件（Excel、CSV）列表，按路径排序；
+// recursive 则递归子目录，include、exclude 按文件名匹配（为空则不限）；
+// 跳过 Excel 打开文件时生成的 ~$ 临时文件；文件名与通配符均不区分大小写
+func ExpandDataFiles(path string, recursive bool, include []string, exclude []string) ([]string, error) {
+	res := []string{}
+	accept := func(file string) {
+		name := filepath.Base(file)
+		if strings.HasPrefix(name, "~$") || !IsDataFile(file) {
+			return
+		}
+		if len(include) > 0 && !matchAny(name, include) {
+			return
+		}
+		if matchAny(name, exclude) {
+			return
+		}
+		if !slices.Contains(res, file) {
+			res = append(res, file)
+		}
+	}
+	walk := func(root string, pattern string) error {
+		return filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if file != root && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if pattern != "" && !matchAny(d.Name(), []string{pattern}) {
+				return nil
+			}
+			accept(file)
+			return nil
+		})
+	}
+
+	if HasGlobMeta(path) {
+		dir, pattern := filepath.Split(path)
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("通配符有误：%s", path)
+		}
+		if dir == "" {
+			dir = "."
+		}
+		roots, err := filepath.Glob(filepath.Clean(dir))
+		if err != nil {
+			return nil, fmt.Errorf("通配符有误：%s", path)
+		}
+		for _, root := range roots {
+			if ok, _ := IsDir(root); !ok {
+				continue
+			}
+			if err := walk(root, pattern); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		ok, err := IsDir(path)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("该目录不存在：%s", path)
+		}
+		if err := walk(path, ""); err != nil {
+			return nil, err
+		}
+	}
+	slices.Sort(res)
+	return res, nil
+}
+
+// SortFilesByMtime
+// 按修改时间升序排序，时间相同按路径
+func SortFilesByMtime(files []string) error {
+	mtimes := make(map[string]int64, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		mtimes[file] = info.ModTime().UnixNano()
+	}
+	slices.SortStableFunc(files, func(a, b string) int {
+		if c := cmp.Compare(mtimes[a], mtimes[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	return nil
+}
+
+// NaturalCompare
+// 自然排序比较：连续数字按数值比较，如 part2 排在 part10 之前，其余字符不区分大小写
+func NaturalCompare(a string, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			si, sj := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			na := strings.TrimLeft(a[si:i], "0")
+			nb := strings.TrimLeft(b[sj:j], "0")
+			if len(na) != len(nb) {
+				return cmp.Compare(len(na), len(nb))
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+		ca, cb := lower(a[i]), lower(b[j])
+		if ca != cb {
+			return cmp.Compare(int(ca), int(cb))
+		}
+		i++
+		j++
+	}
+	if c := cmp.Compare(len(a)-i, len(b)-j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
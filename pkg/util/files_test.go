@@ -0,0 +1,103 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIsOutputOf(t *testing.T) {
+	tarPath := filepath.Join("dir", "report-merge.xlsx")
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{filepath.Join("dir", "report-merge.xlsx"), true},
+		{filepath.Join("dir", "REPORT-merge.XLSX"), true},
+		{filepath.Join("dir", "report-merge-2.xlsx"), true},
+		{filepath.Join("dir", "report-merge-passwords.csv"), true},
+		{filepath.Join("dir", "sub", "..", "report-merge-3.xlsx"), true},
+		{filepath.Join("other", "report-merge-2.xlsx"), false},
+		{filepath.Join("dir", "report-merge-x.xlsx"), false},
+		{filepath.Join("dir", "report-merge-2.csv"), false},
+		{filepath.Join("dir", "team-merge.xlsx"), false},
+		{filepath.Join("dir", "report.xlsx"), false},
+	}
+	for _, tt := range tests {
+		if got := IsOutputOf(tt.file, tarPath); got != tt.want {
+			t.Errorf("IsOutputOf(%q, %q) = %v, want %v", tt.file, tarPath, got, tt.want)
+		}
+	}
+}
+
+func TestExpandDataFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"a.xlsx", "b.csv", "c.XLSX", "~$a.xlsx", "note.txt",
+		"a-merge.xlsx", "a-merge-2.xlsx", "a-merge-passwords.csv",
+		"sub/d.xlsx", "sub/e.csv",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name      string
+		path      string
+		recursive bool
+		include   []string
+		exclude   []string
+		want      []string
+	}{
+		{"目录", dir, false, nil, nil, []string{"a-merge-2.xlsx", "a-merge-passwords.csv", "a-merge.xlsx", "a.xlsx", "b.csv", "c.XLSX"}},
+		{"递归", dir, true, nil, nil, []string{"a-merge-2.xlsx", "a-merge-passwords.csv", "a-merge.xlsx", "a.xlsx", "b.csv", "c.XLSX", "sub/d.xlsx", "sub/e.csv"}},
+		{"通配符", filepath.Join(dir, "*.xlsx"), false, nil, nil, []string{"a-merge-2.xlsx", "a-merge.xlsx", "a.xlsx", "c.XLSX"}},
+		{"通配符不区分大小写", filepath.Join(dir, "C.*"), false, nil, nil, []string{"c.XLSX"}},
+		{"通配符递归", filepath.Join(dir, "*.csv"), true, nil, nil, []string{"a-merge-passwords.csv", "b.csv", "sub/e.csv"}},
+		{"include", dir, true, []string{"*.xlsx"}, []string{"a-*"}, []string{"a.xlsx", "c.XLSX", "sub/d.xlsx"}},
+		{"exclude", dir, true, nil, []string{"a-*", "b*", "E.CSV"}, []string{"a.xlsx", "c.XLSX", "sub/d.xlsx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ExpandDataFiles(tt.path, tt.recursive, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range files {
+				rel, _ := filepath.Rel(dir, file)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := ExpandDataFiles(filepath.Join(dir, "missing"), false, nil, nil); err == nil {
+		t.Error("目录不存在时期望返回错误")
+	}
+}
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"part2", "part10", -1},
+		{"part10", "part2", 1},
+		{"Part2", "part2", -1},
+		{"part02", "part2", -1},
+		{"a", "a", 0},
+		{"a", "ab", -1},
+	}
+	for _, tt := range tests {
+		if got := NaturalCompare(tt.a, tt.b); got != tt.want {
+			t.Errorf("NaturalCompare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/xuri/excelize/v2"
 )
 
 func InitLog(outPath string) (func(), error) {
@@ -105,7 +107,10 @@ func IsExcelFile(file string) bool {
 	if file == "" {
 		return false
 	}
-	if !strings.HasSuffix(strings.ToLower(file), ".xlsx") {
+	ext := strings.ToLower(filepath.Ext(file))
+	switch ext {
+	case ".xlsx", ".xlsm", ".xltx", ".xltm", ".xls":
+	default:
 		return false
 	}
 	info, err := os.Stat(file)
@@ -115,6 +120,26 @@ func IsExcelFile(file string) bool {
 	return !info.IsDir()
 }
 
+func IsCSVFile(file string) bool {
+	if file == "" {
+		return false
+	}
+	if !strings.HasSuffix(strings.ToLower(file), ".csv") {
+		return false
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return false
+	}
+	return !info.IsDir()
+}
+
+// IsDataFile
+// 可作为合并、拆分数据来源的文件：Excel 或 CSV
+func IsDataFile(file string) bool {
+	return IsExcelFile(file) || IsCSVFile(file)
+}
+
 func CopyFile(src, dst string) error {
 	in, err := os.Open(src)
 	if err != nil {
@@ -188,3 +213,42 @@ func IsDirAndNotEmpty(path string) (bool, error) {
 	}
 	return len(entries) > 0, nil
 }
+
+// ColumnIndexFromKey
+// 根据行首文字或列名（A、B…）定位列，返回从0开始的列序号，行首文字优先
+func ColumnIndexFromKey(header []string, key string) (int, error) {
+	for i, name := range header {
+		if strings.TrimSpace(name) == key {
+			return i, nil
+		}
+	}
+	col := strings.ToUpper(key)
+	if col != "" && strings.Trim(col, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == "" {
+		return XlsxColIndexFromAxis(col) - 1, nil
+	}
+	return 0, fmt.Errorf("未找到该列：%s", key)
+}
+
+// FieldNames
+// 行首转为字段名（JSON 键、Parquet 列名）：空白的列以列名（A、B…）代替，重名的依次附加 _2、_3…
+func FieldNames(header []string) []string {
+	names := make([]string, len(header))
+	seen := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name, _ = excelize.ColumnNumberToName(i + 1)
+		}
+		seen[name]++
+		for n := seen[name]; n > 1; n++ {
+			alt := name + "_" + strconv.Itoa(n)
+			if seen[alt] == 0 {
+				seen[alt]++
+				name = alt
+				break
+			}
+		}
+		names[i] = name
+	}
+	return names
+}
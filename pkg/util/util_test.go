@@ -0,0 +1,51 @@
+package util
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFieldNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   []string
+	}{
+		{"原样", []string{"id", "姓名"}, []string{"id", "姓名"}},
+		{"去除首尾空白", []string{" id ", "name\t"}, []string{"id", "name"}},
+		{"空白的列", []string{"id", "", " "}, []string{"id", "B", "C"}},
+		{"重名", []string{"a", "a", "a"}, []string{"a", "a_2", "a_3"}},
+		{"区分大小写", []string{"Name", "name", "NAME"}, []string{"Name", "name", "NAME"}},
+		{"附加后缀已被占用", []string{"a", "a_2", "a"}, []string{"a", "a_2", "a_3"}},
+		{"空白的列与列名重名", []string{"B", ""}, []string{"B", "B_2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FieldNames(tt.header); !slices.Equal(got, tt.want) {
+				t.Errorf("FieldNames(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnIndexFromKey(t *testing.T) {
+	header := []string{"id", "B", "姓名"}
+	tests := []struct {
+		key  string
+		want int
+		ok   bool
+	}{
+		{"id", 0, true},
+		{"姓名", 2, true},
+		{"B", 1, true}, // 行首文字优先
+		{"C", 2, true},
+		{"c", 2, true},
+		{"不存在", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ColumnIndexFromKey(header, tt.key)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("ColumnIndexFromKey(%q) = %d, %v; want %d, ok %v", tt.key, got, err, tt.want, tt.ok)
+		}
+	}
+}